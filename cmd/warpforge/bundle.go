@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/warptools/warpforge/cmd/warpforge/internal/util"
+	"github.com/warptools/warpforge/pkg/bundle"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+var bundleCmdDef = cli.Command{
+	Name:  "bundle",
+	Usage: "Subcommands that transfer catalogs and wares between machines as a single archive",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "export",
+			Usage: "Write catalog releases, their replays and their wares to a bundle archive",
+			Description: strings.Join([]string{
+				`[module[:release]]: a module name, optionally with a release name. Without a release, all releases of the module are exported.`,
+				`Releases used by the replays of exported releases are exported as well.`,
+			}, "\n"),
+			Flags: []cli.Flag{
+				&cli.PathFlag{
+					Name:     "output",
+					Aliases:  []string{"o"},
+					Usage:    "Path of the bundle to write, or \"-\" for stdout",
+					Required: true,
+				},
+			},
+			Action: util.ChainCmdMiddleware(cmdBundleExport,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			ArgsUsage: "[module[:release]]...",
+		},
+		{
+			Name:  "import",
+			Usage: "Verify a bundle archive and merge it into the root workspace's catalog and warehouse",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "catalog",
+					Aliases: []string{"n"},
+					Usage:   "Name of the root workspace catalog to merge into",
+					Value:   "default",
+				},
+				&cli.BoolFlag{
+					Name:    "force",
+					Aliases: []string{"f"},
+					Usage:   "Overwrite releases which differ from the ones in the bundle",
+				},
+			},
+			Action: util.ChainCmdMiddleware(cmdBundleImport,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			ArgsUsage: "[bundle path, or \"-\" for stdin]",
+		},
+	},
+}
+
+func cmdBundleExport(c *cli.Context) error {
+	if c.Args().Len() < 1 {
+		return fmt.Errorf("no modules provided")
+	}
+	var selection []wfapi.CatalogRef
+	for _, arg := range c.Args().Slice() {
+		module, release, _ := strings.Cut(arg, ":")
+		selection = append(selection, wfapi.CatalogRef{
+			ModuleName:  wfapi.ModuleName(module),
+			ReleaseName: wfapi.ReleaseName(release),
+		})
+	}
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+
+	output := c.Path("output")
+	var w io.Writer = c.App.Writer
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create bundle file: %s", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	summary, err := bundle.Export(c.Context, wss, w, selection)
+	if err != nil {
+		if output != "-" {
+			os.Remove(output)
+		}
+		return err
+	}
+	if output != "-" {
+		fmt.Fprintf(c.App.Writer, "exported %d releases and %d wares to %s\n", len(summary.Releases), len(summary.Wares), output)
+	}
+	return nil
+}
+
+func cmdBundleImport(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("invalid number of arguments")
+	}
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+
+	input := c.Args().First()
+	var r io.Reader = c.App.Reader
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return fmt.Errorf("failed to open bundle file: %s", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	cfg := bundle.ImportConfig{
+		Catalog:  c.String("catalog"),
+		Force:    c.Bool("force"),
+		ScanWare: scanWareFile,
+	}
+	summary, err := bundle.Import(c.Context, wss.Root(), r, cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "imported %d releases and %d wares into catalog %q\n", len(summary.Releases), len(summary.Wares), cfg.Catalog)
+	return nil
+}
+
+// scanWareFile computes the WareID of a ware file on the host using rio.
+func scanWareFile(ctx context.Context, packtype wfapi.Packtype, path string) (wfapi.WareID, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return wfapi.WareID{}, wfapi.ErrorIo("failed to convert ware path to absolute path", path, err)
+	}
+	wareId, err := scanWareId(ctx, packtype, wfapi.WarehouseAddr("file://"+path))
+	if err != nil {
+		return wfapi.WareID{}, wfapi.ErrorExecutorFailed("rio", err)
+	}
+	return wareId, nil
+}
@@ -3,16 +3,25 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
 	"github.com/serum-errors/go-serum"
 	"github.com/urfave/cli/v2"
 	"go.opentelemetry.io/otel/trace"
@@ -22,10 +31,12 @@ import (
 	"github.com/warptools/warpforge/pkg/cataloghtml"
 	"github.com/warptools/warpforge/pkg/config"
 	"github.com/warptools/warpforge/pkg/dab"
+	"github.com/warptools/warpforge/pkg/fetch"
 	"github.com/warptools/warpforge/pkg/logging"
 	"github.com/warptools/warpforge/pkg/mirroring"
 	"github.com/warptools/warpforge/pkg/plotexec"
 	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
 	"github.com/warptools/warpforge/wfapi"
 )
 
@@ -63,6 +74,7 @@ var catalogCmdDef = cli.Command{
 				util.CmdMiddlewareTracingConfig,
 				util.CmdMiddlewareTracingSpan,
 			),
+			Flags: catalogMetadataFlags(),
 		},
 		{
 			Name:  "release",
@@ -72,6 +84,65 @@ var catalogCmdDef = cli.Command{
 				util.CmdMiddlewareTracingConfig,
 				util.CmdMiddlewareTracingSpan,
 			),
+			Flags: catalogMetadataFlags(),
+		},
+		{
+			Name:  "sign",
+			Usage: "Sign a release in the root workspace catalog with an ed25519 key",
+			Description: strings.Join([]string{
+				`[module:release]: the release to sign, e.g. "example.com/foo:v1.0".`,
+				`The key is a PEM encoded PKCS #8 ed25519 private key, such as the output of "openssl genpkey -algorithm ed25519".`,
+				`The base64 public key which made the signature is printed, for use in trust policies.`,
+			}, "\n"),
+			Flags: []cli.Flag{
+				&cli.PathFlag{
+					Name:     "key",
+					Aliases:  []string{"k"},
+					Usage:    "Path of the private key to sign with",
+					Required: true,
+				},
+			},
+			Action: util.ChainCmdMiddleware(cmdCatalogSign,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			ArgsUsage: "[module:release]",
+		},
+		{
+			Name:  "yank",
+			Usage: "Mark a release in the root workspace catalog as yanked or deprecated",
+			Description: strings.Join([]string{
+				`[module:release]: the release to mark, e.g. "example.com/foo:v1.0".`,
+				`Yanked releases are skipped when resolving version constraints and upgrading plots, and using them produces a warning.`,
+				`Deprecated releases still work, but using them produces a warning.`,
+				`Marking a release changes its CID, so it must be signed again if it was signed.`,
+				`If the trust policy requires the release to be signed, and it is, marking it is refused unless --force is given.`,
+			}, "\n"),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "reason",
+					Usage: "Why the release should not be used. Required unless --undo is given",
+				},
+				&cli.StringFlag{
+					Name:  "replacement",
+					Usage: "Name of a release of the same module to use instead",
+				},
+				&cli.BoolFlag{
+					Name:  "deprecate",
+					Usage: "Mark the release as deprecated rather than yanked",
+				},
+				&cli.BoolFlag{
+					Name:  "undo",
+					Usage: "Clear the yanked or deprecated status of the release",
+				},
+			},
+			Action: util.ChainCmdMiddleware(cmdCatalogYank,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			ArgsUsage: "[module:release]",
 		},
 		{
 			Name:  "ls",
@@ -84,7 +155,7 @@ var catalogCmdDef = cli.Command{
 		},
 		{
 			Name:  "show",
-			Usage: "Show the contents of a module in the root workspace catalog",
+			Usage: "Show the contents of a module in the root workspace catalog, with releases in version order",
 
 			Action: util.ChainCmdMiddleware(cmdCatalogShow,
 				util.CmdMiddlewareLogging,
@@ -92,32 +163,218 @@ var catalogCmdDef = cli.Command{
 				util.CmdMiddlewareTracingSpan,
 			),
 		},
+		{
+			Name:      "search",
+			Usage:     "Search every catalog in the workspace stack for items of modules matching a glob or substring",
+			ArgsUsage: "[module pattern]",
+			Description: strings.Join([]string{
+				`Patterns containing any of "*?[" are globs, in which "*" matches any characters including "/", so "*gcc*" finds "warpsys.org/gcc".`,
+				`Other patterns match any name, label, or value containing them.`,
+			}, "\n"),
+			Action: util.ChainCmdMiddleware(cmdCatalogSearch,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "metadata",
+					Usage: "Only match releases with this metadata, given as 'key' or 'key=pattern'. May be repeated",
+				},
+				&cli.StringSliceFlag{
+					Name:  "item",
+					Usage: "Only match items whose label matches this pattern. May be repeated to match any of several patterns",
+				},
+			},
+		},
+		{
+			Name:      "diff",
+			Usage:     "Compare two catalogs in the root workspace, or one catalog at two git revisions",
+			ArgsUsage: "[old catalog[@revision]] [new catalog[@revision]]",
+			Description: strings.Join([]string{
+				`Each side is the name of a catalog in the root workspace, optionally followed by "@" and a git revision of it,`,
+				`such as "warpsys@HEAD~1" or "warpsys@3f2a1c9". A catalog without a revision is compared as it is on disk.`,
+				`If only one side is given, it must have a revision, and it's compared with the same catalog as it is on disk.`,
+				`Reports added and removed modules and releases, changed items, releases with changed metadata or status, and mirror changes.`,
+			}, "\n"),
+			Action: util.ChainCmdMiddleware(cmdCatalogDiff,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+		},
+		{
+			Name:      "rdeps",
+			Usage:     "Find the releases in every catalog of the workspace stack whose replays depend on a catalog item or ware",
+			ArgsUsage: "[module:release[:item]]",
+			Description: strings.Join([]string{
+				`Scans the replays of every release for inputs using the given item (or any item of the given release),`,
+				`or using the given ware with --ware, either directly or through a catalog reference.`,
+				`Releases depending on those releases are found in turn, so transitive dependents are reported too,`,
+				`with a depth of 1 for direct dependents, 2 for their dependents, and so on.`,
+				`Releases whose replays use a version constraint which the release matches, but which now resolves to a newer one,`,
+				`may or may not have been built with it, so they're reported as possible dependents.`,
+			}, "\n"),
+			Action: util.ChainCmdMiddleware(cmdCatalogRdeps,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "ware",
+					Usage: "Find dependents of this ware, given as 'packtype:hash', instead of a catalog item",
+				},
+			},
+		},
+		{
+			Name:      "verify-replay",
+			Usage:     "Execute the replays of releases in the root workspace catalog, and check that they reproduce the release's items",
+			ArgsUsage: "[module[:release]]",
+			Description: strings.Join([]string{
+				`Verifies every release with a replay in the catalog, every release of a module, or a single release.`,
+				`Replays are executed with memoization disabled, so every step really runs,`,
+				`and each item of the release is compared with the WareID the replay produced.`,
+				`Outputs of the replay which the release doesn't have as items are reported, but aren't failures.`,
+				`With --json, the outcome for each release is output as a ReplayVerificationReport; --report writes the same report to a file.`,
+			}, "\n"),
+			Action: util.ChainCmdMiddleware(cmdCatalogVerifyReplay,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			Flags: []cli.Flag{
+				&cli.PathFlag{
+					Name:  "report",
+					Usage: "Write a JSON report of the verification to this file",
+				},
+				&cli.BoolFlag{
+					Name:  "record",
+					Usage: "Record the time of verification in the metadata of each release which is verified. Refused for releases the trust policy requires to be signed, unless --force is given",
+				},
+				&cli.BoolFlag{
+					Name:  "recursive",
+					Usage: "Allow replays to run the replays of their inputs, if those wares are not available",
+				},
+			},
+		},
 		{
 			Name:  "bundle",
-			Usage: "Bundle required catalog items into the local workspace.",
+			Usage: "Bundle required catalog items, their replays, and everything those replays require into the local workspace.",
 			Action: util.ChainCmdMiddleware(cmdCatalogBundle,
 				util.CmdMiddlewareLogging,
 				util.CmdMiddlewareTracingConfig,
 				util.CmdMiddlewareTracingSpan,
 			),
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "wares",
+					Usage: "Also fetch and verify the wares of bundled items into the local workspace's warehouse, so the module can be built offline",
+				},
+			},
+		},
+		{
+			Name:  "fsck",
+			Usage: "Check the consistency of a catalog in the root workspace",
+			Action: util.ChainCmdMiddleware(cmdCatalogFsck,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "repair",
+					Usage: "Rewrite module release indexes to match the release files which exist",
+				},
+			},
 		},
 		{
 			Name:  "update",
-			Usage: "Update remote catalogs in the root workspace. Will install the default warpsys catalog.",
+			Usage: "Clone or update the catalogs the root workspace subscribes to. Without a subscription config, subscribes to the warpsys catalog, and to the origin of each other catalog which is a git clone.",
 			Action: util.ChainCmdMiddleware(cmdCatalogUpdate,
 				util.CmdMiddlewareLogging,
 				util.CmdMiddlewareTracingConfig,
 				util.CmdMiddlewareTracingSpan,
 			),
 		},
+		{
+			Name:      "subscribe",
+			Usage:     "Subscribe the root workspace to a remote catalog, or change an existing subscription",
+			ArgsUsage: "[catalog name] [git URL or path]",
+			Description: strings.Join([]string{
+				`Adds the catalog to the root workspace's subscription config; "warpforge catalog update" then clones it.`,
+				`Subscribing to a catalog which is already subscribed replaces its subscription.`,
+			}, "\n"),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "branch",
+					Usage: "Branch to follow, instead of the remote's default branch",
+				},
+				&cli.StringFlag{
+					Name:  "commit",
+					Usage: "Full commit hash to pin the catalog to, instead of following a branch",
+				},
+				&cli.BoolFlag{
+					Name:  "disable",
+					Usage: "Keep the subscription, but don't update the catalog",
+				},
+			},
+			Action: util.ChainCmdMiddleware(cmdCatalogSubscribe,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+		},
+		{
+			Name:      "unsubscribe",
+			Usage:     "Remove a catalog subscription from the root workspace",
+			ArgsUsage: "[catalog name]",
+			Description: strings.Join([]string{
+				`The catalog itself is kept, as a local catalog, unless --remove is given.`,
+				`Catalogs with local changes are only removed with --force.`,
+			}, "\n"),
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "remove",
+					Usage: "Remove the catalog as well",
+				},
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "Remove the catalog even if it has local changes",
+				},
+			},
+			Action: util.ChainCmdMiddleware(cmdCatalogUnsubscribe,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+		},
 		{
 			Name:  "ingest-git-tags",
-			Usage: "Ingest all tags from a git repository into a root workspace catalog entry",
+			Usage: "Ingest tags from a git repository into a root workspace catalog entry, as releases which are newer than any the module already has",
+			Description: strings.Join([]string{
+				`Tags which were ingested before, but now point to a different commit, are reported and left alone,`,
+				`unless --force is given to the catalog command. Even then, they aren't replaced in releases whose signatures the trust policy requires.`,
+			}, "\n"),
 			Action: util.ChainCmdMiddleware(cmdIngestGitTags,
 				util.CmdMiddlewareLogging,
 				util.CmdMiddlewareTracingConfig,
 				util.CmdMiddlewareTracingSpan,
 			),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "tag-pattern",
+					Usage: "Only ingest tags matching this regular expression",
+				},
+				&cli.StringFlag{
+					Name:  "release-name",
+					Usage: "Template for release names, using the capture groups of --tag-pattern, e.g. '$1' for --tag-pattern '^v(.*)$'",
+				},
+				&cli.BoolFlag{
+					Name:  "all",
+					Usage: "Ingest every matching tag, including those older than the newest release of the module",
+				},
+			},
 		},
 		{
 			Name:  "generate-html",
@@ -146,11 +403,58 @@ var catalogCmdDef = cli.Command{
 		{
 			Name:  "mirror",
 			Usage: "Mirror the contents of a catalog to remote warehouses",
+			Description: strings.Join([]string{
+				`Pushes each ware of the catalog to the mirror its catalog entry names, if that mirror is configured`,
+				`and the ware is in the local warehouse, and reports what happened to each ware.`,
+				`Wares a mirror already has are skipped, so an interrupted or partly failed mirroring can be resumed by running it again.`,
+				``,
+				`With --check, nothing is pushed: instead, each mirror is asked whether it has each ware, and the wares it's missing are reported.`,
+				`With --rehash as well, wares are also downloaded from mirrors which can be read from and rehashed, to find corrupt copies.`,
+				``,
+				`The --module, --release, --item and --newer-than flags limit which wares are pushed or checked,`,
+				`so that publishing a new release doesn't need to look at every other release in the catalog.`,
+			}, "\n"),
 			Action: util.ChainCmdMiddleware(cmdMirror,
 				util.CmdMiddlewareLogging,
 				util.CmdMiddlewareTracingConfig,
 				util.CmdMiddlewareTracingSpan,
 			),
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:  "concurrency",
+					Usage: "How many wares to push, or check, at once",
+					Value: mirroring.DefaultPushOptions.Concurrency,
+				},
+				&cli.IntFlag{
+					Name:  "retries",
+					Usage: "How many times to retry pushing a ware after a transient failure",
+					Value: mirroring.DefaultPushOptions.Retries,
+				},
+				&cli.BoolFlag{
+					Name:  "check",
+					Usage: "Check that each mirror has the catalog's wares, without pushing anything",
+				},
+				&cli.BoolFlag{
+					Name:  "rehash",
+					Usage: "With --check, also download and rehash the wares of mirrors which can be read from",
+				},
+				&cli.StringSliceFlag{
+					Name:  "module",
+					Usage: `Only mirror modules whose name matches this glob, in which "*" also matches "/". May be repeated`,
+				},
+				&cli.StringSliceFlag{
+					Name:  "release",
+					Usage: "Only mirror releases with this name. May be repeated",
+				},
+				&cli.StringSliceFlag{
+					Name:  "item",
+					Usage: "Only mirror items with this label. May be repeated",
+				},
+				&cli.StringFlag{
+					Name:  "newer-than",
+					Usage: "Only mirror releases ordered after the release with this name",
+				},
+			},
 		},
 	},
 }
@@ -231,6 +535,10 @@ func cmdCatalogAdd(c *cli.Context) error {
 	packType := c.Args().Get(0)
 	catalogRefStr := c.Args().Get(1)
 	url := c.Args().Get(2)
+	moduleMetadata, releaseMetadata, err := metadataFromFlags(c)
+	if err != nil {
+		return err
+	}
 
 	// open the workspace set
 	wsSet, err := util.OpenWorkspaceSet()
@@ -270,6 +578,9 @@ func cmdCatalogAdd(c *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to open catalog %q: %s", catalogName, err)
 	}
+	if err := checkReleaseMetadataChange(c, root, catalogName, ref, releaseMetadata); err != nil {
+		return err
+	}
 
 	switch packType {
 	case "tar":
@@ -336,6 +647,9 @@ func cmdCatalogAdd(c *cli.Context) error {
 	default:
 		return fmt.Errorf("unsupported packtype: %q", packType)
 	}
+	if err := applyMetadata(&cat, ref, moduleMetadata, releaseMetadata); err != nil {
+		return err
+	}
 
 	if c.Bool("verbose") {
 		catalogPath, _ := root.CatalogPath(catalogName) // assume an error would be handled earlier
@@ -366,241 +680,849 @@ func cmdCatalogLs(c *cli.Context) error {
 	return nil
 }
 
-func cmdCatalogBundle(c *cli.Context) error {
-	var err error
-	wsSet, err := util.OpenWorkspaceSet()
+func cmdCatalogSign(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog sign --key [key path] [module:release]")
+	}
+	moduleName, releaseName, ok := strings.Cut(c.Args().First(), ":")
+	if !ok || releaseName == "" {
+		return fmt.Errorf("invalid release %q, expected [module:release]", c.Args().First())
+	}
+	ref := wfapi.CatalogRef{
+		ModuleName:  wfapi.ModuleName(moduleName),
+		ReleaseName: wfapi.ReleaseName(releaseName),
+	}
+	key, err := loadSigningKey(c.Path("key"))
 	if err != nil {
 		return err
 	}
-	pwd, err := os.Getwd()
+
+	wss, err := util.OpenWorkspaceSet()
 	if err != nil {
-		return fmt.Errorf("failed to get pwd: %s", err)
+		return err
 	}
-	plot, err := util.PlotFromFile(filepath.Join(pwd, dab.MagicFilename_Plot))
+	catalogName := c.String("name")
+	cat, err := wss.Root().OpenCatalog(catalogName)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to open catalog %q: %s", catalogName, err)
 	}
-
-	if err := wsSet.Tidy(c.Context, plot, c.Bool("force")); err != nil {
+	if err := cat.SignRelease(ref, key); err != nil {
 		return err
 	}
-
+	publicKey := base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	fmt.Fprintf(c.App.Writer, "signed %s:%s with key %s\n", ref.ModuleName, ref.ReleaseName, publicKey)
 	return nil
 }
 
-func cmdCatalogUpdate(c *cli.Context) error {
-	var err error
-	wss, err := util.OpenWorkspaceSet()
-	if err != nil {
-		return fmt.Errorf("failed to open workspace set: %s", err)
-	}
-
-	// get the catalog path for the root workspace
-	catalogPath := filepath.Join("/", wss.Root().CatalogBasePath())
-	// create the path if it does not exist
-	if _, err := os.Stat(catalogPath); os.IsNotExist(err) {
-		err = os.MkdirAll(catalogPath, 0755)
-		if err != nil {
-			return fmt.Errorf("failed to create catalog path: %s", err)
-		}
+func cmdCatalogYank(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog yank [--reason reason] [module:release]")
 	}
-
-	if err = catalog.InstallDefaultRemoteCatalog(c.Context, catalogPath); err != nil {
-		return fmt.Errorf("failed to install default catalog: %s", err)
+	moduleName, releaseName, ok := strings.Cut(c.Args().First(), ":")
+	if !ok || releaseName == "" {
+		return fmt.Errorf("invalid release %q, expected [module:release]", c.Args().First())
 	}
-
-	catalogs, err := os.ReadDir(catalogPath)
-	if err != nil {
-		return fmt.Errorf("failed to list catalog path: %s", err)
+	ref := wfapi.CatalogRef{
+		ModuleName:  wfapi.ModuleName(moduleName),
+		ReleaseName: wfapi.ReleaseName(releaseName),
 	}
 
-	for _, cat := range catalogs {
-		if !cat.IsDir() {
-			// ignore non-directory items
-			continue
+	var status *wfapi.ReleaseStatus
+	if c.Bool("undo") {
+		if c.IsSet("reason") || c.IsSet("replacement") || c.Bool("deprecate") {
+			return fmt.Errorf("--undo cannot be combined with --reason, --replacement or --deprecate")
 		}
-
-		path := filepath.Join(catalogPath, cat.Name())
-
-		r, err := git.PlainOpen(path)
-		if err == git.ErrRepositoryNotExists {
-			if !c.Bool("quiet") {
-				fmt.Fprintf(c.App.Writer, "%s: local catalog\n", cat.Name())
-			}
-			continue
-		} else if err != nil {
-			return fmt.Errorf("failed to open git repo: %s", err)
+	} else {
+		if c.String("reason") == "" {
+			return fmt.Errorf("a --reason is required")
 		}
-
-		wt, err := r.Worktree()
-		if err != nil {
-			return fmt.Errorf("failed to open git worktree: %s", err)
+		status = &wfapi.ReleaseStatus{
+			Kind:   wfapi.ReleaseStatusKind_Yanked,
+			Reason: c.String("reason"),
 		}
-
-		err = wt.Pull(&git.PullOptions{})
-		if err == git.NoErrAlreadyUpToDate {
-			if !c.Bool("quiet") {
-				fmt.Fprintf(c.App.Writer, "%s: already up to date\n", cat.Name())
-			}
-		} else if err != nil {
-			return fmt.Errorf("failed to pull from git: %s", err)
-		} else {
-			if !c.Bool("quiet") {
-				fmt.Fprintf(c.App.Writer, "%s: updated\n", cat.Name())
-			}
+		if c.Bool("deprecate") {
+			status.Kind = wfapi.ReleaseStatusKind_Deprecated
+		}
+		if c.IsSet("replacement") {
+			replacement := wfapi.ReleaseName(c.String("replacement"))
+			status.Replacement = &replacement
 		}
 	}
 
-	return nil
-}
-
-func cmdCatalogRelease(c *cli.Context) error {
-	ctx := c.Context
-	var err error
-
-	if c.Args().Len() != 1 {
-		return fmt.Errorf("invalid input. usage: warpforge catalog release [release name]")
-	}
-	catalogName := c.String("name")
 	wss, err := util.OpenWorkspaceSet()
 	if err != nil {
 		return err
 	}
-	rootWs := wss.Root()
-	// create the catalog if it does not exist
-	exists, err := rootWs.HasCatalog(catalogName)
+	catalogName := c.String("name")
+	cat, err := wss.Root().OpenCatalog(catalogName)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to open catalog %q: %s", catalogName, err)
 	}
-	if !exists {
-		err := rootWs.CreateCatalog(catalogName)
-		if err != nil {
-			return err
+	if !c.Bool("force") {
+		if err := wss.Root().CheckReleaseChange(catalogName, ref); err != nil {
+			return fmt.Errorf("%s; use --force to change it anyway, then sign it again", err)
 		}
 	}
-
-	fsys := os.DirFS("/")
-	// get the module, release, and item values (in format `module:release:item`)
-	module, err := dab.ModuleFromFile(fsys, dab.MagicFilename_Module)
+	sigs, err := cat.GetSignatures(ref)
 	if err != nil {
 		return err
 	}
+	if err := cat.SetReleaseStatus(ref, status); err != nil {
+		return err
+	}
 
-	releaseName := c.Args().Get(0)
+	if status == nil {
+		fmt.Fprintf(c.App.Writer, "cleared status of %s:%s\n", ref.ModuleName, ref.ReleaseName)
+	} else {
+		fmt.Fprintf(c.App.Writer, "marked %s:%s as %s\n", ref.ModuleName, ref.ReleaseName, status.Kind)
+	}
+	if sigs != nil && len(sigs.Signatures) > 0 {
+		fmt.Fprintf(c.App.Writer, "the release CID has changed, so %s:%s must be signed again\n", ref.ModuleName, ref.ReleaseName)
+	}
+	return nil
+}
 
-	fmt.Printf("building replay for module = %q, release = %q, executing plot...\n", module.Name, releaseName)
-	plot, err := util.PlotFromFile(dab.MagicFilename_Plot)
+// loadSigningKey reads a PEM encoded PKCS #8 ed25519 private key.
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	keyBytes, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read key: %s", err)
+	}
+	block, _ := pem.Decode(keyBytes)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("key %q is not a PEM encoded private key", path)
 	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %s", path, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key %q is not an ed25519 key", path)
+	}
+	return edKey, nil
+}
 
-	execCfg, err := config.PlotExecConfig(nil)
+func cmdCatalogBundle(c *cli.Context) error {
+	var err error
+	wsSet, err := util.OpenWorkspaceSet()
 	if err != nil {
 		return err
 	}
-	results, err := plotexec.Exec(ctx, execCfg, wss, wfapi.PlotCapsule{Plot: &plot}, wfapi.PlotExecConfig{Recursive: false})
+	pwd, err := os.Getwd()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get pwd: %s", err)
 	}
-
-	cat, err := rootWs.OpenCatalog(catalogName)
+	plot, err := util.PlotFromFile(filepath.Join(pwd, dab.MagicFilename_Plot))
 	if err != nil {
 		return err
 	}
 
-	parent := wfapi.CatalogRef{
-		ModuleName:  module.Name,
-		ReleaseName: wfapi.ReleaseName(releaseName),
-		ItemName:    wfapi.ItemLabel(""), // replay is not item specific
+	cfg := workspace.TidyConfig{
+		Force: c.Bool("force"),
 	}
-
-	for itemName, wareId := range results.Values {
-		ref := wfapi.CatalogRef{
-			ModuleName:  module.Name,
-			ReleaseName: wfapi.ReleaseName(releaseName),
-			ItemName:    wfapi.ItemLabel(itemName),
-		}
-
-		fmt.Println(ref.String(), "->", wareId)
-		err := cat.AddItem(ref, wareId, c.Bool("force"))
+	if c.Bool("wares") {
+		binPath, err := config.BinPath()
 		if err != nil {
 			return err
 		}
+		cfg.FetchWare = fetch.TidyFetcher(wsSet, fetch.Options{Verify: fetch.RioVerifier(binPath)})
 	}
-
-	err = cat.AddReplay(parent, plot, c.Bool("force"))
-	if err != nil {
+	if err := wsSet.Tidy(c.Context, plot, cfg); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func cmdIngestGitTags(c *cli.Context) error {
-	if c.Args().Len() != 3 {
-		return fmt.Errorf("invalid input. usage: warpforge catalog ingest-git-repo [module name] [url] [item name]")
+func cmdCatalogFsck(c *cli.Context) error {
+	if c.Args().Len() != 0 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog fsck [--repair]")
 	}
-	ctx := c.Context
-
-	moduleName := c.Args().Get(0)
-	url := c.Args().Get(1)
-	itemName := c.Args().Get(2)
-
-	// open the remote and list all references
-	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
-		Name: "origin",
-		URLs: []string{url},
-	})
-
-	listCtx, listSpan := tracing.Start(ctx, "git ls-remote", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitLs))
-	defer listSpan.End()
-	refs, err := remote.ListContext(listCtx, &git.ListOptions{})
-	tracing.EndWithStatus(listSpan, err)
+	wss, err := util.OpenWorkspaceSet()
 	if err != nil {
 		return err
 	}
+	catalogName := c.String("name")
+	cat, err := wss.Root().OpenCatalog(catalogName)
+	if err != nil {
+		return fmt.Errorf("failed to open catalog %q: %s", catalogName, err)
+	}
 
-	// open the workspace set and catalog
+	problems, err := cat.Fsck(workspace.FsckConfig{Repair: c.Bool("repair")})
+	if err != nil {
+		return err
+	}
+	unrepaired := 0
+	for _, p := range problems {
+		status := ""
+		if p.Repaired {
+			status = " (repaired)"
+		} else {
+			unrepaired++
+		}
+		fmt.Fprintf(c.App.Writer, "%s: %s%s\n", p.Module, p.Err, status)
+	}
+	fmt.Fprintf(c.App.Writer, "checked %d modules: %d problems, %d repaired\n", len(cat.Modules()), len(problems), len(problems)-unrepaired)
+	if unrepaired > 0 {
+		return serum.Error(wfapi.ECodeCatalogInvalid,
+			serum.WithMessageTemplate("catalog {{catalog|q}} has {{count}} unrepaired problems"),
+			serum.WithDetail("catalog", catalogName),
+			serum.WithDetail("count", strconv.Itoa(unrepaired)),
+		)
+	}
+	return nil
+}
+
+func cmdCatalogUpdate(c *cli.Context) error {
+	var err error
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return fmt.Errorf("failed to open workspace set: %s", err)
+	}
+	root := wss.Root()
+	configured, err := root.GetSubscriptionConfig()
+	if err != nil {
+		return err
+	}
+	subs, err := catalog.LoadSubscriptions(root)
+	if err != nil {
+		return err
+	}
+	if configured == nil {
+		defaults := catalog.DefaultSubscriptions()
+		for _, name := range subs.Catalogs.Keys {
+			if _, isDefault := defaults.Catalogs.Values[name]; !isDefault {
+				fmt.Fprintf(c.App.ErrWriter, "%s: not in a subscription config, so updating from its origin %s\n", name, subs.Catalogs.Values[name].Source)
+			}
+		}
+	}
+
+	// get the catalog path for the root workspace
+	catalogPath := filepath.Join("/", root.CatalogBasePath())
+	// create the path if it does not exist
+	if _, err := os.Stat(catalogPath); os.IsNotExist(err) {
+		err = os.MkdirAll(catalogPath, 0755)
+		if err != nil {
+			return fmt.Errorf("failed to create catalog path: %s", err)
+		}
+	}
+
+	failed := 0
+	for _, name := range subs.Catalogs.Keys {
+		sub := subs.Catalogs.Values[name]
+		if !sub.IsEnabled() {
+			if !c.Bool("quiet") {
+				fmt.Fprintf(c.App.Writer, "%s: subscription disabled\n", name)
+			}
+			continue
+		}
+		path, err := root.CatalogPath(name)
+		if err != nil {
+			return err
+		}
+		result, err := catalog.UpdateSubscription(c.Context, filepath.Join("/", path), sub)
+		if err != nil {
+			// keep going, so one unreachable catalog doesn't hold up the others
+			fmt.Fprintf(c.App.ErrWriter, "%s: update failed: %s\n", name, err)
+			failed++
+			continue
+		}
+		if c.Bool("quiet") {
+			continue
+		}
+		pinned := ""
+		if sub.Commit != nil {
+			pinned = " (pinned)"
+		}
+		switch {
+		case result.Cloned:
+			fmt.Fprintf(c.App.Writer, "%s: cloned at %s%s\n", name, result.New, pinned)
+		case result.Old == result.New:
+			fmt.Fprintf(c.App.Writer, "%s: already up to date at %s%s\n", name, result.New, pinned)
+		default:
+			fmt.Fprintf(c.App.Writer, "%s: updated %s -> %s%s (see changes with: warpforge catalog diff %s@%s)\n",
+				name, result.Old, result.New, pinned, name, result.Old)
+		}
+	}
+
+	// anything else in the catalogs directory is a local catalog, which updates leave alone
+	catalogs, err := os.ReadDir(catalogPath)
+	if err != nil {
+		return fmt.Errorf("failed to list catalog path: %s", err)
+	}
+	for _, cat := range catalogs {
+		if _, subscribed := subs.Catalogs.Values[cat.Name()]; subscribed || !cat.IsDir() {
+			continue
+		}
+		if !c.Bool("quiet") {
+			fmt.Fprintf(c.App.Writer, "%s: local catalog\n", cat.Name())
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d of %d subscribed catalogs", failed, len(subs.Catalogs.Keys))
+	}
+	return nil
+}
+
+func cmdCatalogSubscribe(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog subscribe [--branch branch] [--commit hash] [--disable] [catalog name] [git URL or path]")
+	}
+	name := c.Args().Get(0)
+	sub := wfapi.CatalogSubscription{Source: c.Args().Get(1)}
+	if c.IsSet("branch") {
+		branch := c.String("branch")
+		sub.Branch = &branch
+	}
+	if c.IsSet("commit") {
+		commit := c.String("commit")
+		if !plumbing.IsHash(commit) {
+			return serum.Error(wfapi.ECodeArgument,
+				serum.WithMessageTemplate("pinned commit {{commit|q}} must be a full commit hash"),
+				serum.WithDetail("commit", commit),
+			)
+		}
+		sub.Commit = &commit
+	}
+	if c.Bool("disable") {
+		enabled := false
+		sub.Enabled = &enabled
+	}
+	// a local repository path is resolved now, so the subscription works from anywhere
+	if info, err := os.Stat(sub.Source); err == nil && info.IsDir() {
+		abs, err := filepath.Abs(sub.Source)
+		if err != nil {
+			return wfapi.ErrorIo("failed to get absolute path of catalog source", sub.Source, err)
+		}
+		sub.Source = abs
+	}
+
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	root := wss.Root()
+	if _, err := root.CatalogPath(name); err != nil {
+		return err
+	}
+	subs, err := catalog.LoadSubscriptions(root)
+	if err != nil {
+		return err
+	}
+	_, exists := subs.Catalogs.Values[name]
+	if !exists {
+		subs.Catalogs.Keys = append(subs.Catalogs.Keys, name)
+	}
+	subs.Catalogs.Values[name] = sub
+	if err := root.SetSubscriptionConfig(subs); err != nil {
+		return err
+	}
+
+	if exists {
+		fmt.Fprintf(c.App.Writer, "updated subscription of catalog %q to %s\n", name, sub.Source)
+	} else {
+		fmt.Fprintf(c.App.Writer, "subscribed catalog %q to %s\n", name, sub.Source)
+	}
+	fmt.Fprintf(c.App.Writer, "run \"warpforge catalog update\" to fetch it\n")
+	return nil
+}
+
+func cmdCatalogUnsubscribe(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog unsubscribe [--remove [--force]] [catalog name]")
+	}
+	name := c.Args().First()
+
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	root := wss.Root()
+	subs, err := catalog.LoadSubscriptions(root)
+	if err != nil {
+		return err
+	}
+	if _, exists := subs.Catalogs.Values[name]; !exists {
+		return fmt.Errorf("not subscribed to a catalog named %q", name)
+	}
+	delete(subs.Catalogs.Values, name)
+	for i, k := range subs.Catalogs.Keys {
+		if k == name {
+			subs.Catalogs.Keys = append(subs.Catalogs.Keys[:i], subs.Catalogs.Keys[i+1:]...)
+			break
+		}
+	}
+	if err := root.SetSubscriptionConfig(subs); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "unsubscribed from catalog %q\n", name)
+
+	if !c.Bool("remove") {
+		return nil
+	}
+	path, err := root.CatalogPath(name)
+	if err != nil {
+		return err
+	}
+	if err := catalog.RemoveCatalog(filepath.Join("/", path), c.Bool("force")); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "removed catalog %q\n", name)
+	return nil
+}
+
+func cmdCatalogRelease(c *cli.Context) error {
+	ctx := c.Context
+	var err error
+
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog release [release name]")
+	}
+	// metadata is checked first, so the plot isn't executed for nothing
+	moduleMetadata, releaseMetadata, err := metadataFromFlags(c)
+	if err != nil {
+		return err
+	}
 	catalogName := c.String("name")
-	wsSet, err := util.OpenWorkspaceSet()
+	wss, err := util.OpenWorkspaceSet()
 	if err != nil {
 		return err
 	}
-	cat, err := wsSet.Root().OpenCatalog(catalogName)
+	rootWs := wss.Root()
+	// create the catalog if it does not exist
+	exists, err := rootWs.HasCatalog(catalogName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err := rootWs.CreateCatalog(catalogName)
+		if err != nil {
+			return err
+		}
+	}
+
+	fsys := os.DirFS("/")
+	// get the module, release, and item values (in format `module:release:item`)
+	module, err := dab.ModuleFromFile(fsys, dab.MagicFilename_Module)
+	if err != nil {
+		return err
+	}
+
+	releaseName := c.Args().Get(0)
+	parent := wfapi.CatalogRef{
+		ModuleName:  module.Name,
+		ReleaseName: wfapi.ReleaseName(releaseName),
+		ItemName:    wfapi.ItemLabel(""), // replay is not item specific
+	}
+	if err := checkReleaseMetadataChange(c, rootWs, catalogName, parent, releaseMetadata); err != nil {
+		return err
+	}
+
+	fmt.Printf("building replay for module = %q, release = %q, executing plot...\n", module.Name, releaseName)
+	plot, err := util.PlotFromFile(dab.MagicFilename_Plot)
+	if err != nil {
+		return err
+	}
+
+	execCfg, err := config.PlotExecConfig(nil)
+	if err != nil {
+		return err
+	}
+	results, err := plotexec.Exec(ctx, execCfg, wss, wfapi.PlotCapsule{Plot: &plot}, wfapi.PlotExecConfig{Recursive: false})
+	if err != nil {
+		return err
+	}
+
+	cat, err := rootWs.OpenCatalog(catalogName)
+	if err != nil {
+		return err
+	}
+
+	for itemName, wareId := range results.Values {
+		ref := wfapi.CatalogRef{
+			ModuleName:  module.Name,
+			ReleaseName: wfapi.ReleaseName(releaseName),
+			ItemName:    wfapi.ItemLabel(itemName),
+		}
+
+		fmt.Println(ref.String(), "->", wareId)
+		err := cat.AddItem(ref, wareId, c.Bool("force"))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cat.AddReplay(parent, plot, c.Bool("force"))
+	if err != nil {
+		return err
+	}
+
+	return applyMetadata(&cat, parent, moduleMetadata, releaseMetadata)
+}
+
+// catalogMetadataFlags returns the flags for setting well-known metadata while adding to a catalog.
+// Values which describe the project are set on the module, and those specific to a release on the release.
+func catalogMetadataFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "description",
+			Usage: "Set the module's description, a single line",
+		},
+		&cli.StringFlag{
+			Name:  "homepage",
+			Usage: "Set the URL of the module's home page",
+		},
+		&cli.StringFlag{
+			Name:  "source",
+			Usage: "Set the URL of the module's source code",
+		},
+		&cli.StringSliceFlag{
+			Name:  "maintainer",
+			Usage: "Set the module's maintainers, such as 'Jane Doe <jane@example.com>'. May be repeated",
+		},
+		&cli.StringFlag{
+			Name:  "license",
+			Usage: "Set the release's license, as an SPDX license expression such as 'MIT' or 'Apache-2.0 OR MIT'. Refused on releases whose signatures the trust policy requires, unless --force is given",
+		},
+		&cli.StringFlag{
+			Name:  "release-date",
+			Usage: "Set the release's date, as YYYY-MM-DD. Refused on releases whose signatures the trust policy requires, unless --force is given",
+		},
+	}
+}
+
+// metadataFromFlags collects the metadata given by catalogMetadataFlags, and checks it.
+func metadataFromFlags(c *cli.Context) (module map[string]string, release map[string]string, err error) {
+	module = map[string]string{}
+	release = map[string]string{}
+	for flag, key := range map[string]string{
+		"description": wfapi.MetadataDescription,
+		"homepage":    wfapi.MetadataHomepage,
+		"source":      wfapi.MetadataSource,
+	} {
+		if c.IsSet(flag) {
+			module[key] = c.String(flag)
+		}
+	}
+	if c.IsSet("maintainer") {
+		module[wfapi.MetadataMaintainers] = strings.Join(c.StringSlice("maintainer"), ", ")
+	}
+	if c.IsSet("license") {
+		release[wfapi.MetadataLicense] = c.String("license")
+	}
+	if c.IsSet("release-date") {
+		release[wfapi.MetadataReleaseDate] = c.String("release-date")
+	}
+	for _, metadata := range []map[string]string{module, release} {
+		for key, value := range metadata {
+			if err := workspace.ValidateMetadataValue(key, value); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return module, release, nil
+}
+
+// checkReleaseMetadataChange refuses to set release metadata collected by metadataFromFlags
+// on a release whose signatures the trust policy requires, unless forced,
+// since setting it changes the release CID.
+func checkReleaseMetadataChange(c *cli.Context, ws *workspace.Workspace, catalogName string, ref wfapi.CatalogRef, release map[string]string) error {
+	if len(release) == 0 || c.Bool("force") {
+		return nil
+	}
+	if err := ws.CheckReleaseChange(catalogName, ref); err != nil {
+		return fmt.Errorf("%s; use --force to change it anyway, then sign it again", err)
+	}
+	return nil
+}
+
+// applyMetadata sets metadata collected by metadataFromFlags on a module and release,
+// in the order of wfapi.WellKnownMetadata.
+func applyMetadata(cat *workspace.Catalog, ref wfapi.CatalogRef, module map[string]string, release map[string]string) error {
+	for _, key := range wfapi.WellKnownMetadata {
+		if value, ok := module[key]; ok {
+			if err := cat.SetModuleMetadata(ref, key, value); err != nil {
+				return err
+			}
+		}
+		if value, ok := release[key]; ok {
+			if err := cat.SetReleaseMetadata(ref, key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func cmdCatalogVerifyReplay(c *cli.Context) error {
+	ctx := c.Context
+	if c.Args().Len() > 1 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog verify-replay [--report file] [--record] [module[:release]]")
+	}
+	moduleName, releaseName, _ := strings.Cut(c.Args().First(), ":")
+
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	catalogName := c.String("name")
+	cat, err := wss.Root().OpenCatalog(catalogName)
 	if err != nil {
 		return fmt.Errorf("failed to open catalog %q: %s", catalogName, err)
 	}
 
+	// find the releases to verify
+	var refs []wfapi.CatalogRef
+	modules := cat.Modules()
+	if moduleName != "" {
+		modules = []wfapi.ModuleName{wfapi.ModuleName(moduleName)}
+	}
+	for _, m := range modules {
+		ref := wfapi.CatalogRef{ModuleName: m}
+		if releaseName != "" {
+			ref.ReleaseName = wfapi.ReleaseName(releaseName)
+			refs = append(refs, ref)
+			continue
+		}
+		module, err := cat.GetModule(ref)
+		if err != nil {
+			return err
+		}
+		if module == nil {
+			return wfapi.ErrorMissingCatalogEntry(ref, false)
+		}
+		for _, r := range module.Releases.Keys {
+			ref.ReleaseName = r
+			refs = append(refs, ref)
+		}
+	}
+
+	// recording changes the releases, so refuse before running anything if that would make any untrusted
+	if c.Bool("record") && !c.Bool("force") {
+		for _, ref := range refs {
+			if replay, err := cat.GetReplay(ref); err != nil || replay == nil {
+				// releases without replays are never recorded, and other errors are reported as they're verified
+				continue
+			}
+			if err := wss.Root().CheckReleaseChange(catalogName, ref); err != nil {
+				return fmt.Errorf("%s; use --force to record the verification anyway, then sign it again", err)
+			}
+		}
+	}
+
+	execCfg, err := config.PlotExecConfig(nil)
+	if err != nil {
+		return err
+	}
+	pltCfg := wfapi.PlotExecConfig{Recursive: c.Bool("recursive")}
+
+	logger := logging.Ctx(ctx)
+	report := wfapi.ReplayVerificationReport{Releases: []wfapi.ReplayVerification{}}
+	verified, failed := 0, 0
 	for _, ref := range refs {
-		var err error
-		if ref.Name().IsTag() {
-			catalogRef := wfapi.CatalogRef{
-				ModuleName:  wfapi.ModuleName(moduleName),
-				ReleaseName: wfapi.ReleaseName(ref.Name().Short()),
-				ItemName:    wfapi.ItemLabel(itemName),
+		release, err := cat.GetRelease(ref)
+		if err != nil {
+			return err
+		}
+		if release == nil {
+			return wfapi.ErrorMissingCatalogEntry(ref, false)
+		}
+		replay, err := cat.GetReplay(ref)
+		if err != nil {
+			return err
+		}
+		row := wfapi.ReplayVerification{Module: ref.ModuleName, Release: ref.ReleaseName}
+		if replay == nil {
+			row.Status = wfapi.ReplayVerificationStatus_NoReplay
+			report.Releases = append(report.Releases, row)
+			if releaseName != "" {
+				// a release asked for by name which can't be verified is a failure
+				logger.Info("verify-replay", "%s:%s: release has no replay", ref.ModuleName, ref.ReleaseName)
+				failed++
 			}
-			wareId := wfapi.WareID{
-				Packtype: "git",
-				Hash:     ref.Hash().String(),
+			continue
+		}
+
+		result, err := plotexec.VerifyReplay(ctx, execCfg, wss, *release, *replay, pltCfg)
+		if err != nil {
+			// keep going, so one broken replay doesn't hold up the others
+			msg := err.Error()
+			row.Status = wfapi.ReplayVerificationStatus_Failed
+			row.Error = &msg
+			report.Releases = append(report.Releases, row)
+			failed++
+			continue
+		}
+		for _, item := range result.Items {
+			row.Items = append(row.Items, wfapi.ReplayVerificationItem{
+				Item:     item.Item,
+				Status:   item.Status,
+				Expected: item.Expected,
+				Produced: item.Produced,
+			})
+		}
+		if !result.Ok() {
+			row.Status = wfapi.ReplayVerificationStatus_Mismatch
+			report.Releases = append(report.Releases, row)
+			failed++
+			continue
+		}
+
+		verifiedAt := time.Now().UTC().Format(time.RFC3339)
+		row.Status = wfapi.ReplayVerificationStatus_Verified
+		row.VerifiedAt = &verifiedAt
+		verified++
+		if c.Bool("record") {
+			if err := cat.SetReleaseMetadata(ref, wfapi.ReleaseMetadataReplayVerified, verifiedAt); err != nil {
+				return err
+			}
+			sigs, err := cat.GetSignatures(ref)
+			if err != nil {
+				return err
+			}
+			if sigs != nil && len(sigs.Signatures) > 0 {
+				logger.Info("verify-replay", "the CID of %s:%s has changed, so it must be signed again", ref.ModuleName, ref.ReleaseName)
 			}
-			err = cat.AddItem(catalogRef, wareId, c.Bool("force"))
-			if err != nil && serum.Code(err) == "warpforge-error-catalog-item-already-exists" {
-				fmt.Printf("catalog already has item %s:%s:%s\n", catalogRef.ModuleName,
-					catalogRef.ReleaseName, catalogRef.ItemName)
+		}
+		report.Releases = append(report.Releases, row)
+	}
+
+	logger.PrintReplayVerificationReport("verify-replay", report)
+	if c.IsSet("report") {
+		reportSerial, err := ipld.Marshal(json.Encode, &report, wfapi.TypeSystem.TypeByName("ReplayVerificationReport"))
+		if err != nil {
+			return wfapi.ErrorSerialization("failed to serialize replay verification report", err)
+		}
+		if err := os.WriteFile(c.Path("report"), append(reportSerial, '\n'), 0644); err != nil {
+			return wfapi.ErrorIo("failed to write replay verification report", c.Path("report"), err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to verify %d of %d releases", failed, verified+failed)
+	}
+	return nil
+}
+
+func cmdIngestGitTags(c *cli.Context) error {
+	const usage = "invalid input. usage: warpforge catalog ingest-git-tags [--tag-pattern regexp [--release-name template]] [--all] [module name] [url] [item name]"
+	if c.Args().Len() != 3 {
+		return fmt.Errorf(usage)
+	}
+	ctx := c.Context
+	logger := logging.Ctx(ctx)
+
+	ref := wfapi.CatalogRef{
+		ModuleName: wfapi.ModuleName(c.Args().Get(0)),
+		ItemName:   wfapi.ItemLabel(c.Args().Get(2)),
+	}
+	url := c.Args().Get(1)
+
+	opts := catalog.TagIngestOptions{All: c.Bool("all")}
+	if c.IsSet("tag-pattern") {
+		pattern, err := regexp.Compile(c.String("tag-pattern"))
+		if err != nil {
+			return serum.Error(wfapi.ECodeArgument, serum.WithCause(err),
+				serum.WithMessageTemplate("invalid tag pattern {{pattern|q}}"),
+				serum.WithDetail("pattern", c.String("tag-pattern")),
+			)
+		}
+		opts.Pattern = pattern
+	}
+	if c.IsSet("release-name") {
+		if opts.Pattern == nil {
+			return serum.Error(wfapi.ECodeArgument,
+				serum.WithMessageLiteral("--release-name refers to the capture groups of --tag-pattern, which must be given too"),
+			)
+		}
+		opts.ReleaseName = c.String("release-name")
+	}
+
+	// open the workspace set and catalog
+	catalogName := c.String("name")
+	wsSet, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	cat, err := wsSet.Root().OpenCatalog(catalogName)
+	if err != nil {
+		return fmt.Errorf("failed to open catalog %q: %s", catalogName, err)
+	}
+
+	logger.Info("ingest", "listing tags of %s", url)
+	tags, err := catalog.ListUpstreamTags(ctx, url)
+	if err != nil {
+		return err
+	}
+	plan, err := catalog.PlanTagIngest(&cat, ref, tags, opts)
+	if err != nil {
+		return err
+	}
+	logger.Debug("ingest", "%d of %d tags selected", len(plan), len(tags))
+
+	// --force replaces the items of moved tags, but not in releases whose signatures the trust policy requires,
+	// since a tag which moved under a signed release is exactly what signing is meant to catch
+	force := c.Bool("force")
+	untrusted := map[wfapi.ReleaseName]error{}
+	var toIngest []catalog.UpstreamTag
+	for _, entry := range plan {
+		if force && entry.Action == wfapi.TagIngestAction_Moved {
+			err := wsSet.Root().CheckReleaseChange(catalogName, entry.Ref)
+			if serum.Code(err) == wfapi.ECodeCatalogUntrusted {
+				untrusted[entry.Ref.ReleaseName] = err
 				continue
-			} else if err != nil {
-				return fmt.Errorf("failed to add item to catalog: %s", err)
 			}
-			err = cat.AddByModuleMirror(catalogRef, wfapi.Packtype("git"), wfapi.WarehouseAddr(url))
 			if err != nil {
-				return fmt.Errorf("failed to add mirror: %s", err)
+				return err
 			}
-			fmt.Printf("adding item %s:%s:%s \t-> %s\n", catalogRef.ModuleName,
-				catalogRef.ReleaseName, catalogRef.ItemName, wareId)
-
+		}
+		if entry.Action == wfapi.TagIngestAction_Add || (force && entry.Action == wfapi.TagIngestAction_Moved) {
+			toIngest = append(toIngest, entry.Tag)
 		}
 	}
+	metadata, err := catalog.FetchTagMetadata(ctx, url, toIngest)
+	if err != nil {
+		return err
+	}
 
+	out := wfapi.TagIngestResults{Module: ref.ModuleName, Item: ref.ItemName, Tags: []wfapi.TagIngestResult{}}
+	for _, entry := range plan {
+		result := wfapi.TagIngestResult{
+			Tag:     entry.Tag.Name,
+			Release: entry.Ref.ReleaseName,
+			WareID:  entry.WareID,
+			Action:  entry.Action,
+		}
+		switch {
+		case untrusted[entry.Ref.ReleaseName] != nil:
+			logger.Info("ingest", "tag %q now points to %s, but %s; replace it by hand and sign it again, if the move is expected",
+				entry.Tag.Name, entry.WareID, untrusted[entry.Ref.ReleaseName])
+		case entry.Action == wfapi.TagIngestAction_Add || (force && entry.Action == wfapi.TagIngestAction_Moved):
+			if err := cat.AddItem(entry.Ref, entry.WareID, force); err != nil {
+				return err
+			}
+			if err := cat.AddByModuleMirror(entry.Ref, entry.WareID.Packtype, wfapi.WarehouseAddr(url)); err != nil {
+				return err
+			}
+			for _, key := range []string{wfapi.MetadataReleaseDate, wfapi.ReleaseMetadataTagMessage} {
+				if value, ok := metadata[entry.Tag.Name][key]; ok {
+					if err := cat.SetReleaseMetadata(entry.Ref, key, value); err != nil {
+						return err
+					}
+				}
+			}
+			result.Ingested = true
+			logger.Debug("ingest", "added %s:%s:%s -> %s", entry.Ref.ModuleName, entry.Ref.ReleaseName, entry.Ref.ItemName, entry.WareID)
+		case entry.Action == wfapi.TagIngestAction_Moved:
+			logger.Info("ingest", "tag %q now points to %s, which differs from %s:%s:%s; use --force to replace it",
+				entry.Tag.Name, entry.WareID, entry.Ref.ModuleName, entry.Ref.ReleaseName, entry.Ref.ItemName)
+		default:
+			logger.Debug("ingest", "skipping tag %q: %s", entry.Tag.Name, entry.Action)
+		}
+		out.Tags = append(out.Tags, result)
+	}
+	logger.PrintTagIngestResults("ingest", out)
 	return nil
 }
 
@@ -638,19 +1560,26 @@ func cmdCatalogShow(c *cli.Context) error {
 		return nil
 	}
 
+	releases, err := cat.OrderedReleases(mod.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get releases of module %q: %s", ref.ModuleName, err)
+	}
+
 	fmt.Println(mod.Name)
-	for nr, releaseName := range mod.Releases.Keys {
+	for nr, release := range releases {
+		releaseName := release.ReleaseName
+		status := ""
+		if release.Status != nil {
+			status = fmt.Sprintf(" (%s: %s)", release.Status.Kind, release.Status.Reason)
+		}
 		var chr string
-		if nr+1 < len(mod.Releases.Keys) {
-			fmt.Printf(" ├─ %s:%s\n", mod.Name, releaseName)
+		if nr+1 < len(releases) {
+			fmt.Printf(" ├─ %s:%s%s\n", mod.Name, releaseName, status)
 			chr = "│"
 		} else {
-			fmt.Printf(" └─ %s:%s\n", mod.Name, releaseName)
+			fmt.Printf(" └─ %s:%s%s\n", mod.Name, releaseName, status)
 			chr = " "
 		}
-		ref.ReleaseName = releaseName
-		release, _ := cat.GetRelease(ref)
-
 		for ni, itemName := range release.Items.Keys {
 			if ni+1 < len(release.Items.Keys) {
 				if c.Bool("verbose") {
@@ -671,6 +1600,166 @@ func cmdCatalogShow(c *cli.Context) error {
 	return nil
 }
 
+func cmdCatalogSearch(c *cli.Context) error {
+	if c.Args().Len() > 1 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog search [--metadata key[=pattern]] [--item pattern] [module pattern]")
+	}
+	query := workspace.CatalogQuery{
+		Module:   c.Args().First(),
+		Metadata: map[string]string{},
+		Items:    c.StringSlice("item"),
+	}
+	for _, m := range c.StringSlice("metadata") {
+		key, pattern, _ := strings.Cut(m, "=")
+		query.Metadata[key] = pattern
+	}
+
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	results, err := wss.SearchCatalogs(query)
+	if err != nil {
+		return err
+	}
+
+	if results == nil {
+		results = []wfapi.CatalogSearchResult{}
+	}
+	logging.Ctx(c.Context).PrintCatalogSearchResults("search", wfapi.CatalogSearchResults{Results: results})
+	return nil
+}
+
+func cmdCatalogDiff(c *cli.Context) error {
+	if c.Args().Len() < 1 || c.Args().Len() > 2 {
+		return fmt.Errorf("invalid input. usage: warpforge catalog diff [old catalog[@revision]] [new catalog[@revision]]")
+	}
+	oldArg := c.Args().Get(0)
+	newArg := c.Args().Get(1)
+	if newArg == "" {
+		var hasRevision bool
+		newArg, _, hasRevision = strings.Cut(oldArg, "@")
+		if !hasRevision {
+			return fmt.Errorf("invalid input: a catalog given alone must have a revision to compare it at, such as %q", oldArg+"@HEAD~1")
+		}
+	}
+
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	oldCat, err := openCatalogRevision(wss.Root(), oldArg)
+	if err != nil {
+		return err
+	}
+	newCat, err := openCatalogRevision(wss.Root(), newArg)
+	if err != nil {
+		return err
+	}
+	diff, err := workspace.DiffCatalogs(&oldCat, &newCat)
+	if err != nil {
+		return err
+	}
+
+	out := wfapi.CatalogDiff{Old: oldArg, New: newArg, Entries: []wfapi.CatalogDiffEntry{}}
+	for _, d := range diff {
+		d := d
+		entry := wfapi.CatalogDiffEntry{Kind: d.Kind, Module: d.Ref.ModuleName}
+		if d.Ref.ReleaseName != "" {
+			entry.Release = &d.Ref.ReleaseName
+		}
+		if d.Ref.ItemName != "" {
+			entry.Item = &d.Ref.ItemName
+		}
+		if d.Old != "" {
+			entry.Old = &d.Old
+		}
+		if d.New != "" {
+			entry.New = &d.New
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	logging.Ctx(c.Context).PrintCatalogDiff("diff", out)
+	return nil
+}
+
+func cmdCatalogRdeps(c *cli.Context) error {
+	const usage = "invalid input. usage: warpforge catalog rdeps [--ware packtype:hash | module:release[:item]]"
+	var query workspace.ReverseDependencyQuery
+	target := c.Args().First()
+	if c.IsSet("ware") {
+		if c.Args().Len() != 0 {
+			return fmt.Errorf(usage)
+		}
+		packtype, hash, ok := strings.Cut(c.String("ware"), ":")
+		if !ok || packtype == "" || hash == "" {
+			return fmt.Errorf("invalid ware %q, expected [packtype:hash]", c.String("ware"))
+		}
+		query.WareID = &wfapi.WareID{Packtype: wfapi.Packtype(packtype), Hash: hash}
+		target = query.WareID.String()
+	} else {
+		if c.Args().Len() != 1 {
+			return fmt.Errorf(usage)
+		}
+		parts := strings.Split(target, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid catalog reference %q, expected [module:release[:item]]", target)
+		}
+		ref := wfapi.CatalogRef{
+			ModuleName:  wfapi.ModuleName(parts[0]),
+			ReleaseName: wfapi.ReleaseName(parts[1]),
+		}
+		if len(parts) == 3 {
+			ref.ItemName = wfapi.ItemLabel(parts[2])
+		}
+		query.Ref = &ref
+	}
+
+	wss, err := util.OpenWorkspaceSet()
+	if err != nil {
+		return err
+	}
+	rdeps, err := wss.ReverseDependencies(query)
+	if err != nil {
+		return err
+	}
+
+	out := wfapi.ReverseDependencies{Target: target, Dependents: []wfapi.ReverseDependency{}}
+	for _, d := range rdeps {
+		out.Dependents = append(out.Dependents, wfapi.ReverseDependency{
+			Workspace: d.Workspace,
+			Catalog:   d.Catalog,
+			Module:    d.Ref.ModuleName,
+			Release:   d.Ref.ReleaseName,
+			Depth:     d.Depth,
+			Via:       d.Via,
+			Possible:  d.Possible,
+		})
+	}
+	logging.Ctx(c.Context).PrintReverseDependencies("rdeps", out)
+	return nil
+}
+
+// openCatalogRevision opens a catalog of the root workspace given as "name" or "name@revision".
+func openCatalogRevision(root *workspace.Workspace, arg string) (workspace.Catalog, error) {
+	name, revision, hasRevision := strings.Cut(arg, "@")
+	exists, err := root.HasCatalog(name)
+	if err != nil {
+		return workspace.Catalog{}, err
+	}
+	if !exists {
+		return workspace.Catalog{}, fmt.Errorf("catalog %q does not exist", name)
+	}
+	if !hasRevision {
+		return root.OpenCatalog(name)
+	}
+	path, err := root.CatalogPath(name)
+	if err != nil {
+		return workspace.Catalog{}, err
+	}
+	return workspace.OpenCatalogAtRevision(filepath.Join("/", path), revision)
+}
+
 func cmdGenerateHtml(c *cli.Context) error {
 	catalogName := c.String("name")
 
@@ -737,10 +1826,42 @@ func cmdGenerateHtml(c *cli.Context) error {
 	return nil
 }
 
+// reportError returns the message of an error for a report, or nil if there was no error.
+func reportError(err error) *string {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	return &msg
+}
+
 func cmdMirror(c *cli.Context) error {
 	ctx := c.Context
 	logger := logging.Ctx(ctx)
 
+	if c.Bool("rehash") && !c.Bool("check") {
+		return serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageLiteral("--rehash can only be used with --check"),
+		)
+	}
+	filter := mirroring.WareFilter{
+		Modules:   c.StringSlice("module"),
+		NewerThan: wfapi.ReleaseName(c.String("newer-than")),
+	}
+	for _, r := range c.StringSlice("release") {
+		filter.Releases = append(filter.Releases, wfapi.ReleaseName(r))
+	}
+	for _, i := range c.StringSlice("item") {
+		filter.Items = append(filter.Items, wfapi.ItemLabel(i))
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+	opts := mirroring.DefaultPushOptions
+	opts.Concurrency = c.Int("concurrency")
+	opts.Retries = c.Int("retries")
+	opts.Filter = filter
+
 	wsSet, err := util.OpenWorkspaceSet()
 	if err != nil {
 		return err
@@ -756,14 +1877,86 @@ func cmdMirror(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if c.Bool("check") {
+		return cmdMirrorCheck(c, cat, configs, filter)
+	}
 
-	for wareAddr, cfg := range configs.Values {
+	out := wfapi.MirrorReport{Warehouses: []wfapi.MirrorWarehouseReport{}}
+	failedWares, failedWarehouses := 0, 0
+	for _, wareAddr := range configs.Keys {
 		logger.Info("mirror", "mirroring to warehouse %q", wareAddr)
-		err = mirroring.PushToWarehouseAddr(ctx, *wsSet.Root(), cat, wareAddr, cfg)
+		row := wfapi.MirrorWarehouseReport{Warehouse: wareAddr, Wares: []wfapi.MirrorWareResult{}}
+		report, err := mirroring.PushToWarehouseAddr(ctx, *wsSet.Root(), cat, wareAddr, configs.Values[wareAddr], opts)
+		if err != nil {
+			row.Error = reportError(err)
+			failedWarehouses++
+		}
+		for _, result := range report.Results {
+			row.Wares = append(row.Wares, wfapi.MirrorWareResult{WareID: result.WareID, Ref: result.Ref, Status: result.Status, Error: reportError(result.Err)})
+		}
+		row.Pushed = report.Count(wfapi.MirrorPushStatus_Pushed)
+		row.AlreadyPresent = report.Count(wfapi.MirrorPushStatus_AlreadyPresent)
+		row.MissingLocally = report.Count(wfapi.MirrorPushStatus_MissingLocally)
+		row.Failed = report.Count(wfapi.MirrorPushStatus_Failed)
+		failedWares += row.Failed
+		out.Warehouses = append(out.Warehouses, row)
+	}
+
+	logger.PrintMirrorReport("mirror", out)
+	if failedWarehouses > 0 {
+		return fmt.Errorf("failed to mirror to %d of %d warehouses", failedWarehouses, len(configs.Keys))
+	}
+	if failedWares > 0 {
+		return fmt.Errorf("failed to push %d wares; run the mirror again to retry them", failedWares)
+	}
+	return nil
+}
+
+// cmdMirrorCheck is cmdMirror with the --check flag, reporting the wares each warehouse is missing.
+func cmdMirrorCheck(c *cli.Context, cat workspace.Catalog, configs wfapi.MirroringConfig, filter mirroring.WareFilter) error {
+	ctx := c.Context
+	logger := logging.Ctx(ctx)
+
+	opts := mirroring.CheckOptions{Concurrency: c.Int("concurrency"), Filter: filter}
+	if c.Bool("rehash") {
+		binPath, err := config.BinPath()
 		if err != nil {
 			return err
 		}
+		opts.Verify = fetch.RioVerifier(binPath)
+	}
+
+	out := wfapi.MirrorCheckReport{Warehouses: []wfapi.MirrorCheckWarehouseReport{}}
+	problems, failedWarehouses := 0, 0
+	for _, wareAddr := range configs.Keys {
+		logger.Info("mirror", "checking warehouse %q", wareAddr)
+		row := wfapi.MirrorCheckWarehouseReport{
+			Warehouse: wareAddr,
+			Rehashed:  opts.Verify != nil && fetch.Readable(wareAddr),
+			Wares:     []wfapi.MirrorCheckWareResult{},
+		}
+		report, err := mirroring.CheckWarehouseAddr(ctx, cat, wareAddr, configs.Values[wareAddr], opts)
+		if err != nil {
+			row.Error = reportError(err)
+			failedWarehouses++
+		}
+		for _, result := range report.Results {
+			row.Wares = append(row.Wares, wfapi.MirrorCheckWareResult{WareID: result.WareID, Ref: result.Ref, Status: result.Status, Error: reportError(result.Err)})
+		}
+		row.Ok = report.Count(wfapi.MirrorCheckStatus_Ok)
+		row.Missing = report.Count(wfapi.MirrorCheckStatus_Missing)
+		row.Corrupt = report.Count(wfapi.MirrorCheckStatus_Corrupt)
+		row.Failed = report.Count(wfapi.MirrorCheckStatus_Failed)
+		problems += row.Missing + row.Corrupt + row.Failed
+		out.Warehouses = append(out.Warehouses, row)
 	}
 
-	return err
+	logger.PrintMirrorCheckReport("mirror", out)
+	if failedWarehouses > 0 {
+		return fmt.Errorf("failed to check %d of %d warehouses", failedWarehouses, len(configs.Keys))
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d wares missing, corrupt, or unchecked in mirrors", problems)
+	}
+	return nil
 }
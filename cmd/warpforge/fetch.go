@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/warptools/warpforge/cmd/warpforge/internal/util"
+	"github.com/warptools/warpforge/pkg/config"
+	"github.com/warptools/warpforge/pkg/fetch"
+	"github.com/warptools/warpforge/pkg/logging"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+var fetchCmdDef = cli.Command{
+	Name:  "fetch",
+	Usage: "Fetch the wares a plot needs into the root workspace's warehouse, so that running it doesn't need the network",
+	Description: strings.Join([]string{
+		"Every ware the steps of the plot which would be executed will unpack is resolved, as for `warpforge run --dry-run`.",
+		"Wares not already in the root workspace's warehouse are fetched from the warehouses of the other workspaces,",
+		"or else from the mirrors the catalogs give for them, trying each mirror in turn.",
+		"Each fetched ware is verified to match its WareID before it's placed in the warehouse.",
+		"",
+		"Wares which are only known once an upstream step has executed can't be fetched ahead of time.",
+	}, "\n"),
+	ArgsUsage: "[plot file or module directory]",
+	Action: util.ChainCmdMiddleware(cmdFetch,
+		util.CmdMiddlewareLogging,
+		util.CmdMiddlewareTracingConfig,
+		util.CmdMiddlewareTracingSpan,
+	),
+}
+
+func cmdFetch(c *cli.Context) error {
+	ctx := c.Context
+	plotPath, err := plotPathFromArgs(c)
+	if err != nil {
+		return err
+	}
+	wss, err := workspace.FindWorkspaceStack(os.DirFS("/"), "", filepath.Dir(plotPath)[1:])
+	if err != nil {
+		return err
+	}
+	binPath, err := config.BinPath()
+	if err != nil {
+		return err
+	}
+
+	planned, err := util.PlanPlotWares(ctx, wss, wfapi.PlotExecConfig{}, plotPath)
+	if err != nil {
+		return err
+	}
+	// wares already in the cache are as good as in the warehouse, so they're only reported
+	var wares []fetch.Ware
+	for _, ware := range planned {
+		if !ware.Available {
+			wares = append(wares, fetch.Ware{WareID: ware.WareID, Ref: ware.Ref})
+		}
+	}
+	results, err := fetch.Fetch(ctx, wss, wares, fetch.Options{Verify: fetch.RioVerifier(binPath)})
+	if err != nil {
+		return err
+	}
+
+	out := wfapi.FetchReport{Wares: []wfapi.FetchWareResult{}}
+	for _, ware := range planned {
+		if ware.Available {
+			out.Wares = append(out.Wares, wfapi.FetchWareResult{WareID: ware.WareID, Ref: ware.Ref, Status: wfapi.FetchStatus_Present})
+		}
+	}
+	counts := map[wfapi.FetchStatus]int{wfapi.FetchStatus_Present: len(out.Wares)}
+	for _, result := range results {
+		ware := wfapi.FetchWareResult{WareID: result.WareID, Ref: result.Ref, Status: result.Status}
+		if result.Source != "" {
+			source := result.Source
+			ware.Source = &source
+		}
+		for _, err := range result.Errs {
+			ware.Errors = append(ware.Errors, err.Error())
+		}
+		counts[result.Status]++
+		out.Wares = append(out.Wares, ware)
+	}
+
+	logging.Ctx(ctx).PrintFetchReport("fetch", out)
+	if missing := counts[wfapi.FetchStatus_Unavailable] + counts[wfapi.FetchStatus_Failed]; missing > 0 {
+		return fmt.Errorf("failed to fetch %d wares", missing)
+	}
+	return nil
+}
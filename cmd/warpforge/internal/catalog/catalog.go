@@ -2,18 +2,108 @@ package catalog
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
 	"github.com/warptools/warpforge/pkg/logging"
 	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
 	"github.com/warptools/warpforge/wfapi"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/serum-errors/go-serum"
 	"go.opentelemetry.io/otel/trace"
 )
 
-const defaultCatalogUrl = "https://github.com/warptools/warpsys-catalog.git"
+const (
+	defaultCatalogName = "warpsys"
+	defaultCatalogUrl  = "https://github.com/warptools/warpsys-catalog.git"
+)
+
+// DefaultSubscriptions returns the subscriptions of a root workspace without a subscription config,
+// which is to the warpsys catalog only.
+func DefaultSubscriptions() wfapi.SubscriptionConfig {
+	var cfg wfapi.SubscriptionConfig
+	cfg.Catalogs.Keys = []string{defaultCatalogName}
+	cfg.Catalogs.Values = map[string]wfapi.CatalogSubscription{
+		defaultCatalogName: {Source: defaultCatalogUrl},
+	}
+	return cfg
+}
+
+// LoadSubscriptions returns the subscriptions of a root workspace.
+// If it has no subscription config, these are the default subscriptions,
+// plus a subscription to the origin remote of each other catalog which is a git clone,
+// so that catalogs cloned before subscriptions existed keep being updated.
+//
+// Errors:
+//
+//    - warpforge-error-io -- for errors reading the config, or listing the catalogs
+//    - warpforge-error-serialization -- when the config cannot be parsed
+//    - warpforge-error-datatoonew -- if the config is from a newer version of warpforge
+func LoadSubscriptions(ws *workspace.Workspace) (wfapi.SubscriptionConfig, error) {
+	cfg, err := ws.GetSubscriptionConfig()
+	if err != nil {
+		return wfapi.SubscriptionConfig{}, err
+	}
+	if cfg == nil {
+		return clonedSubscriptions(ws)
+	}
+	if cfg.Catalogs.Values == nil {
+		cfg.Catalogs.Values = map[string]wfapi.CatalogSubscription{}
+	}
+	return *cfg, nil
+}
+
+// clonedSubscriptions returns the default subscriptions, plus a subscription to the origin remote
+// of each other catalog of the root workspace which is a git clone.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the catalogs cannot be listed
+func clonedSubscriptions(ws *workspace.Workspace) (wfapi.SubscriptionConfig, error) {
+	cfg := DefaultSubscriptions()
+	basePath := filepath.Join("/", ws.CatalogBasePath())
+	entries, err := os.ReadDir(basePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, wfapi.ErrorIo("failed to list catalogs", basePath, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, exists := cfg.Catalogs.Values[name]; exists || !entry.IsDir() {
+			continue
+		}
+		source := originUrl(filepath.Join(basePath, name))
+		if source == "" {
+			// not a clone, so a local catalog
+			continue
+		}
+		cfg.Catalogs.Keys = append(cfg.Catalogs.Keys, name)
+		cfg.Catalogs.Values[name] = wfapi.CatalogSubscription{Source: source}
+	}
+	return cfg, nil
+}
+
+// originUrl returns the URL of the origin remote of the git repository at path,
+// or the empty string if it's not a git repository, or has no origin remote.
+func originUrl(path string) string {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return ""
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	return remote.Config().URLs[0]
+}
 
 // InstallDefaultRemoteCatalog creates the default catalog by cloning a remote catalog over network.
 // This function will do nothing if the default catalog already exists.
@@ -26,7 +116,7 @@ func InstallDefaultRemoteCatalog(ctx context.Context, path string) error {
 	log := logging.Ctx(ctx)
 	// install our default remote catalog as "default-remote" by cloning from git
 	// this will noop if the catalog already exists
-	defaultCatalogPath := filepath.Join(path, "warpsys")
+	defaultCatalogPath := filepath.Join(path, defaultCatalogName)
 	_, err := os.Stat(defaultCatalogPath)
 	if !os.IsNotExist(err) {
 		if err == nil {
@@ -37,18 +127,201 @@ func InstallDefaultRemoteCatalog(ctx context.Context, path string) error {
 	}
 
 	log.Info("", "installing default catalog to %s...", defaultCatalogPath)
+	_, err = UpdateSubscription(ctx, defaultCatalogPath, DefaultSubscriptions().Catalogs.Values[defaultCatalogName])
+	log.Info("", "installing default catalog complete")
+	return err
+}
+
+// UpdateResult describes what UpdateSubscription did to a catalog.
+type UpdateResult struct {
+	Cloned bool          // The catalog didn't exist, and was cloned.
+	Old    plumbing.Hash // The commit the catalog was at before the update; zero if it was cloned.
+	New    plumbing.Hash // The commit the catalog is at now.
+}
 
+// UpdateSubscription brings the catalog at path up to date with its subscription.
+// If the catalog does not exist, it's cloned from the subscription's source.
+// Otherwise the source is fetched, and the catalog's branch is reset to the pinned commit,
+// or to the newest commit of the subscribed branch (or of the branch it's already on, if none is given).
+// If the source has changed since the catalog was cloned, the new source is used.
+//
+// Catalogs with local changes are not updated, since those changes would be lost.
+//
+// Errors:
+//
+//    - warpforge-error-git -- when cloning or fetching fails, the catalog is not a git repository,
+//      has local changes, or the subscribed branch or commit does not exist
+func UpdateSubscription(ctx context.Context, path string, sub wfapi.CatalogSubscription) (UpdateResult, error) {
+	var result UpdateResult
+	repo, err := git.PlainOpen(path)
+	switch {
+	case errors.Is(err, git.ErrRepositoryNotExists):
+		if _, statErr := os.Stat(path); statErr == nil {
+			return result, wfapi.ErrorGit(fmt.Sprintf("catalog %q is not a git repository", path), err)
+		}
+		repo, err = cloneSubscription(ctx, path, sub)
+		if err != nil {
+			return result, err
+		}
+		result.Cloned = true
+	case err != nil:
+		return result, wfapi.ErrorGit(fmt.Sprintf("failed to open catalog %q", path), err)
+	default:
+		if err := fetchSubscription(ctx, repo, sub); err != nil {
+			return result, err
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return result, wfapi.ErrorGit("failed to get git HEAD", err)
+	}
+	if !result.Cloned {
+		result.Old = head.Hash()
+	}
+
+	branch := head.Name()
+	if sub.Branch != nil {
+		branch = plumbing.NewBranchReferenceName(*sub.Branch)
+	} else if !branch.IsBranch() {
+		return result, serum.Error(wfapi.ECodeGit,
+			serum.WithMessageTemplate("catalog {{path|q}} is not on a branch, and its subscription does not name one"),
+			serum.WithDetail("path", path),
+		)
+	}
+
+	var target plumbing.Hash
+	if sub.Commit != nil {
+		if !plumbing.IsHash(*sub.Commit) {
+			return result, serum.Error(wfapi.ECodeGit,
+				serum.WithMessageTemplate("pinned commit {{commit|q}} is not a full commit hash"),
+				serum.WithDetail("commit", *sub.Commit),
+			)
+		}
+		target = plumbing.NewHash(*sub.Commit)
+		if _, err := repo.CommitObject(target); err != nil {
+			return result, wfapi.ErrorGit(fmt.Sprintf("pinned commit %q not found in %q", *sub.Commit, sub.Source), err)
+		}
+	} else {
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch.Short()), true)
+		if err != nil {
+			return result, wfapi.ErrorGit(fmt.Sprintf("branch %q not found in %q", branch.Short(), sub.Source), err)
+		}
+		target = remoteRef.Hash()
+	}
+	result.New = target
+	if target == head.Hash() && branch == head.Name() {
+		return result, nil
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return result, wfapi.ErrorGit("failed to open git worktree", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return result, wfapi.ErrorGit("failed to get git status", err)
+	}
+	if !status.IsClean() {
+		return result, serum.Error(wfapi.ECodeGit,
+			serum.WithMessageTemplate("catalog {{path|q}} has local changes, which an update would lose"),
+			serum.WithDetail("path", path),
+		)
+	}
+	if branch != head.Name() {
+		_, err := repo.Reference(branch, false)
+		err = wt.Checkout(&git.CheckoutOptions{Branch: branch, Hash: target, Create: err != nil, Force: true})
+		if err != nil {
+			return result, wfapi.ErrorGit(fmt.Sprintf("failed to check out branch %q", branch.Short()), err)
+		}
+	}
+	if err := wt.Reset(&git.ResetOptions{Commit: target, Mode: git.HardReset}); err != nil {
+		return result, wfapi.ErrorGit(fmt.Sprintf("failed to reset catalog to %s", target), err)
+	}
+	return result, nil
+}
+
+// RemoveCatalog deletes the catalog at path, which was cloned by a subscription.
+// Catalogs with local changes, or which aren't git repositories (so their changes can't be checked for),
+// are only removed if force is set, since the changes would be lost.
+// Removing a catalog which doesn't exist does nothing.
+//
+// Errors:
+//
+//    - warpforge-error-git -- when the catalog has local changes, or is not a git repository
+//    - warpforge-error-io -- when the catalog cannot be removed
+func RemoveCatalog(path string, force bool) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if !force {
+		repo, err := git.PlainOpen(path)
+		if err != nil {
+			return wfapi.ErrorGit(fmt.Sprintf("catalog %q is not a git repository, so it may have changes a removal would lose", path), err)
+		}
+		wt, err := repo.Worktree()
+		if err != nil {
+			return wfapi.ErrorGit("failed to open git worktree", err)
+		}
+		status, err := wt.Status()
+		if err != nil {
+			return wfapi.ErrorGit("failed to get git status", err)
+		}
+		if !status.IsClean() {
+			return serum.Error(wfapi.ECodeGit,
+				serum.WithMessageTemplate("catalog {{path|q}} has local changes, which a removal would lose"),
+				serum.WithDetail("path", path),
+			)
+		}
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return wfapi.ErrorIo("failed to remove catalog", path, err)
+	}
+	return nil
+}
+
+// Errors:
+//
+//    - warpforge-error-git -- when cloning fails
+func cloneSubscription(ctx context.Context, path string, sub wfapi.CatalogSubscription) (*git.Repository, error) {
+	opts := &git.CloneOptions{URL: sub.Source}
+	if sub.Branch != nil {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(*sub.Branch)
+	}
 	gitCtx, gitSpan := tracing.Start(ctx, "clone catalog", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitClone))
 	defer gitSpan.End()
-	_, err = git.PlainCloneContext(gitCtx, defaultCatalogPath, false, &git.CloneOptions{
-		URL: defaultCatalogUrl,
-	})
+	repo, err := git.PlainCloneContext(gitCtx, path, false, opts)
 	tracing.EndWithStatus(gitSpan, err)
+	if err != nil {
+		return nil, wfapi.ErrorGit(fmt.Sprintf("unable to git clone catalog from %q", sub.Source), err)
+	}
+	return repo, nil
+}
 
-	log.Info("", "installing default catalog complete")
+// Errors:
+//
+//    - warpforge-error-git -- when fetching fails
+func fetchSubscription(ctx context.Context, repo *git.Repository, sub wfapi.CatalogSubscription) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return wfapi.ErrorGit("failed to read git config", err)
+	}
+	if remote, ok := cfg.Remotes["origin"]; ok && (len(remote.URLs) != 1 || remote.URLs[0] != sub.Source) {
+		remote.URLs = []string{sub.Source}
+		if err := repo.SetConfig(cfg); err != nil {
+			return wfapi.ErrorGit("failed to write git config", err)
+		}
+	}
 
+	gitCtx, gitSpan := tracing.Start(ctx, "fetch catalog", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitFetch))
+	defer gitSpan.End()
+	err = repo.FetchContext(gitCtx, &git.FetchOptions{RemoteName: "origin", Force: true})
+	if err == git.NoErrAlreadyUpToDate {
+		err = nil
+	}
+	tracing.EndWithStatus(gitSpan, err)
 	if err != nil {
-		return wfapi.ErrorGit("Unable to git clone catalog", err)
+		return wfapi.ErrorGit(fmt.Sprintf("unable to fetch catalog from %q", sub.Source), err)
 	}
 	return nil
 }
@@ -0,0 +1,178 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// constructs a root workspace in a temporary directory, returning it and its path
+func newTestRootWorkspace(t *testing.T) (*workspace.Workspace, string) {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, ".warpforge"), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, ".warpforge", "root"), nil, 0644), qt.IsNil)
+	root, err := workspace.OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	return root, rootPath
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// a source repository, published to a bare repository which is subscribed to
+	srcPath := filepath.Join(dir, "src")
+	src, err := git.PlainInit(srcPath, false)
+	qt.Assert(t, err, qt.IsNil)
+	srcWt, err := src.Worktree()
+	qt.Assert(t, err, qt.IsNil)
+	commit := func(contents string) plumbing.Hash {
+		qt.Assert(t, os.WriteFile(filepath.Join(srcPath, "file"), []byte(contents), 0644), qt.IsNil)
+		_, err := srcWt.Add("file")
+		qt.Assert(t, err, qt.IsNil)
+		hash, err := srcWt.Commit(contents, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		qt.Assert(t, err, qt.IsNil)
+		return hash
+	}
+	first := commit("one")
+	barePath := filepath.Join(dir, "bare.git")
+	bare, err := git.PlainClone(barePath, true, &git.CloneOptions{URL: srcPath})
+	qt.Assert(t, err, qt.IsNil)
+	publish := func() {
+		qt.Assert(t, bare.Fetch(&git.FetchOptions{RefSpecs: []config.RefSpec{"+refs/heads/*:refs/heads/*"}}), qt.IsNil)
+	}
+
+	catPath := filepath.Join(dir, "catalogs", "test")
+	sub := wfapi.CatalogSubscription{Source: barePath}
+	result, err := UpdateSubscription(ctx, catPath, sub)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, result, qt.Equals, UpdateResult{Cloned: true, New: first})
+
+	second := commit("two")
+	publish()
+	result, err = UpdateSubscription(ctx, catPath, sub)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, result, qt.Equals, UpdateResult{Old: first, New: second})
+	contents, err := os.ReadFile(filepath.Join(catPath, "file"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, string(contents), qt.Equals, "two")
+
+	// a pin holds the catalog at a commit, even when there are newer ones
+	commit("three")
+	publish()
+	pin := first.String()
+	sub.Commit = &pin
+	result, err = UpdateSubscription(ctx, catPath, sub)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, result, qt.Equals, UpdateResult{Old: second, New: first})
+	contents, err = os.ReadFile(filepath.Join(catPath, "file"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, string(contents), qt.Equals, "one")
+
+	// local changes aren't overwritten
+	sub.Commit = nil
+	qt.Assert(t, os.WriteFile(filepath.Join(catPath, "file"), []byte("local"), 0644), qt.IsNil)
+	_, err = UpdateSubscription(ctx, catPath, sub)
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeGit)
+}
+
+func TestLoadSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	ws, dir := newTestRootWorkspace(t)
+
+	// without any catalogs, only the default subscriptions are loaded
+	subs, err := LoadSubscriptions(ws)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, subs, qt.DeepEquals, DefaultSubscriptions())
+
+	// a catalog cloned before subscriptions existed, and a local catalog
+	srcPath := filepath.Join(dir, "src")
+	src, err := git.PlainInit(srcPath, false)
+	qt.Assert(t, err, qt.IsNil)
+	srcWt, err := src.Worktree()
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(srcPath, "file"), []byte("one"), 0644), qt.IsNil)
+	_, err = srcWt.Add("file")
+	qt.Assert(t, err, qt.IsNil)
+	head, err := srcWt.Commit("one", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	qt.Assert(t, err, qt.IsNil)
+	clonedPath := filepath.Join("/", ws.CatalogBasePath(), "mine")
+	_, err = git.PlainClone(clonedPath, false, &git.CloneOptions{URL: srcPath})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, os.MkdirAll(filepath.Join("/", ws.CatalogBasePath(), "local"), 0755), qt.IsNil)
+
+	// without a subscription config, the clone is subscribed to its origin, and the local catalog is left alone
+	subs, err = LoadSubscriptions(ws)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, subs.Catalogs.Keys, qt.DeepEquals, []string{defaultCatalogName, "mine"})
+	qt.Check(t, subs.Catalogs.Values["mine"], qt.DeepEquals, wfapi.CatalogSubscription{Source: srcPath})
+	result, err := UpdateSubscription(ctx, clonedPath, subs.Catalogs.Values["mine"])
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, result, qt.Equals, UpdateResult{Old: head, New: head})
+
+	// with a subscription config, only what it subscribes to is
+	var cfg wfapi.SubscriptionConfig
+	cfg.Catalogs.Keys = []string{"other"}
+	cfg.Catalogs.Values = map[string]wfapi.CatalogSubscription{"other": {Source: srcPath}}
+	qt.Assert(t, ws.SetSubscriptionConfig(cfg), qt.IsNil)
+	subs, err = LoadSubscriptions(ws)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, subs, qt.DeepEquals, cfg)
+}
+
+func TestRemoveCatalog(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	src, err := git.PlainInit(srcPath, false)
+	qt.Assert(t, err, qt.IsNil)
+	srcWt, err := src.Worktree()
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(srcPath, "file"), []byte("one"), 0644), qt.IsNil)
+	_, err = srcWt.Add("file")
+	qt.Assert(t, err, qt.IsNil)
+	_, err = srcWt.Commit("one", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	qt.Assert(t, err, qt.IsNil)
+	catPath := filepath.Join(dir, "catalogs", "test")
+	_, err = git.PlainClone(catPath, false, &git.CloneOptions{URL: srcPath})
+	qt.Assert(t, err, qt.IsNil)
+
+	// local changes and local catalogs are kept, unless forced
+	qt.Assert(t, os.WriteFile(filepath.Join(catPath, "file"), []byte("local"), 0644), qt.IsNil)
+	qt.Check(t, serum.Code(RemoveCatalog(catPath, false)), qt.Equals, wfapi.ECodeGit)
+	qt.Check(t, exists(catPath), qt.IsTrue)
+	localPath := filepath.Join(dir, "catalogs", "local")
+	qt.Assert(t, os.MkdirAll(localPath, 0755), qt.IsNil)
+	qt.Check(t, serum.Code(RemoveCatalog(localPath, false)), qt.Equals, wfapi.ECodeGit)
+	qt.Check(t, exists(localPath), qt.IsTrue)
+	qt.Assert(t, RemoveCatalog(localPath, true), qt.IsNil)
+	qt.Check(t, exists(localPath), qt.IsFalse)
+
+	// clean clones are removed
+	qt.Assert(t, os.WriteFile(filepath.Join(catPath, "file"), []byte("one"), 0644), qt.IsNil)
+	qt.Assert(t, RemoveCatalog(catPath, false), qt.IsNil)
+	qt.Check(t, exists(catPath), qt.IsFalse)
+	qt.Check(t, RemoveCatalog(catPath, false), qt.IsNil)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
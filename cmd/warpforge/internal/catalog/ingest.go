@@ -0,0 +1,265 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/serum-errors/go-serum"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// UpstreamTag is a tag of an upstream git repository, as advertised by the repository.
+type UpstreamTag struct {
+	Name   string        // The short name of the tag, such as "v1.2.3".
+	Commit plumbing.Hash // The commit the tag points to.
+	Object plumbing.Hash // The annotated tag object; zero for a lightweight tag.
+}
+
+// Annotated returns true if the tag is an annotated tag, which has its own date and message.
+func (tag UpstreamTag) Annotated() bool {
+	return !tag.Object.IsZero()
+}
+
+// ListUpstreamTags lists the tags of a remote git repository, sorted by name, without fetching any objects.
+// Annotated tags are told apart from lightweight tags by the peeled references the remote advertises;
+// a remote which doesn't advertise them has its annotated tags listed as lightweight tags of the tag objects.
+//
+// Errors:
+//
+//    - warpforge-error-git -- when the remote cannot be reached or listed
+func ListUpstreamTags(ctx context.Context, url string) ([]UpstreamTag, error) {
+	listCtx, listSpan := tracing.Start(ctx, "git ls-remote", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitLs))
+	defer listSpan.End()
+	tags, err := listUpstreamTags(listCtx, url)
+	tracing.EndWithStatus(listSpan, err)
+	return tags, err
+}
+
+// Errors:
+//
+//    - warpforge-error-git -- when the remote cannot be reached or listed
+func listUpstreamTags(ctx context.Context, url string) (_ []UpstreamTag, err error) {
+	// this is what git.Remote.List does, except that it drops the peeled references.
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, wfapi.ErrorGit(fmt.Sprintf("invalid git url %q", url), err)
+	}
+	cl, err := client.NewClient(ep)
+	if err != nil {
+		return nil, wfapi.ErrorGit(fmt.Sprintf("unsupported git url %q", url), err)
+	}
+	session, err := cl.NewUploadPackSession(ep, nil)
+	if err != nil {
+		return nil, wfapi.ErrorGit(fmt.Sprintf("failed to connect to %q", url), err)
+	}
+	defer session.Close()
+	advRefs, err := session.AdvertisedReferencesContext(ctx)
+	if err != nil {
+		return nil, wfapi.ErrorGit(fmt.Sprintf("failed to list references of %q", url), err)
+	}
+
+	var tags []UpstreamTag
+	for name, hash := range advRefs.References {
+		refName := plumbing.ReferenceName(name)
+		if !refName.IsTag() {
+			continue
+		}
+		tag := UpstreamTag{Name: refName.Short(), Commit: hash}
+		if peeled, ok := advRefs.Peeled[name]; ok {
+			tag.Object = hash
+			tag.Commit = peeled
+		}
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// FetchTagMetadata fetches the tag objects of the annotated tags given from a remote git repository,
+// and returns the release metadata each one provides, by tag name:
+// the date it was tagged, as wfapi.MetadataReleaseDate, and its message, as wfapi.ReleaseMetadataTagMessage.
+// Lightweight tags have no metadata, and nothing is fetched for them.
+//
+// Errors:
+//
+//    - warpforge-error-git -- when fetching fails, or the remote does not have a tag object
+func FetchTagMetadata(ctx context.Context, url string, tags []UpstreamTag) (map[string]map[string]string, error) {
+	var refSpecs []gitconfig.RefSpec
+	for _, tag := range tags {
+		if tag.Annotated() {
+			refName := plumbing.NewTagReferenceName(tag.Name)
+			refSpecs = append(refSpecs, gitconfig.RefSpec(fmt.Sprintf("+%s:%s", refName, refName)))
+		}
+	}
+	metadata := map[string]map[string]string{}
+	if len(refSpecs) == 0 {
+		return metadata, nil
+	}
+
+	storage := memory.NewStorage()
+	remote := git.NewRemote(storage, &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{url},
+	})
+	fetchCtx, fetchSpan := tracing.Start(ctx, "git fetch", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitFetch))
+	defer fetchSpan.End()
+	// only the tag objects are wanted, but the commits they point to come with them,
+	// so keep those as shallow as possible.
+	err := remote.FetchContext(fetchCtx, &git.FetchOptions{RefSpecs: refSpecs, Depth: 1, Tags: git.NoTags})
+	if err == git.NoErrAlreadyUpToDate {
+		err = nil
+	}
+	tracing.EndWithStatus(fetchSpan, err)
+	if err != nil {
+		return nil, wfapi.ErrorGit(fmt.Sprintf("failed to fetch tags from %q", url), err)
+	}
+
+	for _, tag := range tags {
+		if !tag.Annotated() {
+			continue
+		}
+		tagObject, err := object.GetTag(storage, tag.Object)
+		if err != nil {
+			return nil, wfapi.ErrorGit(fmt.Sprintf("failed to read tag %q fetched from %q", tag.Name, url), err)
+		}
+		values := map[string]string{
+			wfapi.MetadataReleaseDate: tagObject.Tagger.When.Format("2006-01-02"),
+		}
+		if message := strings.TrimSpace(tagObject.Message); message != "" {
+			values[wfapi.ReleaseMetadataTagMessage] = message
+		}
+		metadata[tag.Name] = values
+	}
+	return metadata, nil
+}
+
+// TagIngestOptions configures which tags of an upstream repository PlanTagIngest turns into releases,
+// and how those releases are named.
+type TagIngestOptions struct {
+	// Pattern selects the tags to ingest. If nil, every tag is selected.
+	Pattern *regexp.Regexp
+
+	// ReleaseName is a template for the name of the release ingested from a tag,
+	// which may refer to the capture groups of Pattern as regexp.Expand does, such as "$1" or "${version}".
+	// If empty, or if there is no Pattern, releases are named after their tags.
+	ReleaseName string
+
+	// All considers every selected tag.
+	// Otherwise, tags for releases which would be ordered before the newest existing release of the module
+	// are skipped, so only tags added upstream since the last ingest are considered.
+	All bool
+}
+
+// TagIngest is the plan for ingesting one tag of an upstream repository into a catalog.
+type TagIngest struct {
+	Tag    UpstreamTag
+	Ref    wfapi.CatalogRef
+	WareID wfapi.WareID
+	Action wfapi.TagIngestAction
+}
+
+// PlanTagIngest works out which tags of an upstream repository should become items of releases of a module.
+// The module and item name are given by the ref, whose release name is ignored.
+// Each tag selected by the options gets a release, whose item is a git ware of the tagged commit.
+// The plan is ordered from the oldest release to the newest, as by workspace.CompareReleases.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when a tag's release name is empty, or two tags have the same release name
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a release does not match its CID
+func PlanTagIngest(cat *workspace.Catalog, ref wfapi.CatalogRef, tags []UpstreamTag, opts TagIngestOptions) ([]TagIngest, error) {
+	releases, err := cat.OrderedReleases(ref.ModuleName)
+	if err != nil {
+		return nil, err
+	}
+	var newest *wfapi.CatalogRelease
+	if len(releases) > 0 {
+		newest = &releases[len(releases)-1]
+	}
+
+	// existing releases are ordered by their version metadata, if they have any, so they're kept to sort the plan by
+	var plan []TagIngest
+	planReleases := map[wfapi.ReleaseName]*wfapi.CatalogRelease{}
+	tagsByRelease := map[wfapi.ReleaseName]string{}
+	for _, tag := range tags {
+		releaseName := tag.Name
+		if opts.Pattern != nil {
+			match := opts.Pattern.FindStringSubmatchIndex(tag.Name)
+			if match == nil {
+				continue
+			}
+			if opts.ReleaseName != "" {
+				releaseName = string(opts.Pattern.ExpandString(nil, opts.ReleaseName, tag.Name, match))
+			}
+		}
+		if releaseName == "" {
+			return nil, serum.Error(wfapi.ECodeArgument,
+				serum.WithMessageTemplate("tag {{tag|q}} has an empty release name"),
+				serum.WithDetail("tag", tag.Name),
+			)
+		}
+		if other, exists := tagsByRelease[wfapi.ReleaseName(releaseName)]; exists {
+			return nil, serum.Error(wfapi.ECodeArgument,
+				serum.WithMessageTemplate("tags {{tag|q}} and {{other|q}} both have the release name {{release|q}}"),
+				serum.WithDetail("tag", tag.Name),
+				serum.WithDetail("other", other),
+				serum.WithDetail("release", releaseName),
+			)
+		}
+		tagsByRelease[wfapi.ReleaseName(releaseName)] = tag.Name
+
+		ingest := TagIngest{
+			Tag: tag,
+			Ref: wfapi.CatalogRef{
+				ModuleName:  ref.ModuleName,
+				ReleaseName: wfapi.ReleaseName(releaseName),
+				ItemName:    ref.ItemName,
+			},
+			WareID: wfapi.WareID{Packtype: "git", Hash: tag.Commit.String()},
+			Action: wfapi.TagIngestAction_Add,
+		}
+		release, err := cat.GetRelease(ingest.Ref)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			if wareID, ok := release.Items.Values[ref.ItemName]; ok {
+				ingest.Action = wfapi.TagIngestAction_Exists
+				if wareID != ingest.WareID {
+					ingest.Action = wfapi.TagIngestAction_Moved
+				}
+			}
+		} else {
+			// a new release has no metadata yet, so only its name can order it
+			release = &wfapi.CatalogRelease{ReleaseName: ingest.Ref.ReleaseName}
+			if !opts.All && newest != nil && workspace.CompareReleases(release, newest) < 0 {
+				ingest.Action = wfapi.TagIngestAction_Older
+			}
+		}
+		planReleases[ingest.Ref.ReleaseName] = release
+		plan = append(plan, ingest)
+	}
+
+	sort.SliceStable(plan, func(i, j int) bool {
+		return workspace.CompareReleases(planReleases[plan[i].Ref.ReleaseName], planReleases[plan[j].Ref.ReleaseName]) < 0
+	})
+	return plan, nil
+}
@@ -0,0 +1,118 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestIngestTags(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// an upstream repository with a mix of annotated and lightweight tags
+	srcPath := filepath.Join(dir, "src")
+	src, err := git.PlainInit(srcPath, false)
+	qt.Assert(t, err, qt.IsNil)
+	srcWt, err := src.Worktree()
+	qt.Assert(t, err, qt.IsNil)
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Date(2022, 10, 19, 12, 0, 0, 0, time.UTC)}
+	commit := func(contents string) plumbing.Hash {
+		qt.Assert(t, os.WriteFile(filepath.Join(srcPath, "file"), []byte(contents), 0644), qt.IsNil)
+		_, err := srcWt.Add("file")
+		qt.Assert(t, err, qt.IsNil)
+		hash, err := srcWt.Commit(contents, &git.CommitOptions{Author: sig})
+		qt.Assert(t, err, qt.IsNil)
+		return hash
+	}
+	tag := func(name string, hash plumbing.Hash, message string) {
+		var opts *git.CreateTagOptions
+		if message != "" {
+			opts = &git.CreateTagOptions{Tagger: sig, Message: message}
+		}
+		_, err := src.CreateTag(name, hash, opts)
+		qt.Assert(t, err, qt.IsNil)
+	}
+	first := commit("one")
+	tag("v1.0.0", first, "First release\n")
+	second := commit("two")
+	tag("v1.1.0", second, "")
+	tag("nightly", second, "")
+
+	tags, err := ListUpstreamTags(ctx, srcPath)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, tags, qt.HasLen, 3)
+	qt.Check(t, tags[0].Name, qt.Equals, "nightly")
+	qt.Check(t, tags[1].Name, qt.Equals, "v1.0.0")
+	qt.Check(t, tags[1].Commit, qt.Equals, first)
+	qt.Check(t, tags[1].Annotated(), qt.IsTrue)
+	qt.Check(t, tags[2].Commit, qt.Equals, second)
+	qt.Check(t, tags[2].Annotated(), qt.IsFalse)
+
+	metadata, err := FetchTagMetadata(ctx, srcPath, tags)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, metadata, qt.DeepEquals, map[string]map[string]string{
+		"v1.0.0": {
+			wfapi.MetadataReleaseDate:       "2022-10-19",
+			wfapi.ReleaseMetadataTagMessage: "First release",
+		},
+	})
+
+	catPath := t.TempDir()
+	cat, err := workspace.OpenCatalog(os.DirFS("/"), catPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	ref := wfapi.CatalogRef{ModuleName: "example.com/src", ItemName: "src"}
+	opts := TagIngestOptions{Pattern: regexp.MustCompile(`^v(.*)$`), ReleaseName: "$1"}
+	plan, err := PlanTagIngest(&cat, ref, tags, opts)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, plan, qt.HasLen, 2)
+	qt.Check(t, plan[0].Ref.ReleaseName, qt.Equals, wfapi.ReleaseName("1.0.0"))
+	qt.Check(t, plan[0].WareID, qt.Equals, wfapi.WareID{Packtype: "git", Hash: first.String()})
+	qt.Check(t, plan[0].Action, qt.Equals, wfapi.TagIngestAction_Add)
+	qt.Check(t, plan[1].Ref.ReleaseName, qt.Equals, wfapi.ReleaseName("1.1.0"))
+	qt.Check(t, plan[1].Action, qt.Equals, wfapi.TagIngestAction_Add)
+
+	// once the newer release is in the catalog, older tags are skipped unless all are asked for,
+	// and a tag which has moved since it was ingested is noticed
+	qt.Assert(t, cat.AddItem(plan[1].Ref, wfapi.WareID{Packtype: "git", Hash: first.String()}, false), qt.IsNil)
+	plan, err = PlanTagIngest(&cat, ref, tags, opts)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, plan[0].Action, qt.Equals, wfapi.TagIngestAction_Older)
+	qt.Check(t, plan[1].Action, qt.Equals, wfapi.TagIngestAction_Moved)
+	opts.All = true
+	plan, err = PlanTagIngest(&cat, ref, tags, opts)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, plan[0].Action, qt.Equals, wfapi.TagIngestAction_Add)
+
+	// existing releases are ordered by their version metadata, not their names
+	cat, err = workspace.OpenCatalog(os.DirFS("/"), t.TempDir()[1:])
+	qt.Assert(t, err, qt.IsNil)
+	oldRef := wfapi.CatalogRef{ModuleName: ref.ModuleName, ReleaseName: "1.0.0", ItemName: ref.ItemName}
+	qt.Assert(t, cat.AddItem(oldRef, wfapi.WareID{Packtype: "git", Hash: first.String()}, false), qt.IsNil)
+	qt.Assert(t, cat.SetReleaseMetadata(oldRef, wfapi.ReleaseMetadataVersion, "3.0.0"), qt.IsNil)
+	opts.All = false
+	plan, err = PlanTagIngest(&cat, ref, tags, opts)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, plan, qt.HasLen, 2)
+	qt.Check(t, plan[0].Ref.ReleaseName, qt.Equals, wfapi.ReleaseName("1.1.0"))
+	qt.Check(t, plan[0].Action, qt.Equals, wfapi.TagIngestAction_Older)
+	qt.Check(t, plan[1].Ref.ReleaseName, qt.Equals, wfapi.ReleaseName("1.0.0"))
+	qt.Check(t, plan[1].Action, qt.Equals, wfapi.TagIngestAction_Exists)
+
+	// release names must be distinct
+	opts.Pattern = regexp.MustCompile(`^(v)`)
+	_, err = PlanTagIngest(&cat, ref, tags, opts)
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+}
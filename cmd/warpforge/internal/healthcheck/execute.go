@@ -102,7 +102,7 @@ func (e *ExecutionInfo) Run(ctx context.Context) error {
 	if err := catalog.InstallDefaultRemoteCatalog(ctx, catalogPath); err != nil {
 		return serum.Error(CodeRunFailure, serum.WithCause(err))
 	}
-	if err := wss.Tidy(ctx, *plotCapsule.Plot, true); err != nil {
+	if err := wss.Tidy(ctx, *plotCapsule.Plot, workspace.TidyConfig{Force: true}); err != nil {
 		return serum.Error(CodeRunFailure, serum.WithCause(err),
 			serum.WithMessageLiteral("Execution failed"),
 		)
@@ -3,6 +3,7 @@ package util
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -93,7 +94,8 @@ func canonicalizePath(pwd, path string) string {
 //    - warpforge-error-plot-execution-failed --
 //    - warpforge-error-plot-invalid -- when the plot data is invalid
 //    - warpforge-error-plot-step-failed --
-//    - warpforge-error-serialization -- when the module or plot cannot be parsed
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+//    - warpforge-error-serialization -- when the module, plot, or plot lock cannot be parsed
 //    - warpforge-error-workspace-missing -- when opening the workspace set fails
 //    - warpforge-error-datatoonew -- when error is too new
 //    - warpforge-error-searching-filesystem -- unexpected error traversing filesystem
@@ -128,6 +130,9 @@ func ExecModule(ctx context.Context, wss workspace.WorkspaceSet, pltCfg wfapi.Pl
 	if werr != nil {
 		return result, werr
 	}
+	if werr := loadPlotLock(fsys, modulePath, &pltCfg); werr != nil {
+		return result, werr
+	}
 
 	result, werr = plotexec.Exec(ctx, execCfg, wss, wfapi.PlotCapsule{Plot: plot}, pltCfg)
 
@@ -138,3 +143,202 @@ func ExecModule(ctx context.Context, wss workspace.WorkspaceSet, pltCfg wfapi.Pl
 	span.SetStatus(codes.Ok, "")
 	return result, nil
 }
+
+// PlanModule performs a dry run of the given module file with the default plot file in the same directory.
+// Nothing is executed; the returned plan describes what ExecModule would do.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid --
+//    - warpforge-error-catalog-parse --
+//    - warpforge-error-missing -- when a required file is missing
+//    - warpforge-error-io -- when the module or plot files cannot be read
+//    - warpforge-error-module-invalid -- when the module data is invalid
+//    - warpforge-error-plot-invalid -- when the plot data is invalid
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+//    - warpforge-error-serialization -- when the module, plot, plot lock, or a memo cannot be parsed
+//    - warpforge-error-workspace-missing -- when opening the workspace set fails
+//    - warpforge-error-datatoonew -- when error is too new
+//    - warpforge-error-searching-filesystem -- unexpected error traversing filesystem
+//    - warpforge-error-initialization -- fail to get working directory or executable path
+func PlanModule(ctx context.Context, wss workspace.WorkspaceSet, pltCfg wfapi.PlotExecConfig, fileName string) (result wfapi.PlotPlan, err error) {
+	ctx, span := tracing.StartFn(ctx, "planModule")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	fsys := os.DirFS("/")
+
+	if _, err := dab.ModuleFromFile(fsys, fileName); err != nil {
+		return result, err
+	}
+
+	moduleDir := filepath.Dir(fileName)
+	execCfg, err := config.PlotExecConfig(&moduleDir)
+	if err != nil {
+		return result, err
+	}
+	modulePath := canonicalizePath(execCfg.WorkingDirectory, filepath.Dir(fileName))
+
+	if wss == nil {
+		wss, err = workspace.FindWorkspaceStack(fsys, "", modulePath[1:])
+		if err != nil {
+			return result, err
+		}
+	}
+
+	plot, err := dab.PlotFromFile(fsys, filepath.Join(modulePath, dab.MagicFilename_Plot))
+	if err != nil {
+		return result, err
+	}
+	if err := loadPlotLock(fsys, modulePath, &pltCfg); err != nil {
+		return result, err
+	}
+
+	return plotexec.DryRun(ctx, execCfg, wss, wfapi.PlotCapsule{Plot: plot}, pltCfg)
+}
+
+// PlanPlotFile determines what executing the plot in a file would do, as by plotexec.Plan, without printing the plan.
+// The plot lock beside the plot file is used, if there is one, so the plan matches what "warpforge run" would do.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid --
+//    - warpforge-error-catalog-parse --
+//    - warpforge-error-missing -- when the plot file is missing
+//    - warpforge-error-io -- when the plot file cannot be read
+//    - warpforge-error-plot-invalid -- when the plot data is invalid
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+//    - warpforge-error-serialization -- when the plot, plot lock, or a memo cannot be parsed
+//    - warpforge-error-workspace-missing -- when opening the workspace set fails
+//    - warpforge-error-datatoonew -- when error is too new
+//    - warpforge-error-searching-filesystem -- unexpected error traversing filesystem
+//    - warpforge-error-initialization -- fail to get working directory or executable path
+func PlanPlotFile(ctx context.Context, wss workspace.WorkspaceSet, pltCfg wfapi.PlotExecConfig, plotPath string) (result wfapi.PlotPlan, err error) {
+	ctx, span := tracing.StartFn(ctx, "planPlotFile")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	fsys := os.DirFS("/")
+
+	plotDir := filepath.Dir(plotPath)
+	execCfg, err := config.PlotExecConfig(&plotDir)
+	if err != nil {
+		return result, err
+	}
+	if wss == nil {
+		wss, err = workspace.FindWorkspaceStack(fsys, "", plotDir[1:])
+		if err != nil {
+			return result, err
+		}
+	}
+
+	plot, err := dab.PlotFromFile(fsys, plotPath)
+	if err != nil {
+		return result, err
+	}
+	if err := loadPlotLock(fsys, plotDir, &pltCfg); err != nil {
+		return result, err
+	}
+
+	return plotexec.Plan(ctx, execCfg, wss, wfapi.PlotCapsule{Plot: plot}, pltCfg)
+}
+
+// PlanPlotWares lists the wares executing the plot in a file would unpack, as by plotexec.PlanWares.
+// The plot lock beside the plot file is used, if there is one.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid --
+//    - warpforge-error-catalog-parse --
+//    - warpforge-error-missing -- when the plot file is missing
+//    - warpforge-error-io -- when the plot file cannot be read
+//    - warpforge-error-plot-invalid -- when the plot data is invalid
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+//    - warpforge-error-serialization -- when the plot, plot lock, or a memo cannot be parsed
+//    - warpforge-error-workspace-missing -- when opening the workspace set fails
+//    - warpforge-error-datatoonew -- when error is too new
+//    - warpforge-error-searching-filesystem -- unexpected error traversing filesystem
+//    - warpforge-error-initialization -- fail to get working directory or executable path
+func PlanPlotWares(ctx context.Context, wss workspace.WorkspaceSet, pltCfg wfapi.PlotExecConfig, plotPath string) (result []plotexec.PlannedWare, err error) {
+	ctx, span := tracing.StartFn(ctx, "planPlotWares")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	fsys := os.DirFS("/")
+
+	plotDir := filepath.Dir(plotPath)
+	execCfg, err := config.PlotExecConfig(&plotDir)
+	if err != nil {
+		return result, err
+	}
+	if wss == nil {
+		wss, err = workspace.FindWorkspaceStack(fsys, "", plotDir[1:])
+		if err != nil {
+			return result, err
+		}
+	}
+
+	plot, err := dab.PlotFromFile(fsys, plotPath)
+	if err != nil {
+		return result, err
+	}
+	if err := loadPlotLock(fsys, plotDir, &pltCfg); err != nil {
+		return result, err
+	}
+
+	return plotexec.PlanWares(ctx, execCfg, wss, wfapi.PlotCapsule{Plot: plot}, pltCfg)
+}
+
+// loadPlotLock sets the plot lock in the config from the lock file in the module directory,
+// unless the config already has a lock or there is no lock file.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the lock file cannot be read
+//    - warpforge-error-serialization -- when the lock file cannot be parsed
+//    - warpforge-error-datatoonew -- when the lock file is from a newer version of warpforge
+func loadPlotLock(fsys fs.FS, modulePath string, pltCfg *wfapi.PlotExecConfig) error {
+	if pltCfg.Lock != nil {
+		return nil
+	}
+	lock, err := dab.PlotLockFromFile(fsys, filepath.Join(modulePath, dab.MagicFilename_PlotLock))
+	if err != nil {
+		if serum.Code(err) == wfapi.ECodeMissing {
+			return nil
+		}
+		// Error Codes -= warpforge-error-missing
+		return err
+	}
+	pltCfg.Lock = lock
+	return nil
+}
+
+// LockPlotFile resolves the catalog references of a plot file and writes the plot lock beside it.
+// If wss is nil, the workspace stack is found from the plot's directory.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the plot cannot be read or the lock cannot be written
+//    - warpforge-error-serialization -- when the plot cannot be parsed or the lock cannot be serialized
+//    - warpforge-error-plot-invalid -- when the plot data is invalid
+//    - warpforge-error-catalog-missing-entry -- when a catalog reference cannot be resolved
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-searching-filesystem -- unexpected error traversing filesystem
+func LockPlotFile(ctx context.Context, wss workspace.WorkspaceSet, plotPath string) (result wfapi.PlotLock, err error) {
+	ctx, span := tracing.StartFn(ctx, "lockPlotFile")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	plot, err := PlotFromFile(plotPath)
+	if err != nil {
+		return result, err
+	}
+	if wss == nil {
+		wss, err = workspace.FindWorkspaceStack(os.DirFS("/"), "", filepath.Dir(plotPath)[1:])
+		if err != nil {
+			return result, err
+		}
+	}
+	result, err = plotexec.LockPlot(ctx, wss, plot)
+	if err != nil {
+		return result, err
+	}
+	return result, dab.PlotLockToFile(result, filepath.Join(filepath.Dir(plotPath), dab.MagicFilename_PlotLock))
+}
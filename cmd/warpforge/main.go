@@ -81,7 +81,10 @@ func makeApp(stdin io.Reader, stdout, stderr io.Writer) *cli.App {
 		&healthCmdDef,
 		&wareCmdDef,
 		&planCmdDef,
+		&plotCmdDef,
 		&sparkCmdDef,
+		&bundleCmdDef,
+		&fetchCmdDef,
 	}
 	return app
 }
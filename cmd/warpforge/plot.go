@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/serum-errors/go-serum"
+	"github.com/urfave/cli/v2"
+
+	"github.com/warptools/warpforge/cmd/warpforge/internal/util"
+	"github.com/warptools/warpforge/pkg/dab"
+	"github.com/warptools/warpforge/pkg/plotexec"
+	"github.com/warptools/warpforge/pkg/plotgraph"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+var plotCmdDef = cli.Command{
+	Name:  "plot",
+	Usage: "Subcommands that inspect plots",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "graph",
+			Usage:     "Render a plot as a graph of steps, inputs, and pipes",
+			ArgsUsage: "[plot file or module directory]",
+			Action: util.ChainCmdMiddleware(cmdPlotGraph,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Graph syntax to render: 'dot' or 'mermaid'",
+					Value: string(plotgraph.Format_Dot),
+				},
+				&cli.BoolFlag{
+					Name:  "annotate",
+					Usage: "Annotate steps with their memo status and formula IDs, and pipes with the WareIDs of the last run",
+				},
+			},
+		},
+		{
+			Name:      "lock",
+			Usage:     "Resolve every catalog reference in a plot and record the results in a plot.lock beside it",
+			ArgsUsage: "[plot file or module directory]",
+			Action: util.ChainCmdMiddleware(cmdPlotLock,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+		},
+		{
+			Name:      "upgrade",
+			Usage:     "Change the catalog references in a plot to the newest releases in the workspace catalogs, and rewrite it in place",
+			ArgsUsage: "[plot file or module directory]",
+			Action: util.ChainCmdMiddleware(cmdPlotUpgrade,
+				util.CmdMiddlewareLogging,
+				util.CmdMiddlewareTracingConfig,
+				util.CmdMiddlewareTracingSpan,
+			),
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:    "module",
+					Aliases: []string{"m"},
+					Usage:   "Only upgrade references to this module. May be repeated",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Report the upgrades without rewriting the plot",
+				},
+			},
+		},
+	},
+}
+
+// plotPathFromArgs returns the absolute path of the plot file named by the command's argument.
+// The argument may be a plot file or a directory containing one, and defaults to the working directory.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when more than one argument is given
+//    - warpforge-error-io -- when the working directory or absolute path cannot be determined
+func plotPathFromArgs(c *cli.Context) (string, error) {
+	if c.Args().Len() > 1 {
+		return "", serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageLiteral("too many arguments"),
+		)
+	}
+
+	plotPath := c.Args().First()
+	if plotPath == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", wfapi.ErrorIo("unable to get working directory", "", err)
+		}
+		plotPath = cwd
+	}
+	plotPath, err := filepath.Abs(plotPath)
+	if err != nil {
+		return "", wfapi.ErrorIo("unable to get absolute path", plotPath, err)
+	}
+	if info, err := os.Stat(plotPath); err == nil && info.IsDir() {
+		plotPath = filepath.Join(plotPath, dab.MagicFilename_Plot)
+	}
+	return plotPath, nil
+}
+
+func cmdPlotLock(c *cli.Context) error {
+	plotPath, err := plotPathFromArgs(c)
+	if err != nil {
+		return err
+	}
+	lock, err := util.LockPlotFile(c.Context, nil, plotPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "locked %d catalog references in %s\n", len(lock.CatalogRefs.Keys),
+		filepath.Join(filepath.Dir(plotPath), dab.MagicFilename_PlotLock))
+	return nil
+}
+
+func cmdPlotUpgrade(c *cli.Context) error {
+	ctx := c.Context
+	plotPath, err := plotPathFromArgs(c)
+	if err != nil {
+		return err
+	}
+	plot, err := util.PlotFromFile(plotPath)
+	if err != nil {
+		return err
+	}
+	wss, err := workspace.FindWorkspaceStack(os.DirFS("/"), "", filepath.Dir(plotPath)[1:])
+	if err != nil {
+		return err
+	}
+
+	cfg := plotexec.UpgradeConfig{}
+	for _, m := range c.StringSlice("module") {
+		cfg.Modules = append(cfg.Modules, wfapi.ModuleName(m))
+	}
+	upgrades, err := plotexec.UpgradePlot(ctx, wss, &plot, cfg)
+	if err != nil {
+		return err
+	}
+	for _, u := range upgrades {
+		fmt.Fprintf(c.App.Writer, "%s:%s: %s -> %s\n", u.From.ModuleName, u.From.ItemName, u.From.ReleaseName, u.To.ReleaseName)
+	}
+	if len(upgrades) == 0 {
+		fmt.Fprintf(c.App.Writer, "all catalog references in %s are up to date\n", plotPath)
+		return nil
+	}
+	if c.Bool("dry-run") {
+		fmt.Fprintf(c.App.Writer, "would upgrade %d catalog references in %s\n", len(upgrades), plotPath)
+		return nil
+	}
+	if err := dab.PlotToFile(plot, plotPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.App.Writer, "upgraded %d catalog references in %s\n", len(upgrades), plotPath)
+
+	// an existing lock would now be out of date, so it's updated too
+	lockPath := filepath.Join(filepath.Dir(plotPath), dab.MagicFilename_PlotLock)
+	if _, err := os.Stat(lockPath); err == nil {
+		if _, err := util.LockPlotFile(ctx, wss, plotPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.App.Writer, "updated %s\n", lockPath)
+	}
+	return nil
+}
+
+func cmdPlotGraph(c *cli.Context) error {
+	ctx := c.Context
+	plotPath, err := plotPathFromArgs(c)
+	if err != nil {
+		return err
+	}
+	plot, err := util.PlotFromFile(plotPath)
+	if err != nil {
+		return err
+	}
+
+	var annotations plotgraph.Annotations
+	if c.Bool("annotate") {
+		plotDir := filepath.Dir(plotPath)
+		wss, err := workspace.FindWorkspaceStack(os.DirFS("/"), "", plotDir[1:])
+		if err != nil {
+			return err
+		}
+		// the plan uses the plot lock, as running the plot would
+		plan, err := util.PlanPlotFile(ctx, wss, wfapi.PlotExecConfig{}, plotPath)
+		if err != nil {
+			return err
+		}
+		annotations = make(plotgraph.Annotations, len(plan.Steps))
+		for _, step := range plan.Steps {
+			ann := plotgraph.StepAnnotation{Status: step.Status}
+			if step.FormulaID != nil {
+				ann.FormulaID = *step.FormulaID
+				memo, err := wss.Root().LoadMemo(*step.FormulaID)
+				if err != nil {
+					return err
+				}
+				if memo != nil {
+					ann.Outputs = make(map[wfapi.LocalLabel]wfapi.WareID)
+					for label, result := range memo.Results.Values {
+						if result.WareID != nil {
+							ann.Outputs[wfapi.LocalLabel(label)] = *result.WareID
+						}
+					}
+				}
+			}
+			annotations[step.Step] = ann
+		}
+	}
+
+	return plotgraph.Render(c.App.Writer, plot, plotgraph.Format(c.String("format")), annotations)
+}
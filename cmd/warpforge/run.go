@@ -5,9 +5,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/ipld/go-ipld-prime"
 	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
 	"github.com/urfave/cli/v2"
 
 	"github.com/warptools/warpforge/cmd/warpforge/internal/util"
@@ -38,6 +40,23 @@ var runCmdDef = cli.Command{
 			Aliases: []string{"f"},
 			Usage:   "Force execution, even if memoized formulas exist",
 		},
+		&cli.GenericFlag{
+			Name:  "input",
+			Usage: "Override a plot input, as `LABEL=PLOTINPUT` (e.g. 'rootfs=ware:tar:abcd...'). May be repeated",
+			Value: &plotInputOverrides{},
+		},
+		&cli.StringSliceFlag{
+			Name:  "step",
+			Usage: "Execute only the named step and the steps it depends on. May be repeated. Results contain the outputs of the named steps",
+		},
+		&cli.BoolFlag{
+			Name:  "update-lock",
+			Usage: "Re-resolve catalog references and rewrite the module's plot.lock before running",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Report what would be executed, memoized, or blocked for each step, without executing anything",
+		},
 	},
 }
 
@@ -50,6 +69,27 @@ func cmdRun(c *cli.Context) error {
 			DisableMemoization: c.Bool("force"),
 		},
 	}
+	if overrides, ok := c.Generic("input").(*plotInputOverrides); ok && len(overrides.values) > 0 {
+		pltCfg.InputOverrides = overrides.values
+	}
+	for _, step := range c.StringSlice("step") {
+		pltCfg.Steps = append(pltCfg.Steps, wfapi.StepName(step))
+	}
+
+	runModule := func(fileName string) error {
+		if c.Bool("update-lock") {
+			plotPath := filepath.Join(filepath.Dir(fileName), dab.MagicFilename_Plot)
+			if _, err := util.LockPlotFile(ctx, nil, plotPath); err != nil {
+				return err
+			}
+		}
+		if c.Bool("dry-run") {
+			_, err := util.PlanModule(ctx, nil, pltCfg, fileName)
+			return err
+		}
+		_, err := util.ExecModule(ctx, nil, pltCfg, fileName)
+		return err
+	}
 
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -59,11 +99,7 @@ func cmdRun(c *cli.Context) error {
 	if !c.Args().Present() {
 		filename := filepath.Join(cwd, dab.MagicFilename_Module) // execute the module in the current directory
 		logger.Debug("", "working directory module: %s", filename)
-		_, err = util.ExecModule(ctx, nil, pltCfg, filename)
-		if err != nil {
-			return err
-		}
-		return nil
+		return runModule(filename)
 	}
 
 	if filepath.Base(c.Args().First()) == "..." {
@@ -78,8 +114,7 @@ func cmdRun(c *cli.Context) error {
 					if c.Bool("verbose") {
 						logger.Debug("", "executing %q", path)
 					}
-					_, err = util.ExecModule(ctx, nil, pltCfg, path)
-					if err != nil {
+					if err := runModule(path); err != nil {
 						return err
 					}
 				}
@@ -97,8 +132,7 @@ func cmdRun(c *cli.Context) error {
 			return err
 		}
 		if info.IsDir() {
-			_, err := util.ExecModule(ctx, nil, pltCfg, filepath.Join(fileName, dab.MagicFilename_Module))
-			if err != nil {
+			if err := runModule(filepath.Join(fileName, dab.MagicFilename_Module)); err != nil {
 				return err
 			}
 		} else {
@@ -110,6 +144,11 @@ func cmdRun(c *cli.Context) error {
 
 			switch t {
 			case dab.FileType_Formula:
+				if c.Bool("dry-run") {
+					return serum.Error(wfapi.ECodeArgument,
+						serum.WithMessageLiteral("dry run is only supported for modules"),
+					)
+				}
 				// unmarshal FormulaAndContext from file data
 				f, err := ioutil.ReadFile(fileName)
 				if err != nil {
@@ -137,8 +176,7 @@ func cmdRun(c *cli.Context) error {
 				}
 			case dab.FileType_Module:
 				logger.Debug("", "executing module")
-				_, err := util.ExecModule(ctx, nil, pltCfg, fileName)
-				if err != nil {
+				if err := runModule(fileName); err != nil {
 					return err
 				}
 			default:
@@ -148,3 +186,40 @@ func cmdRun(c *cli.Context) error {
 	}
 	return nil
 }
+
+// plotInputOverrides accumulates repeated `--input LABEL=PLOTINPUT` flags.
+// Values are parsed as they are set, so that invalid inputs are reported before anything runs.
+type plotInputOverrides struct {
+	values map[wfapi.LocalLabel]wfapi.PlotInput
+	args   []string
+}
+
+// Set parses and stores a single override.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when the value is not of the form LABEL=PLOTINPUT
+//    - warpforge-error-serialization -- when the plot input cannot be parsed
+func (o *plotInputOverrides) Set(value string) error {
+	label, input, found := strings.Cut(value, "=")
+	if !found || label == "" {
+		return serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageTemplate("invalid input override {{value|q}}: expected LABEL=PLOTINPUT"),
+			serum.WithDetail("value", value),
+		)
+	}
+	plotInput, err := wfapi.ParsePlotInput(input)
+	if err != nil {
+		return err
+	}
+	if o.values == nil {
+		o.values = make(map[wfapi.LocalLabel]wfapi.PlotInput)
+	}
+	o.values[wfapi.LocalLabel(label)] = plotInput
+	o.args = append(o.args, value)
+	return nil
+}
+
+func (o *plotInputOverrides) String() string {
+	return strings.Join(o.args, ", ")
+}
@@ -0,0 +1,155 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/dab"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// Creates a root workspace in a temporary directory.
+func newTestRoot(t *testing.T) *workspace.Workspace {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, dab.MagicFilename_Workspace), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, dab.MagicFilename_Workspace, "root"), nil, 0644), qt.IsNil)
+	root, err := workspace.OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, root.IsRootWorkspace(), qt.IsTrue)
+	return root
+}
+
+// Writes a fake ware whose contents are its hash, as understood by fakeScanWare.
+func writeTestWare(t *testing.T, ws *workspace.Workspace, wareId wfapi.WareID) {
+	path, err := ws.WarePath(wareId)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, os.MkdirAll(filepath.Dir(path), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(path, []byte(wareId.Hash), 0644), qt.IsNil)
+}
+
+func fakeScanWare(ctx context.Context, packtype wfapi.Packtype, path string) (wfapi.WareID, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return wfapi.WareID{}, err
+	}
+	return wfapi.WareID{Packtype: packtype, Hash: string(content)}, nil
+}
+
+func TestExportImport(t *testing.T) {
+	appRef := wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v1", ItemName: "x86_64"}
+	toolRef := wfapi.CatalogRef{ModuleName: "example.com/tool", ReleaseName: "v2", ItemName: "x86_64"}
+	otherRef := wfapi.CatalogRef{ModuleName: "example.com/other", ReleaseName: "v1", ItemName: "x86_64"}
+	appWare := wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+	toolWare := wfapi.WareID{Packtype: "tar", Hash: "bbbbbbbbbb"}
+	otherWare := wfapi.WareID{Packtype: "tar", Hash: "cccccccccc"}
+	toolMirror := wfapi.WarehouseAddr("https://example.com/warehouse")
+
+	src := newTestRoot(t)
+	cat, err := src.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, cat.AddItem(appRef, appWare, false), qt.IsNil)
+	qt.Assert(t, cat.AddItem(toolRef, toolWare, false), qt.IsNil)
+	qt.Assert(t, cat.AddItem(otherRef, otherWare, false), qt.IsNil)
+	qt.Assert(t, cat.AddByWareMirror(toolRef, toolWare, toolMirror), qt.IsNil)
+	// the app can be rebuilt from a replay which uses the tool
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err = ipld.Unmarshal([]byte(`{"plot.v1": {
+		"inputs": {"tool": "catalog:example.com/tool:v2:x86_64"},
+		"steps": {},
+		"outputs": {"x86_64": "pipe::tool"}
+	}}`), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, cat.AddReplay(appRef, *plotCapsule.Plot, false), qt.IsNil)
+	// only the tool's ware is available
+	writeTestWare(t, src, toolWare)
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	summary, err := Export(ctx, workspace.WorkspaceSet{src}, &buf, []wfapi.CatalogRef{{ModuleName: "example.com/app"}})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, summary.Releases, qt.DeepEquals, []wfapi.CatalogRef{
+		{ModuleName: "example.com/app", ReleaseName: "v1"},
+		{ModuleName: "example.com/tool", ReleaseName: "v2"},
+	})
+	qt.Check(t, summary.Wares, qt.DeepEquals, []wfapi.WareID{toolWare})
+	bundle := buf.Bytes()
+
+	t.Run("import", func(t *testing.T) {
+		dst := newTestRoot(t)
+		summary, err := Import(ctx, dst, bytes.NewReader(bundle), ImportConfig{Catalog: "imported", ScanWare: fakeScanWare})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, summary.Wares, qt.DeepEquals, []wfapi.WareID{toolWare})
+
+		imported, err := dst.OpenCatalog("imported")
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, imported.Modules(), qt.DeepEquals, []wfapi.ModuleName{"example.com/app", "example.com/tool"})
+		for _, ref := range []wfapi.CatalogRef{appRef, toolRef} {
+			expected, err := cat.GetRelease(ref)
+			qt.Assert(t, err, qt.IsNil)
+			actual, err := imported.GetRelease(ref)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, actual, qt.IsNotNil)
+			qt.Check(t, actual.Cid(), qt.Equals, expected.Cid())
+		}
+		replay, err := imported.GetReplay(appRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, replay, qt.IsNotNil)
+		qt.Check(t, replay.Cid(), qt.Equals, plotCapsule.Plot.Cid())
+		_, addr, err := imported.GetWare(toolRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, addr, qt.DeepEquals, &toolMirror)
+		qt.Check(t, dst.HasWare(toolWare), qt.IsTrue)
+
+		// importing again changes nothing
+		_, err = Import(ctx, dst, bytes.NewReader(bundle), ImportConfig{Catalog: "imported", ScanWare: fakeScanWare})
+		qt.Check(t, err, qt.IsNil)
+	})
+	t.Run("ware-mismatch", func(t *testing.T) {
+		dst := newTestRoot(t)
+		badScan := func(ctx context.Context, packtype wfapi.Packtype, path string) (wfapi.WareID, error) {
+			return wfapi.WareID{Packtype: packtype, Hash: "dddddddddd"}, nil
+		}
+		_, err := Import(ctx, dst, bytes.NewReader(bundle), ImportConfig{Catalog: "imported", ScanWare: badScan})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeBundleInvalid)
+		has, err := dst.HasCatalog("imported")
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, has, qt.IsFalse)
+		qt.Check(t, dst.HasWare(toolWare), qt.IsFalse)
+	})
+	t.Run("conflict", func(t *testing.T) {
+		dst := newTestRoot(t)
+		existing, err := dst.CreateOrOpenCatalog("imported")
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, existing.AddItem(toolRef, otherWare, false), qt.IsNil)
+
+		_, err = Import(ctx, dst, bytes.NewReader(bundle), ImportConfig{Catalog: "imported", ScanWare: fakeScanWare})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeAlreadyExists)
+		module, err := existing.GetModule(appRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, module, qt.IsNil)
+
+		_, err = Import(ctx, dst, bytes.NewReader(bundle), ImportConfig{Catalog: "imported", Force: true, ScanWare: fakeScanWare})
+		qt.Assert(t, err, qt.IsNil)
+		wareId, _, err := existing.GetWare(toolRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId, qt.DeepEquals, &toolWare)
+	})
+	t.Run("unexpected-entry", func(t *testing.T) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		qt.Assert(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../escape", Mode: 0644}), qt.IsNil)
+		qt.Assert(t, tw.Close(), qt.IsNil)
+		_, err := Import(ctx, newTestRoot(t), &buf, ImportConfig{Catalog: "imported", ScanWare: fakeScanWare})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeBundleInvalid)
+	})
+}
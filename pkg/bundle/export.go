@@ -0,0 +1,312 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/logging"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// A bundle is a tar archive with two top-level directories:
+// "catalog", laid out exactly like a catalog in a workspace,
+// and "warehouse", laid out exactly like a workspace warehouse.
+const (
+	catalogDir   = "catalog"
+	warehouseDir = "warehouse"
+)
+
+// Summary lists what was written to or read from a bundle.
+type Summary struct {
+	Releases []wfapi.CatalogRef // Module and release names of every release; item names are empty.
+	Wares    []wfapi.WareID
+}
+
+// Export writes a bundle containing catalog releases, their replays, mirrors and signatures, and their wares.
+//
+// Each selected reference names a module, and optionally a release;
+// without a release name, every release of the module is included.
+// Releases whose replays use other catalog references pull in those releases too, transitively,
+// so that everything needed to rebuild them travels with the bundle.
+// Modules are looked up in the catalogs of the workspace set in lookup order.
+//
+// Wares are read from the warehouses of the workspace set.
+// Wares which are not available but have a replay are left out, since they can be rebuilt.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-missing-entry -- when a selected module or release is not in any catalog
+//    - warpforge-error-catalog-invalid -- when a catalog in the workspace set is invalid
+//    - warpforge-error-catalog-parse -- when a catalog in the workspace set can't be parsed
+//    - warpforge-error-catalog-name -- honestly, shouldn't happen
+//    - warpforge-error-missing -- when a ware has no replay and is not in any warehouse
+//    - warpforge-error-io -- when reading catalogs or wares, or writing the bundle, fails
+//    - warpforge-error-serialization -- when serializing catalog data fails
+func Export(ctx context.Context, wss workspace.WorkspaceSet, w io.Writer, selection []wfapi.CatalogRef) (Summary, error) {
+	ctx, span := tracing.Start(ctx, "bundle.Export")
+	defer span.End()
+	logger := logging.Ctx(ctx)
+	summary := Summary{}
+
+	// the catalog part of the bundle is assembled on disk, then archived
+	tmp, errRaw := os.MkdirTemp("", "warpforge-bundle-")
+	if errRaw != nil {
+		return summary, wfapi.ErrorIo("failed to create temporary directory for bundle", "", errRaw)
+	}
+	defer os.RemoveAll(tmp)
+	out, err := workspace.OpenCatalog(os.DirFS("/"), filepath.Join(tmp, catalogDir))
+	if err != nil {
+		return summary, err
+	}
+
+	// expand modules into their releases
+	var refs []wfapi.CatalogRef
+	for _, sel := range selection {
+		sel.ItemName = ""
+		if sel.ReleaseName != "" {
+			refs = append(refs, sel)
+			continue
+		}
+		_, module, err := findModule(wss, sel)
+		if err != nil {
+			return summary, err
+		}
+		for _, releaseName := range module.Releases.Keys {
+			refs = append(refs, wfapi.CatalogRef{ModuleName: sel.ModuleName, ReleaseName: releaseName})
+		}
+	}
+
+	// refs grows as replays are found, until the closure is complete
+	seen := map[wfapi.CatalogRef]struct{}{}
+	wareSeen := map[wfapi.WareID]struct{}{}
+	rebuildable := map[wfapi.WareID]bool{}
+	for i := 0; i < len(refs); i++ {
+		ref := refs[i]
+		if _, ok := seen[ref]; ok {
+			continue
+		}
+		seen[ref] = struct{}{}
+
+		cat, module, err := findModule(wss, ref)
+		if err != nil {
+			return summary, err
+		}
+		release, err := cat.GetRelease(ref)
+		if err != nil {
+			return summary, err
+		}
+		if release == nil {
+			return summary, wfapi.ErrorMissingCatalogEntry(ref, false)
+		}
+		replay, err := cat.GetReplay(ref)
+		if err != nil {
+			return summary, err
+		}
+		if err := out.AddRelease(*module, *release, replay, false); err != nil {
+			return summary, err
+		}
+		if err := copyMirrors(cat, &out, ref, release); err != nil {
+			return summary, err
+		}
+		if err := copySignatures(cat, &out, ref, release); err != nil {
+			return summary, err
+		}
+		summary.Releases = append(summary.Releases, ref)
+		logger.Debug("", "bundling release \"%s:%s\"", ref.ModuleName, ref.ReleaseName)
+
+		for _, label := range release.Items.Keys {
+			wareId := release.Items.Values[label]
+			if _, ok := wareSeen[wareId]; !ok {
+				wareSeen[wareId] = struct{}{}
+				summary.Wares = append(summary.Wares, wareId)
+			}
+			rebuildable[wareId] = rebuildable[wareId] || replay != nil
+		}
+		if replay != nil {
+			for _, replayRef := range replay.CatalogRefs() {
+				replayRef.ItemName = ""
+				refs = append(refs, replayRef)
+			}
+		}
+	}
+
+	tw := tar.NewWriter(w)
+	catalogPath := filepath.Join(tmp, catalogDir)
+	errRaw = filepath.WalkDir(catalogPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, _ := filepath.Rel(tmp, path)
+		return writeTarFile(tw, filepath.ToSlash(name), path)
+	})
+	if errRaw != nil {
+		return summary, wfapi.ErrorIo("failed to write catalog to bundle", catalogPath, errRaw)
+	}
+
+	wares := summary.Wares[:0]
+	for _, wareId := range summary.Wares {
+		src := ""
+		for _, ws := range wss {
+			if ws.HasWare(wareId) {
+				src, _ = ws.WarePath(wareId)
+				break
+			}
+		}
+		if src == "" {
+			if rebuildable[wareId] {
+				logger.Info("", "ware %q is not available; it can be rebuilt from its replay", wareId.String())
+				continue
+			}
+			return summary, serum.Error(wfapi.ECodeMissing,
+				serum.WithMessageTemplate("ware {{wareID|q}} is not in any warehouse, and has no replay"),
+				serum.WithDetail("wareID", wareId.String()),
+			)
+		}
+		name := filepath.ToSlash(filepath.Join(warehouseDir, wareId.Subpath()))
+		if errRaw := writeTarFile(tw, name, src); errRaw != nil {
+			return summary, wfapi.ErrorIo("failed to write ware to bundle", src, errRaw)
+		}
+		wares = append(wares, wareId)
+	}
+	summary.Wares = wares
+
+	if errRaw := tw.Close(); errRaw != nil {
+		return summary, wfapi.ErrorIo("failed to write bundle", "", errRaw)
+	}
+	return summary, nil
+}
+
+// Finds the first catalog in the workspace set which has the module of the given reference,
+// and its release, if the reference names one.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-missing-entry -- when no catalog has the module or release
+//    - warpforge-error-catalog-invalid -- when a catalog is invalid
+//    - warpforge-error-catalog-parse -- when a catalog can't be parsed
+//    - warpforge-error-catalog-name -- honestly, shouldn't happen
+//    - warpforge-error-io -- when reading a catalog fails
+func findModule(wss workspace.WorkspaceSet, ref wfapi.CatalogRef) (*workspace.Catalog, *wfapi.CatalogModule, error) {
+	for _, ws := range wss {
+		names, err := ws.ListCatalogs()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range names {
+			cat, err := ws.OpenCatalog(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			module, err := cat.GetModule(ref)
+			if err != nil {
+				return nil, nil, err
+			}
+			if module == nil {
+				continue
+			}
+			if _, ok := module.Releases.Values[ref.ReleaseName]; ref.ReleaseName != "" && !ok {
+				continue
+			}
+			return &cat, module, nil
+		}
+	}
+	if ref.ReleaseName == "" {
+		return nil, nil, serum.Error(wfapi.ECodeCatalogMissingEntry,
+			serum.WithMessageTemplate("module {{module|q}} is not in any catalog"),
+			serum.WithDetail("module", string(ref.ModuleName)),
+		)
+	}
+	return nil, nil, serum.Error(wfapi.ECodeCatalogMissingEntry,
+		serum.WithMessageTemplate("release \"{{module}}:{{release}}\" is not in any catalog"),
+		serum.WithDetail("module", string(ref.ModuleName)),
+		serum.WithDetail("release", string(ref.ReleaseName)),
+	)
+}
+
+// Copies the mirrors of a release from one catalog to another.
+// ByWare mirrors are copied for the wares of the release; ByModule mirrors are copied for the whole module.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading or writing mirror files fails
+//    - warpforge-error-catalog-parse -- when parsing a mirror file fails
+//    - warpforge-error-serialization -- when serializing the mirror file fails
+//    - warpforge-error-catalog-invalid -- when the destination has the other type of mirror file
+func copyMirrors(src, dst *workspace.Catalog, ref wfapi.CatalogRef, release *wfapi.CatalogRelease) error {
+	mirrors, err := src.GetMirror(ref)
+	if err != nil || mirrors == nil {
+		return err
+	}
+	if mirrors.ByModule != nil {
+		byPacktype := mirrors.ByModule.Values[ref.ModuleName]
+		for _, packtype := range byPacktype.Keys {
+			for _, addr := range byPacktype.Values[packtype] {
+				if err := dst.AddByModuleMirror(ref, packtype, addr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if mirrors.ByWare != nil {
+		for _, label := range release.Items.Keys {
+			wareId := release.Items.Values[label]
+			for _, addr := range mirrors.ByWare.Values[wareId] {
+				if err := dst.AddByWareMirror(ref, wareId, addr); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Copies the signatures of a release from one catalog to another, if they are for exactly that release.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading or writing the signatures file fails
+//    - warpforge-error-catalog-parse -- when parsing the signatures file fails
+//    - warpforge-error-serialization -- when serializing the signatures fails
+func copySignatures(src, dst *workspace.Catalog, ref wfapi.CatalogRef, release *wfapi.CatalogRelease) error {
+	sigs, err := src.GetSignatures(ref)
+	if err != nil || sigs == nil || sigs.ReleaseCid != release.Cid() {
+		return err
+	}
+	return dst.PutSignatures(ref, *sigs)
+}
+
+// Writes a file into a tar archive under the given name.
+// Timestamps and ownership are normalized, so that bundles of the same content are identical.
+func writeTarFile(tw *tar.Writer, name string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     info.Size(),
+		Mode:     0644,
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,258 @@
+package bundle
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/logging"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// WareScanner computes the WareID of the ware in a file on the host, which has the given packtype.
+type WareScanner func(ctx context.Context, packtype wfapi.Packtype, path string) (wfapi.WareID, error)
+
+// ImportConfig controls how Import merges a bundle into the root workspace.
+type ImportConfig struct {
+	// Catalog names the root workspace catalog the bundle is merged into. It is created if needed.
+	Catalog string
+	// Force overwrites releases which differ from the ones in the bundle.
+	Force bool
+	// ScanWare is used to verify every ware in the bundle against its WareID. It must be set.
+	ScanWare WareScanner
+}
+
+// A release read from a bundle, with its module and replay.
+type importRelease struct {
+	module  *wfapi.CatalogModule
+	release *wfapi.CatalogRelease
+	replay  *wfapi.Plot
+}
+
+// Import verifies a bundle written by Export, and merges it into the root workspace's catalog and warehouse.
+//
+// Every release in the bundle is checked against the CID listed in its module,
+// every replay against the CID listed in its release,
+// and every ware against the WareID of the catalog items which use it.
+// Nothing is written to the root workspace until the whole bundle has been verified
+// and none of its releases conflict with existing ones.
+//
+// Errors:
+//
+//    - warpforge-error-bundle-invalid -- when the bundle is malformed, or a ware doesn't match its WareID
+//    - warpforge-error-catalog-invalid -- when a release or replay in the bundle doesn't match its CID
+//    - warpforge-error-catalog-parse -- when catalog data in the bundle or root workspace can't be parsed
+//    - warpforge-error-catalog-name -- when the catalog name is invalid
+//    - warpforge-error-already-exists -- when a release differs from an existing one and force is not set
+//    - warpforge-error-io -- when reading the bundle or writing the root workspace fails
+//    - warpforge-error-serialization -- when serializing catalog data fails
+//    - warpforge-error-executor-failed -- when scanning a ware fails
+func Import(ctx context.Context, root *workspace.Workspace, r io.Reader, cfg ImportConfig) (Summary, error) {
+	ctx, span := tracing.Start(ctx, "bundle.Import")
+	defer span.End()
+	logger := logging.Ctx(ctx)
+	summary := Summary{}
+
+	// extract next to the warehouse, so that wares can be moved into place
+	internalPath := filepath.Join("/", root.InternalPath())
+	tmp, errRaw := os.MkdirTemp(internalPath, "bundle-")
+	if errRaw != nil {
+		return summary, wfapi.ErrorIo("failed to create temporary directory for bundle", internalPath, errRaw)
+	}
+	defer os.RemoveAll(tmp)
+	if err := extract(r, tmp); err != nil {
+		return summary, err
+	}
+
+	// verify the catalog; GetRelease and GetReplay check CIDs as they read
+	src, err := workspace.OpenCatalog(os.DirFS("/"), filepath.Join(tmp, catalogDir))
+	if err != nil {
+		return summary, err
+	}
+	var releases []importRelease
+	wareIds := map[string]wfapi.WareID{}
+	for _, moduleName := range src.Modules() {
+		module, err := src.GetModule(wfapi.CatalogRef{ModuleName: moduleName})
+		if err != nil {
+			return summary, err
+		}
+		for _, releaseName := range module.Releases.Keys {
+			ref := wfapi.CatalogRef{ModuleName: moduleName, ReleaseName: releaseName}
+			release, err := src.GetRelease(ref)
+			if err != nil {
+				return summary, err
+			}
+			if release == nil {
+				return summary, serum.Error(wfapi.ECodeBundleInvalid,
+					serum.WithMessageTemplate("bundle lists release \"{{module}}:{{release}}\" but does not contain it"),
+					serum.WithDetail("module", string(moduleName)),
+					serum.WithDetail("release", string(releaseName)),
+				)
+			}
+			replay, err := src.GetReplay(ref)
+			if err != nil {
+				return summary, err
+			}
+			releases = append(releases, importRelease{module: module, release: release, replay: replay})
+			summary.Releases = append(summary.Releases, ref)
+			for _, wareId := range release.Items.Values {
+				wareIds[filepath.ToSlash(wareId.Subpath())] = wareId
+			}
+		}
+	}
+
+	// verify the wares; anything in the warehouse must belong to an item
+	warehousePath := filepath.Join(tmp, warehouseDir)
+	errRaw = filepath.WalkDir(warehousePath, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == warehousePath {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name, _ := filepath.Rel(warehousePath, path)
+		wareId, ok := wareIds[filepath.ToSlash(name)]
+		if !ok {
+			return serum.Error(wfapi.ECodeBundleInvalid,
+				serum.WithMessageTemplate("bundle contains ware {{name|q}} which no catalog item uses"),
+				serum.WithDetail("name", name),
+			)
+		}
+		actual, err := cfg.ScanWare(ctx, wareId.Packtype, path)
+		if err != nil {
+			return err
+		}
+		if actual != wareId {
+			return serum.Error(wfapi.ECodeBundleInvalid,
+				serum.WithMessageTemplate("ware in bundle does not match its WareID: expected {{expected|q}}, got {{actual|q}}"),
+				serum.WithDetail("expected", wareId.String()),
+				serum.WithDetail("actual", actual.String()),
+			)
+		}
+		summary.Wares = append(summary.Wares, wareId)
+		return nil
+	})
+	if errRaw != nil {
+		if serum.Code(errRaw) != "" {
+			return summary, errRaw
+		}
+		return summary, wfapi.ErrorIo("failed to read wares from bundle", warehousePath, errRaw)
+	}
+
+	// check for conflicts before writing anything
+	dst, err := root.CreateOrOpenCatalog(cfg.Catalog)
+	if err != nil {
+		return summary, err
+	}
+	if !cfg.Force {
+		for _, rel := range releases {
+			existing, err := dst.GetRelease(wfapi.CatalogRef{ModuleName: rel.module.Name, ReleaseName: rel.release.ReleaseName})
+			if err != nil {
+				return summary, err
+			}
+			if existing != nil && existing.Cid() != rel.release.Cid() {
+				return summary, serum.Error(wfapi.ECodeAlreadyExists,
+					serum.WithMessageTemplate("release \"{{module}}:{{release}}\" already exists in catalog {{catalog|q}} with different contents"),
+					serum.WithDetail("module", string(rel.module.Name)),
+					serum.WithDetail("release", string(rel.release.ReleaseName)),
+					serum.WithDetail("catalog", cfg.Catalog),
+				)
+			}
+		}
+	}
+
+	for _, rel := range releases {
+		ref := wfapi.CatalogRef{ModuleName: rel.module.Name, ReleaseName: rel.release.ReleaseName}
+		if err := dst.AddRelease(*rel.module, *rel.release, rel.replay, cfg.Force); err != nil {
+			return summary, err
+		}
+		if err := copyMirrors(&src, &dst, ref, rel.release); err != nil {
+			return summary, err
+		}
+		if err := copySignatures(&src, &dst, ref, rel.release); err != nil {
+			return summary, err
+		}
+		logger.Debug("", "imported release \"%s:%s\"", ref.ModuleName, ref.ReleaseName)
+	}
+	for _, wareId := range summary.Wares {
+		if root.HasWare(wareId) {
+			continue
+		}
+		dest, err := root.WarePath(wareId)
+		if err != nil {
+			return summary, err
+		}
+		if errRaw := os.MkdirAll(filepath.Dir(dest), 0755); errRaw != nil {
+			return summary, wfapi.ErrorIo("failed to create warehouse directory", filepath.Dir(dest), errRaw)
+		}
+		if errRaw := os.Rename(filepath.Join(warehousePath, wareId.Subpath()), dest); errRaw != nil {
+			return summary, wfapi.ErrorIo("failed to move ware into warehouse", dest, errRaw)
+		}
+	}
+	return summary, nil
+}
+
+// Extracts a bundle into a directory.
+// Only regular files and directories under the top-level bundle directories are accepted.
+//
+// Errors:
+//
+//    - warpforge-error-bundle-invalid -- when the bundle is not a tar archive, or contains unexpected entries
+//    - warpforge-error-io -- when writing the extracted files fails
+func extract(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, errRaw := tr.Next()
+		if errRaw == io.EOF {
+			return nil
+		}
+		if errRaw != nil {
+			return serum.Error(wfapi.ECodeBundleInvalid, serum.WithCause(errRaw),
+				serum.WithMessageTemplate("failed to read bundle"),
+			)
+		}
+		name := path.Clean(hdr.Name)
+		top, _, _ := strings.Cut(name, "/")
+		if path.IsAbs(name) || (top != catalogDir && top != warehouseDir) {
+			return serum.Error(wfapi.ECodeBundleInvalid,
+				serum.WithMessageTemplate("bundle contains unexpected entry {{name|q}}"),
+				serum.WithDetail("name", hdr.Name),
+			)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if errRaw := os.MkdirAll(target, 0755); errRaw != nil {
+				return wfapi.ErrorIo("failed to extract bundle", target, errRaw)
+			}
+		case tar.TypeReg:
+			if errRaw := os.MkdirAll(filepath.Dir(target), 0755); errRaw != nil {
+				return wfapi.ErrorIo("failed to extract bundle", filepath.Dir(target), errRaw)
+			}
+			f, errRaw := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if errRaw != nil {
+				return wfapi.ErrorIo("failed to extract bundle", target, errRaw)
+			}
+			_, errRaw = io.Copy(f, tr)
+			f.Close()
+			if errRaw != nil {
+				return wfapi.ErrorIo("failed to extract bundle", target, errRaw)
+			}
+		default:
+			return serum.Error(wfapi.ECodeBundleInvalid,
+				serum.WithMessageTemplate("bundle entry {{name|q}} is not a regular file or directory"),
+				serum.WithDetail("name", hdr.Name),
+			)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/alecthomas/chroma/formatters"
 	"github.com/alecthomas/chroma/lexers"
@@ -38,6 +39,10 @@ var (
 	//go:embed catalogReplay.tmpl.html
 	catalogReplayTemplate string
 
+	// Templates shared by the others.
+	//go:embed catalogMetadata.tmpl.html
+	catalogMetadataTemplate string
+
 	//go:embed css/index.css
 	indexCssBody []byte
 
@@ -88,7 +93,8 @@ func (cfg SiteConfig) tfuncs() map[string]interface{} {
 			// Very small helper function to stringify things.
 			// This is useful for things that are literally typedefs of string but the template package isn't smart enough to be calm about unboxing it.
 			// (It also does return something for values of non-string types, but not something very useful.)
-			return reflect.ValueOf(x).String()
+			// Pointers are followed, so optional fields can be stringified too.
+			return reflect.Indirect(reflect.ValueOf(x)).String()
 		},
 		"url": func(parts ...string) string {
 			return path.Join(append([]string{cfg.URLPrefix}, parts...)...)
@@ -97,7 +103,73 @@ func (cfg SiteConfig) tfuncs() map[string]interface{} {
 			// Very small helper function to subtract numbers in the templates
 			return strconv.FormatInt(a-b, 10)
 		},
+		"wellKnownMetadata": wellKnownMetadata,
+		"isWellKnownMetadata": func(key string) bool {
+			_, ok := metadataLabels[key]
+			return ok
+		},
+		"otherMetadataCount": func(keys []string) int {
+			// the replay is linked separately, so it doesn't count either
+			n := 0
+			for _, key := range keys {
+				if _, ok := metadataLabels[key]; !ok && key != "replay" {
+					n++
+				}
+			}
+			return n
+		},
+	}
+}
+
+// metadataLabels are the display names of the well-known metadata keys.
+var metadataLabels = map[string]string{
+	wfapi.MetadataDescription: "Description",
+	wfapi.MetadataHomepage:    "Homepage",
+	wfapi.MetadataSource:      "Source",
+	wfapi.MetadataLicense:     "License",
+	wfapi.MetadataMaintainers: "Maintainers",
+	wfapi.MetadataReleaseDate: "Release date",
+}
+
+// metadataField is the value of a well-known metadata key, ready for display.
+type metadataField struct {
+	Key   string
+	Label string
+	Value string
+	Link  string   // Where the value should link to, if anywhere.
+	List  []string // The entries of the value, if it's a list.
+}
+
+// wellKnownMetadata picks out the well-known keys from metadata, in the order of wfapi.WellKnownMetadata.
+// Given several sets of metadata (such as a module's and then a release's), later values override earlier ones.
+func wellKnownMetadata(metadata ...map[string]string) []metadataField {
+	var fields []metadataField
+	for _, key := range wfapi.WellKnownMetadata {
+		field := metadataField{Key: key, Label: metadataLabels[key]}
+		for _, m := range metadata {
+			if v, ok := m[key]; ok {
+				field.Value = v
+			}
+		}
+		if field.Value == "" {
+			continue
+		}
+		switch key {
+		case wfapi.MetadataHomepage, wfapi.MetadataSource:
+			field.Link = field.Value
+		case wfapi.MetadataLicense:
+			// only a single license has a page of its own
+			if !strings.ContainsAny(field.Value, " ()") && !strings.HasPrefix(field.Value, "LicenseRef-") {
+				field.Link = "https://spdx.org/licenses/" + strings.TrimSuffix(field.Value, "+") + ".html"
+			}
+		case wfapi.MetadataMaintainers:
+			for _, m := range strings.Split(field.Value, ",") {
+				field.List = append(field.List, strings.TrimSpace(m))
+			}
+		}
+		fields = append(fields, field)
 	}
+	return fields
 }
 
 // CatalogAndChildrenToHtml performs CatalogToHtml, and also
@@ -152,7 +224,7 @@ func (cfg SiteConfig) CatalogAndChildrenToHtml() error {
 	// Emit all modules within.
 	modNames := cfg.Cat_dab.Modules()
 	for _, modName := range modNames {
-		catMod, err := cfg.Cat_dab.GetModule(wfapi.CatalogRef{modName, "", ""})
+		catMod, err := cfg.Cat_dab.GetModule(wfapi.CatalogRef{ModuleName: modName})
 		if err != nil {
 			return err
 		}
@@ -181,6 +253,7 @@ func (cfg SiteConfig) doTemplate(outputPath string, tmpl string, data interface{
 	defer f.Close()
 
 	t := template.Must(template.New("main").Funcs(cfg.tfuncs()).Parse(tmpl))
+	template.Must(t.New("metadata").Parse(catalogMetadataTemplate))
 	if err := t.Execute(f, data); err != nil {
 		return serum.Errorf(wfapi.ECodeInternal, "templating failed: %w", err)
 	}
@@ -219,7 +292,7 @@ func (cfg SiteConfig) CatalogModuleAndChildrenToHtml(catMod wfapi.CatalogModule)
 		return err
 	}
 	for _, releaseName := range catMod.Releases.Keys {
-		ref := wfapi.CatalogRef{catMod.Name, releaseName, ""}
+		ref := wfapi.CatalogRef{ModuleName: catMod.Name, ReleaseName: releaseName}
 		rel, err := cfg.Cat_dab.GetRelease(ref)
 		if err != nil {
 			return err
@@ -241,21 +314,43 @@ func (cfg SiteConfig) CatalogModuleAndChildrenToHtml(catMod wfapi.CatalogModule)
 }
 
 // CatalogModuleToHtml generates a page for a module which enumerates
-// and links to all the releases within it,
+// and links to all the releases within it, marking those which are yanked or deprecated,
 // as well as enumerates all the metadata attached to the catalog module.
 //
 // Errors:
 //
 //   - warpforge-error-io -- in case of errors writing out the new html content.
 //   - warpforge-error-internal -- in case of templating errors.
+//   - warpforge-error-catalog-invalid -- in case the catalog data is invalid.
+//   - warpforge-error-catalog-parse -- in case the catalog data failed to parse entirely.
 func (cfg SiteConfig) CatalogModuleToHtml(catMod wfapi.CatalogModule) error {
+	data := moduleTemplateData{
+		CatalogModule: catMod,
+		Statuses:      make(map[wfapi.ReleaseName]*wfapi.ReleaseStatus),
+	}
+	for _, releaseName := range catMod.Releases.Keys {
+		rel, err := cfg.Cat_dab.GetRelease(wfapi.CatalogRef{ModuleName: catMod.Name, ReleaseName: releaseName})
+		if err != nil {
+			return err
+		}
+		if rel != nil && rel.Status != nil {
+			data.Statuses[releaseName] = rel.Status
+		}
+	}
 	return cfg.doTemplate(
 		filepath.Join(cfg.OutputPath, string(catMod.Name), "_module.html"),
 		catalogModuleTemplate,
-		catMod,
+		data,
 	)
 }
 
+// moduleTemplateData is the module, plus the status of each release which has one,
+// since the module itself only has release CIDs.
+type moduleTemplateData struct {
+	wfapi.CatalogModule
+	Statuses map[wfapi.ReleaseName]*wfapi.ReleaseStatus
+}
+
 // ReleaseToHtml generates a page for a release within a catalog module
 // which enumerates all the items within it,
 // as well as enumerates all the metadata attached to the release.
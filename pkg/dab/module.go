@@ -3,14 +3,20 @@ package dab
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
 	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/ipld/go-ipld-prime/datamodel"
+	rfmtjson "github.com/polydawn/refmt/json"
 	"github.com/serum-errors/go-serum"
 
 	"github.com/warptools/warpforge/wfapi"
@@ -261,6 +267,38 @@ func PlotFromFile(fsys fs.FS, filename string) (*wfapi.Plot, error) {
 	return plotCapsule.Plot, nil
 }
 
+// PlotToFile writes a wfapi.Plot to a host filesystem path, replacing any existing file.
+// The plot is indented with tabs, as plot files written by hand usually are.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors writing the file.
+// 	- warpforge-error-serialization -- if the plot cannot be serialized.
+func PlotToFile(plot wfapi.Plot, filename string) error {
+	const situation = "writing a plot"
+
+	serial, err := ipld.Marshal(prettyEncode, &wfapi.PlotCapsule{Plot: &plot}, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	if err != nil {
+		return wfapi.ErrorSerialization(situation, err)
+	}
+	if err := os.WriteFile(filename, serial, 0644); err != nil {
+		return wfapi.ErrorIo(situation, filename, err)
+	}
+	return nil
+}
+
+// prettyEncode is json.Encode, with a line for each entry, and tab indentation.
+func prettyEncode(n datamodel.Node, w io.Writer) error {
+	return dagjson.Marshal(n, rfmtjson.NewEncoder(w, rfmtjson.EncodeOptions{
+		Line:   []byte{'\n'},
+		Indent: []byte{'\t'},
+	}), dagjson.EncodeOptions{
+		EncodeLinks: false,
+		EncodeBytes: false,
+		MapSortMode: codec.MapSortMode_None,
+	})
+}
+
 func dirNoDot(path string) string {
 	path = filepath.Dir(path)
 	if path == "." {
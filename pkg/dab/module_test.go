@@ -3,12 +3,15 @@ package dab_test
 import (
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"testing/fstest"
 
 	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
 	"github.com/serum-errors/go-serum"
 	"github.com/warpfork/go-testmark"
 
@@ -462,3 +465,51 @@ func TestSubPathRel(t *testing.T) {
 		})
 	}
 }
+
+func TestPlotToFile(t *testing.T) {
+	// a plot as written by hand, which should be written back the same way
+	const plotJson = `{
+	"plot.v1": {
+		"inputs": {
+			"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static"
+		},
+		"steps": {
+			"one": {
+				"protoformula": {
+					"inputs": {
+						"/": "pipe::rootfs"
+					},
+					"action": {
+						"exec": {
+							"command": [
+								"/bin/sh",
+								"-c",
+								"echo test > /test"
+							]
+						}
+					},
+					"outputs": {
+						"test": {
+							"from": "/test",
+							"packtype": "tar"
+						}
+					}
+				}
+			}
+		},
+		"outputs": {
+			"test": "pipe:one:test"
+		}
+	}
+}
+`
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(plotJson), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+
+	filename := filepath.Join(t.TempDir(), dab.MagicFilename_Plot)
+	qt.Assert(t, dab.PlotToFile(*plotCapsule.Plot, filename), qt.IsNil)
+	written, err := os.ReadFile(filename)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, string(written), qt.Equals, plotJson)
+}
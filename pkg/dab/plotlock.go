@@ -0,0 +1,74 @@
+package dab
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// MagicFilename_PlotLock is the name of the lock file which sits beside a plot.
+const MagicFilename_PlotLock = "plot.lock"
+
+// PlotLockFromFile loads a wfapi.PlotLock from filesystem path.
+//
+// In typical usage, the filename parameter will have the suffix of MagicFilename_PlotLock.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors reading from fsys.
+// 	- warpforge-error-serialization -- for errors from try to parse the data as a PlotLock.
+// 	- warpforge-error-datatoonew -- if encountering unknown data from a newer version of warpforge!
+//  - warpforge-error-missing -- when file does not exist
+func PlotLockFromFile(fsys fs.FS, filename string) (*wfapi.PlotLock, error) {
+	const situation = "loading a plot lock"
+
+	if filepath.IsAbs(filename) {
+		filename = filename[1:]
+	}
+	f, err := fs.ReadFile(fsys, filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, serum.Error(wfapi.ECodeMissing, serum.WithCause(err))
+	}
+	if err != nil {
+		return nil, wfapi.ErrorIo(situation, filename, err)
+	}
+
+	lockCapsule := wfapi.PlotLockCapsule{}
+	_, err = ipld.Unmarshal(f, json.Decode, &lockCapsule, wfapi.TypeSystem.TypeByName("PlotLockCapsule"))
+	if err != nil {
+		return nil, wfapi.ErrorSerialization(situation, err)
+	}
+	if lockCapsule.PlotLock == nil {
+		// ... this isn't really reachable.
+		return nil, wfapi.ErrorDataTooNew(situation, fmt.Errorf("no v1 PlotLock in PlotLockCapsule"))
+	}
+
+	return lockCapsule.PlotLock, nil
+}
+
+// PlotLockToFile writes a wfapi.PlotLock to a host filesystem path, replacing any existing file.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors writing the file.
+// 	- warpforge-error-serialization -- if the lock cannot be serialized.
+func PlotLockToFile(lock wfapi.PlotLock, filename string) error {
+	const situation = "writing a plot lock"
+
+	serial, err := ipld.Marshal(json.Encode, &wfapi.PlotLockCapsule{PlotLock: &lock}, wfapi.TypeSystem.TypeByName("PlotLockCapsule"))
+	if err != nil {
+		return wfapi.ErrorSerialization(situation, err)
+	}
+	if err := os.WriteFile(filename, serial, 0644); err != nil {
+		return wfapi.ErrorIo(situation, filename, err)
+	}
+	return nil
+}
@@ -1,11 +1,16 @@
 package dab
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/ipld/go-ipld-prime"
 	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
 	"github.com/warptools/warpforge/wfapi"
 )
 
@@ -13,6 +18,8 @@ const (
 	MagicFilename_Workspace       = ".warpforge"
 	MagicFilename_HomeWorkspace   = ".warphome"
 	MagicFilename_MirroringConfig = "config/mirroring.json"
+	MagicFilename_TrustPolicy     = "config/trust.json"
+	MagicFilename_Subscriptions   = "config/subscriptions.json"
 )
 
 // MirroringConfigFromFile loads a wfapi.MirroringConfig from filesystem path.
@@ -45,3 +52,98 @@ func MirroringConfigFromFile(fsys fs.FS, filename string) (wfapi.MirroringConfig
 
 	return *mirroringConfigCapsule.MirroringConfig, nil
 }
+
+// TrustPolicyFromFile loads a wfapi.TrustPolicy from filesystem path.
+//
+// In typical usage, the filename parameter will have the suffix of MagicFilename_TrustPolicy.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors reading from fsys.
+// 	- warpforge-error-serialization -- for errors from try to parse the data as a TrustPolicy.
+// 	- warpforge-error-datatoonew -- if encountering unknown data from a newer version of warpforge!
+// 	- warpforge-error-missing -- when file does not exist
+func TrustPolicyFromFile(fsys fs.FS, filename string) (*wfapi.TrustPolicy, error) {
+	const situation = "loading a trust policy"
+	if strings.HasPrefix(filename, "/") {
+		filename = filename[1:]
+	}
+	f, err := fs.ReadFile(fsys, filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, serum.Error(wfapi.ECodeMissing, serum.WithCause(err))
+	}
+	if err != nil {
+		return nil, wfapi.ErrorIo(situation, filename, err)
+	}
+
+	policyCapsule := wfapi.TrustPolicyCapsule{}
+	_, err = ipld.Unmarshal(f, json.Decode, &policyCapsule, wfapi.TypeSystem.TypeByName("TrustPolicyCapsule"))
+	if err != nil {
+		return nil, wfapi.ErrorSerialization(situation, err)
+	}
+	if policyCapsule.TrustPolicy == nil {
+		// ... this isn't really reachable.
+		return nil, wfapi.ErrorDataTooNew(situation, fmt.Errorf("no v1 TrustPolicy in TrustPolicyCapsule"))
+	}
+
+	return policyCapsule.TrustPolicy, nil
+}
+
+// SubscriptionConfigFromFile loads a wfapi.SubscriptionConfig from filesystem path.
+//
+// In typical usage, the filename parameter will have the suffix of MagicFilename_Subscriptions.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors reading from fsys.
+// 	- warpforge-error-serialization -- for errors from try to parse the data as a SubscriptionConfig.
+// 	- warpforge-error-datatoonew -- if encountering unknown data from a newer version of warpforge!
+// 	- warpforge-error-missing -- when file does not exist
+func SubscriptionConfigFromFile(fsys fs.FS, filename string) (*wfapi.SubscriptionConfig, error) {
+	const situation = "loading a subscription config"
+	if strings.HasPrefix(filename, "/") {
+		filename = filename[1:]
+	}
+	f, err := fs.ReadFile(fsys, filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, serum.Error(wfapi.ECodeMissing, serum.WithCause(err))
+	}
+	if err != nil {
+		return nil, wfapi.ErrorIo(situation, filename, err)
+	}
+
+	configCapsule := wfapi.SubscriptionConfigCapsule{}
+	_, err = ipld.Unmarshal(f, json.Decode, &configCapsule, wfapi.TypeSystem.TypeByName("SubscriptionConfigCapsule"))
+	if err != nil {
+		return nil, wfapi.ErrorSerialization(situation, err)
+	}
+	if configCapsule.SubscriptionConfig == nil {
+		// ... this isn't really reachable.
+		return nil, wfapi.ErrorDataTooNew(situation, fmt.Errorf("no v1 SubscriptionConfig in SubscriptionConfigCapsule"))
+	}
+
+	return configCapsule.SubscriptionConfig, nil
+}
+
+// SubscriptionConfigToFile writes a wfapi.SubscriptionConfig to a host filesystem path,
+// replacing any existing file, and creating its directory if needed.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors writing the file.
+// 	- warpforge-error-serialization -- if the config cannot be serialized.
+func SubscriptionConfigToFile(cfg wfapi.SubscriptionConfig, filename string) error {
+	const situation = "writing a subscription config"
+
+	serial, err := ipld.Marshal(json.Encode, &wfapi.SubscriptionConfigCapsule{SubscriptionConfig: &cfg}, wfapi.TypeSystem.TypeByName("SubscriptionConfigCapsule"))
+	if err != nil {
+		return wfapi.ErrorSerialization(situation, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return wfapi.ErrorIo(situation, filepath.Dir(filename), err)
+	}
+	if err := os.WriteFile(filename, serial, 0644); err != nil {
+		return wfapi.ErrorIo(situation, filename, err)
+	}
+	return nil
+}
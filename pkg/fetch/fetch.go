@@ -0,0 +1,347 @@
+/*
+Package fetch downloads wares into the root workspace's warehouse ahead of execution,
+so that executing a plot can unpack every ware it needs without reaching the network.
+It also fetches wares into a module's own warehouse when they're bundled with it.
+
+Wares are fetched from the warehouses of the other workspaces in the workspace set if possible,
+and otherwise from the mirrors the catalogs give for them, trying each in turn until one has the ware.
+Each downloaded ware is verified before it's placed in the warehouse,
+so a mirror which serves the wrong content is passed over like one which doesn't have the ware at all.
+*/
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/warptools/warpforge/pkg/logging"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// A Verifier checks that the file at a path has the content a WareID says it does.
+//
+// Errors:
+//
+//    - warpforge-error-ware-corrupt -- when the content does not match the WareID
+//    - warpforge-error-executor-failed -- when the content cannot be hashed
+type Verifier func(ctx context.Context, wareId wfapi.WareID, path string) error
+
+// RioVerifier returns a Verifier which hashes wares with `rio scan`, using the rio binary in the given directory.
+func RioVerifier(binPath string) Verifier {
+	return func(ctx context.Context, wareId wfapi.WareID, path string) error {
+		ctx, span := tracing.Start(ctx, "rio scan", trace.WithAttributes(tracing.AttrFullExecNameRio))
+		defer span.End()
+		cmd := exec.CommandContext(ctx, filepath.Join(binPath, "rio"),
+			"scan", "--source=file://"+path, string(wareId.Packtype),
+		)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			err = wfapi.ErrorExecutorFailed("rio", fmt.Errorf("rio scan of %s failed: %w: %s", path, err, stderr.String()))
+			tracing.EndWithStatus(span, err)
+			return err
+		}
+		parts := strings.SplitN(strings.TrimSpace(stdout.String()), ":", 2)
+		if len(parts) != 2 {
+			err := wfapi.ErrorExecutorFailed("rio", fmt.Errorf("unexpected output from rio scan of %s: %q", path, stdout.String()))
+			tracing.EndWithStatus(span, err)
+			return err
+		}
+		actual := wfapi.WareID{Packtype: wfapi.Packtype(parts[0]), Hash: parts[1]}
+		if actual != wareId {
+			return wfapi.ErrorWareCorrupt(wareId, actual)
+		}
+		return nil
+	}
+}
+
+// Options controls how Fetch fetches wares.
+type Options struct {
+	// Verify checks each downloaded ware before it's placed in the warehouse.
+	// If nil, wares are not verified, which should only be done when every source is trusted.
+	Verify Verifier
+
+	// Client is used for mirrors reached over HTTP. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Header is added to each request to a mirror reached over HTTP, such as to authorize it.
+	Header http.Header
+
+	// Dest is the workspace whose warehouse wares are fetched into. If nil, it's the root workspace.
+	Dest *workspace.Workspace
+}
+
+// Returns the workspace whose warehouse wares are fetched into.
+func (opts Options) dest(wss workspace.WorkspaceSet) *workspace.Workspace {
+	if opts.Dest != nil {
+		return opts.Dest
+	}
+	return wss.Root()
+}
+
+// Ware is a ware to fetch.
+type Ware struct {
+	WareID wfapi.WareID
+	Ref    *wfapi.CatalogRef // The catalog item the ware is needed for, whose mirrors are tried. Without one, only the workspace set's warehouses are.
+}
+
+// Result is the outcome of fetching one ware.
+type Result struct {
+	WareID wfapi.WareID
+	Ref    *wfapi.CatalogRef
+	Status wfapi.FetchStatus
+	Source wfapi.WarehouseAddr // Where the ware was fetched from, if it was.
+	Errs   []error             // Why each source tried that failed to provide the ware did, in the order they were tried.
+}
+
+// Sources lists where a ware may be fetched from into the warehouse of the dest workspace, in the order they should be tried:
+// the warehouses of the other workspaces in the set, then the mirrors of the ware's catalog item.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when an IO error occurs while reading the catalog entry
+//    - warpforge-error-catalog-parse -- when ipld parsing of a catalog entry fails
+//    - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
+//    - warpforge-error-release-constraint -- when the reference has an invalid version constraint
+func Sources(wss workspace.WorkspaceSet, dest *workspace.Workspace, ware Ware) ([]wfapi.WarehouseAddr, error) {
+	var sources []wfapi.WarehouseAddr
+	for _, ws := range wss {
+		if ws != dest {
+			sources = append(sources, ws.GetWarehouseAddress())
+		}
+	}
+	if ware.Ref == nil {
+		return sources, nil
+	}
+	mirrors, err := wss.GetWareMirrors(*ware.Ref, ware.WareID)
+	if err != nil {
+		return nil, err
+	}
+	return append(sources, mirrors...), nil
+}
+
+// Fetch fetches wares into the warehouse of the options' destination workspace, returning the outcome for each, in the order given.
+// Wares which can't be fetched don't stop the others from being fetched.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when an IO error occurs while reading a catalog entry
+//    - warpforge-error-catalog-parse -- when ipld parsing of a catalog entry fails
+//    - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
+//    - warpforge-error-release-constraint -- when a reference has an invalid version constraint
+//    - warpforge-error-wareid-invalid -- when a WareID is malformed
+func Fetch(ctx context.Context, wss workspace.WorkspaceSet, wares []Ware, opts Options) (_ []Result, err error) {
+	ctx, span := tracing.StartFn(ctx, "Fetch")
+	defer func() { tracing.EndWithStatus(span, err) }()
+	log := logging.Ctx(ctx)
+
+	destWs := opts.dest(wss)
+	results := make([]Result, 0, len(wares))
+	for _, ware := range wares {
+		result := Result{WareID: ware.WareID, Ref: ware.Ref}
+		dest, err := destWs.WarePath(ware.WareID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case destWs.HasWare(ware.WareID):
+			result.Status = wfapi.FetchStatus_Present
+		case ware.WareID.Packtype == "git":
+			// git wares are repositories, which rio fetches itself
+			result.Status = wfapi.FetchStatus_Unsupported
+		default:
+			sources, err := Sources(wss, destWs, ware)
+			if err != nil {
+				return nil, err
+			}
+			result.Status = wfapi.FetchStatus_Unavailable
+			for _, source := range sources {
+				found, err := FetchFrom(ctx, opts, ware.WareID, source, dest)
+				if err != nil {
+					log.Info("fetch", "failed to fetch ware %q from %q: %s", ware.WareID.String(), source, err)
+					result.Errs = append(result.Errs, err)
+					result.Status = wfapi.FetchStatus_Failed
+					continue
+				}
+				if found {
+					log.Info("fetch", "fetched ware %q from %q", ware.WareID.String(), source)
+					result.Status = wfapi.FetchStatus_Fetched
+					result.Source = source
+					break
+				}
+				log.Debug("fetch", "ware %q not found at %q", ware.WareID.String(), source)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// TidyFetcher returns a function for workspace.TidyConfig which fetches the wares of bundled catalog items
+// into the warehouse of the local workspace, as by Fetch.
+// Wares that no source has, or which can't be fetched into a warehouse at all, are reported as not found,
+// and a ware which some source failed to provide is reported with the first error from it.
+func TidyFetcher(wss workspace.WorkspaceSet, opts Options) func(ctx context.Context, wareId wfapi.WareID, ref wfapi.CatalogRef) (bool, error) {
+	opts.Dest = wss.Local()
+	return func(ctx context.Context, wareId wfapi.WareID, ref wfapi.CatalogRef) (bool, error) {
+		results, err := Fetch(ctx, wss, []Ware{{WareID: wareId, Ref: &ref}}, opts)
+		if err != nil {
+			return false, err
+		}
+		switch results[0].Status {
+		case wfapi.FetchStatus_Present, wfapi.FetchStatus_Fetched:
+			return true, nil
+		case wfapi.FetchStatus_Failed:
+			return false, results[0].Errs[0]
+		}
+		return false, nil
+	}
+}
+
+// FetchFrom fetches a ware from a single source to the path given, verifying it on the way,
+// so that nothing is written to the path unless the ware is fetched and verified.
+// Returns false if the source does not have the ware.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the ware cannot be read or written, or a mirror refuses the request
+//    - warpforge-error-connection -- when a mirror cannot be reached, or fails in a way that may be transient
+//    - warpforge-error-invalid -- when the source address is not supported
+//    - warpforge-error-ware-corrupt -- when the ware does not match its WareID
+//    - warpforge-error-executor-failed -- when the ware cannot be verified
+func FetchFrom(ctx context.Context, opts Options, wareId wfapi.WareID, source wfapi.WarehouseAddr, dest string) (bool, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	r, err := open(ctx, opts.Client, opts.Header, wareId, source)
+	if err != nil || r == nil {
+		return false, err
+	}
+	defer r.Close()
+
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, wfapi.ErrorIo("failed to create warehouse directory", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return false, wfapi.ErrorIo("failed to create temporary ware file", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return false, serum.Error(wfapi.ECodeConnection, serum.WithCause(err),
+			serum.WithMessageTemplate("failed to download ware {{wareId}} from {{source|q}}"),
+			serum.WithDetail("wareId", wareId.String()),
+			serum.WithDetail("source", string(source)),
+		)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, wfapi.ErrorIo("failed to write ware", tmp.Name(), err)
+	}
+	if opts.Verify != nil {
+		if err := opts.Verify(ctx, wareId, tmp.Name()); err != nil {
+			return false, err
+		}
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return false, wfapi.ErrorIo("failed to write ware", dest, err)
+	}
+	return true, nil
+}
+
+// Readable returns true if wares can be fetched from the source address,
+// which may be a content-addressed warehouse on the filesystem, or reached over HTTP(S) or S3,
+// or an HTTP(S) URL of the ware itself.
+func Readable(source wfapi.WarehouseAddr) bool {
+	for _, prefix := range []string{"ca+file://", "ca+s3://", "ca+http://", "ca+https://", "http://", "https://"} {
+		if strings.HasPrefix(string(source), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Opens a ware at a source. Returns nil if the source does not have the ware.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the ware cannot be read, or a mirror refuses the request
+//    - warpforge-error-connection -- when a mirror cannot be reached, or fails in a way that may be transient
+//    - warpforge-error-invalid -- when the source address is not supported
+func open(ctx context.Context, client *http.Client, header http.Header, wareId wfapi.WareID, source wfapi.WarehouseAddr) (io.ReadCloser, error) {
+	addr := string(source)
+	switch {
+	case strings.HasPrefix(addr, "ca+file://"):
+		path := filepath.Join(strings.TrimPrefix(addr, "ca+file://"), wareId.Subpath())
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, wfapi.ErrorIo("failed to open ware", path, err)
+		}
+		return f, nil
+	case strings.HasPrefix(addr, "ca+s3://"):
+		// HACK: as for execution, s3 buckets are read over https
+		addr = "https://" + strings.TrimPrefix(addr, "ca+s3://") + "/" + wareId.Subpath()
+	case strings.HasPrefix(addr, "ca+http://"), strings.HasPrefix(addr, "ca+https://"):
+		addr = strings.TrimPrefix(addr, "ca+") + "/" + wareId.Subpath()
+	case strings.HasPrefix(addr, "http://"), strings.HasPrefix(addr, "https://"):
+		// addresses without the "ca+" prefix point directly at the ware
+	default:
+		return nil, serum.Error(wfapi.ECodeInvalid,
+			serum.WithMessageTemplate("can't fetch from {{source|q}}: unsupported address"),
+			serum.WithDetail("source", addr),
+		)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, serum.Error(wfapi.ECodeInvalid, serum.WithCause(err),
+			serum.WithMessageTemplate("can't fetch from {{source|q}}: invalid address"),
+			serum.WithDetail("source", addr),
+		)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, serum.Error(wfapi.ECodeConnection, serum.WithCause(err),
+			serum.WithMessageTemplate("failed to fetch ware from {{source|q}}"),
+			serum.WithDetail("source", addr),
+		)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp.Body, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil
+	}
+	resp.Body.Close()
+	code := wfapi.ECodeIo
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+		code = wfapi.ECodeConnection
+	}
+	return nil, serum.Error(code,
+		serum.WithMessageTemplate("failed to fetch ware from {{source|q}}: server responded {{status}}"),
+		serum.WithDetail("source", addr),
+		serum.WithDetail("status", resp.Status),
+	)
+}
@@ -0,0 +1,153 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// constructs a root workspace in a temporary directory, returning it and its path
+func newTestRootWorkspace(t *testing.T) (*workspace.Workspace, string) {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, ".warpforge"), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, ".warpforge", "root"), nil, 0644), qt.IsNil)
+	root, err := workspace.OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	return root, rootPath
+}
+
+func TestFetch(t *testing.T) {
+	ws, _ := newTestRootWorkspace(t)
+	cat, err := ws.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	wss := workspace.WorkspaceSet{ws}
+
+	// wares have their hash as their content, which the verifier checks
+	verify := func(ctx context.Context, wareId wfapi.WareID, path string) error {
+		content, err := os.ReadFile(path)
+		qt.Assert(t, err, qt.IsNil)
+		if actual := (wfapi.WareID{Packtype: wareId.Packtype, Hash: string(content)}); actual != wareId {
+			return wfapi.ErrorWareCorrupt(wareId, actual)
+		}
+		return nil
+	}
+	good := wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+	missing := wfapi.WareID{Packtype: "tar", Hash: "bbbbbbbbbb"}
+	present := wfapi.WareID{Packtype: "tar", Hash: "cccccccccc"}
+	git := wfapi.WareID{Packtype: "git", Hash: "dddddddddd"}
+
+	// one mirror is down, one has the wrong content, and one has the ware
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down/" + good.Subpath():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/up/" + good.Subpath():
+			w.Write([]byte(good.Hash))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	corruptPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(corruptPath, filepath.Dir(good.Subpath())), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(corruptPath, good.Subpath()), []byte("wrong"), 0644), qt.IsNil)
+
+	goodRef := wfapi.CatalogRef{ModuleName: "example.com/good", ReleaseName: "v1", ItemName: "src"}
+	qt.Assert(t, cat.AddItem(goodRef, good, false), qt.IsNil)
+	for _, addr := range []string{"ca+" + server.URL + "/down", "ca+file://" + corruptPath, "ca+" + server.URL + "/up"} {
+		qt.Assert(t, cat.AddByWareMirror(goodRef, good, wfapi.WarehouseAddr(addr)), qt.IsNil)
+	}
+	missingRef := wfapi.CatalogRef{ModuleName: "example.com/missing", ReleaseName: "v1", ItemName: "src"}
+	qt.Assert(t, cat.AddItem(missingRef, missing, false), qt.IsNil)
+	qt.Assert(t, cat.AddByModuleMirror(missingRef, "tar", wfapi.WarehouseAddr("ca+"+server.URL+"/up")), qt.IsNil)
+	presentPath, err := ws.WarePath(present)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, os.MkdirAll(filepath.Dir(presentPath), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(presentPath, []byte(present.Hash), 0644), qt.IsNil)
+
+	results, err := Fetch(context.Background(), wss, []Ware{
+		{WareID: good, Ref: &goodRef},
+		{WareID: missing, Ref: &missingRef},
+		{WareID: present},
+		{WareID: git},
+	}, Options{Verify: verify})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, results, qt.HasLen, 4)
+
+	qt.Check(t, results[0].Status, qt.Equals, wfapi.FetchStatus_Fetched)
+	qt.Check(t, results[0].Source, qt.Equals, wfapi.WarehouseAddr("ca+"+server.URL+"/up"))
+	qt.Assert(t, results[0].Errs, qt.HasLen, 2)
+	qt.Check(t, serum.Code(results[0].Errs[0]), qt.Equals, wfapi.ECodeConnection)
+	qt.Check(t, serum.Code(results[0].Errs[1]), qt.Equals, wfapi.ECodeWareCorrupt)
+	warePath, err := ws.WarePath(good)
+	qt.Assert(t, err, qt.IsNil)
+	content, err := os.ReadFile(warePath)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, string(content), qt.Equals, good.Hash)
+
+	qt.Check(t, results[1].Status, qt.Equals, wfapi.FetchStatus_Unavailable)
+	qt.Check(t, results[1].Errs, qt.HasLen, 0)
+	qt.Check(t, ws.HasWare(missing), qt.IsFalse)
+	qt.Check(t, results[2].Status, qt.Equals, wfapi.FetchStatus_Present)
+	qt.Check(t, results[3].Status, qt.Equals, wfapi.FetchStatus_Unsupported)
+
+	// nothing is left behind by the failed attempts
+	entries, err := os.ReadDir(filepath.Dir(warePath))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, entries, qt.HasLen, 1)
+}
+
+func TestTidyFetcher(t *testing.T) {
+	root, rootPath := newTestRootWorkspace(t)
+	localPath := filepath.Join(rootPath, "module")
+	qt.Assert(t, os.MkdirAll(filepath.Join(localPath, ".warpforge"), 0755), qt.IsNil)
+	local, err := workspace.OpenWorkspace(os.DirFS("/"), localPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	wss := workspace.WorkspaceSet{local, root}
+
+	verify := func(ctx context.Context, wareId wfapi.WareID, path string) error {
+		content, err := os.ReadFile(path)
+		qt.Assert(t, err, qt.IsNil)
+		if actual := (wfapi.WareID{Packtype: wareId.Packtype, Hash: string(content)}); actual != wareId {
+			return wfapi.ErrorWareCorrupt(wareId, actual)
+		}
+		return nil
+	}
+	// the root warehouse has one good ware, and one corrupt one
+	good := wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+	corrupt := wfapi.WareID{Packtype: "tar", Hash: "bbbbbbbbbb"}
+	missing := wfapi.WareID{Packtype: "tar", Hash: "cccccccccc"}
+	for wareId, content := range map[wfapi.WareID]string{good: good.Hash, corrupt: "wrong"} {
+		path, err := root.WarePath(wareId)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, os.MkdirAll(filepath.Dir(path), 0755), qt.IsNil)
+		qt.Assert(t, os.WriteFile(path, []byte(content), 0644), qt.IsNil)
+	}
+
+	fetchWare := TidyFetcher(wss, Options{Verify: verify})
+	ctx := context.Background()
+	ref := wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v1", ItemName: "src"}
+
+	found, err := fetchWare(ctx, good, ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, found, qt.IsTrue)
+	qt.Check(t, local.HasWare(good), qt.IsTrue)
+
+	found, err = fetchWare(ctx, corrupt, ref)
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeWareCorrupt)
+	qt.Check(t, found, qt.IsFalse)
+	qt.Check(t, local.HasWare(corrupt), qt.IsFalse)
+
+	found, err = fetchWare(ctx, missing, ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, found, qt.IsFalse)
+}
@@ -133,7 +133,8 @@ func (cfg *internalConfig) storeMemo(ctx context.Context, rr wfapi.RunRecord) er
 	return nil
 }
 
-func (cfg *ExecConfig) warehousePathOverride() (string, bool) {
+// WarehousePathOverride returns the absolute path of the warehouse override, if one is configured.
+func (cfg *ExecConfig) WarehousePathOverride() (string, bool) {
 	if cfg.WhPathOverride == nil {
 		return "", false
 	}
@@ -420,7 +421,7 @@ func (cfg internalConfig) newRuncConfig(ctx context.Context, runPath string, bas
 
 	// check if the rio warehouse location has been overridden
 	// if so, mount it to the expected warehouse path
-	if warehousePath, ok := cfg.warehousePathOverride(); ok {
+	if warehousePath, ok := cfg.WarehousePathOverride(); ok {
 		wfWarehouseMount := specs.Mount{
 			Source:      warehousePath,
 			Destination: containerWarehousePath(),
@@ -492,7 +493,7 @@ func (rc *runcConfig) makeWareMount(ctx context.Context,
 			// check if we need to create a mount for this warehouse
 			proto := strings.Split(wareAddr, ":")[0]
 			hostPath := strings.Split(wareAddr, "://")[1]
-			if proto == "file" || proto == "file+ca" {
+			if proto == "file" || proto == "file+ca" || proto == "ca+file" {
 				// this is a local file or directory, we will need to mount it to the container for unpacking
 				// we will mount it at CONTAINER_BASE_PATH/tmp
 				src = filepath.Join(CONTAINER_BASE_PATH, "tmp")
@@ -736,7 +737,59 @@ func (rc *runcConfig) rioPack(ctx context.Context, path string) (wfapi.WareID, e
 		// TODO: this should figure out of the rio op failed, or if runc failed and throw different errors
 		return wfapi.WareID{}, wfapi.ErrorExecutorFailed(fmt.Sprintf("invoke runc for rio pack of %s failed", path), err)
 	}
+	wareId, err := parseRioPackOutput(path, outStr)
+	if err != nil {
+		return wareId, err
+	}
+	span.AddEvent("Found ware ID", trace.WithAttributes(attribute.String(tracing.AttrKeyWarpforgeWareId, wareId.String())))
+	return wareId, nil
+}
 
+// PackHostPath packs a path on the host as a tar ware in the warehouse of the given workspace,
+// or in the overridden warehouse if the config has one.
+// Unlike packing formula outputs, rio is run directly on the host rather than within a container.
+// Ownership is normalized in the same way as for formula outputs,
+// so the resulting WareID depends only on the contents of the path.
+//
+// Errors:
+//
+//    - warpforge-error-executor-failed -- if rio cannot be run
+//    - warpforge-error-ware-pack -- if rio pack of ware fails
+func PackHostPath(ctx context.Context, cfg ExecConfig, ws *workspace.Workspace, path string) (wfapi.WareID, error) {
+	ctx, span := tracing.Start(ctx, "PackHostPath", trace.WithAttributes(tracing.AttrFullExecNameRio))
+	defer span.End()
+
+	warehousePath, ok := cfg.WarehousePathOverride()
+	if !ok {
+		warehousePath = filepath.Join("/", ws.WarehousePath())
+	}
+	cmd := exec.CommandContext(ctx, filepath.Join(cfg.BinPath, "rio"),
+		"pack",
+		"--format=json",
+		"--filters=uid=0,gid=0",
+		"--target=ca+file://"+warehousePath,
+		"tar",
+		path,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		tracing.EndWithStatus(span, err)
+		return wfapi.WareID{}, wfapi.ErrorExecutorFailed("rio", fmt.Errorf("rio pack of %s failed: %w: %s", path, err, stderr.String()))
+	}
+	wareId, err := parseRioPackOutput(path, stdout.String())
+	tracing.EndWithStatus(span, err)
+	return wareId, err
+}
+
+// Finds the WareID in the JSON output of a rio pack.
+//
+// Errors:
+//
+//    - warpforge-error-ware-pack -- if the output cannot be parsed or contains no WareID
+func parseRioPackOutput(path string, outStr string) (wfapi.WareID, error) {
 	out := RioOutput{}
 	for _, line := range strings.Split(outStr, "\n") {
 		err := json.Unmarshal([]byte(line), &out)
@@ -746,7 +799,6 @@ func (rc *runcConfig) rioPack(ctx context.Context, path string) (wfapi.WareID, e
 		}
 		if out.Result.WareId != "" {
 			// found wareId
-			span.AddEvent("Found ware ID", trace.WithAttributes(attribute.String(tracing.AttrKeyWarpforgeWareId, out.Result.WareId)))
 			break
 		}
 	}
@@ -759,6 +811,29 @@ func (rc *runcConfig) rioPack(ctx context.Context, path string) (wfapi.WareID, e
 	return wareId, nil
 }
 
+// FormulaID computes the ID of a formula.
+// This is the CID of the formula's dag-cbor representation,
+// and is what RunRecords and memos are keyed by.
+func FormulaID(formula wfapi.Formula) string {
+	nFormula := bindnode.Wrap(&formula, wfapi.TypeSystem.TypeByName("Formula"))
+	lsys := cidlink.DefaultLinkSystem()
+	lnk, errRaw := lsys.ComputeLink(cidlink.LinkPrototype{Prefix: cid.Prefix{
+		Version:  1,    // Usually '1'.
+		Codec:    0x71, // 0x71 means "dag-cbor" -- See the multicodecs table: https://github.com/multiformats/multicodec/
+		MhType:   0x20, // 0x20 means "sha2-384" -- See the multicodecs table: https://github.com/multiformats/multicodec/
+		MhLength: 48,   // sha2-384 hash has a 48-byte sum.
+	}}, nFormula.(schema.TypedNode).Representation())
+	if errRaw != nil {
+		// panic! this should never fail unless IPLD is broken
+		panic(fmt.Sprintf("Fatal IPLD Error: lsys.ComputeLink failed for Formula: %s", errRaw))
+	}
+	fid, errRaw := lnk.(cidlink.Link).StringOfBase('z')
+	if errRaw != nil {
+		panic(fmt.Sprintf("Fatal IPLD Error: failed to encode CID for Formula: %s", errRaw))
+	}
+	return fid
+}
+
 // Internal function for executing a formula
 //
 // Errors:
@@ -786,27 +861,10 @@ func execFormula(ctx context.Context, cfg internalConfig) (wfapi.RunRecord, erro
 		context = *cfg.FormulaAndContext.Context.FormulaContext
 	}
 
-	// convert formula to node
-	nFormula := bindnode.Wrap(cfg.FormulaAndContext.Formula.Formula, wfapi.TypeSystem.TypeByName("Formula"))
-
 	// set up the runrecord result
 	rr.Guid = uuid.New().String()
 	rr.Time = time.Now().Unix()
-	lsys := cidlink.DefaultLinkSystem()
-	lnk, errRaw := lsys.ComputeLink(cidlink.LinkPrototype{Prefix: cid.Prefix{
-		Version:  1,    // Usually '1'.
-		Codec:    0x71, // 0x71 means "dag-cbor" -- See the multicodecs table: https://github.com/multiformats/multicodec/
-		MhType:   0x20, // 0x20 means "sha2-384" -- See the multicodecs table: https://github.com/multiformats/multicodec/
-		MhLength: 48,   // sha2-384 hash has a 48-byte sum.
-	}}, nFormula.(schema.TypedNode).Representation())
-	if errRaw != nil {
-		// panic! this should never fail unless IPLD is broken
-		panic(fmt.Sprintf("Fatal IPLD Error: lsys.ComputeLink failed for Formula: %s", errRaw))
-	}
-	fid, errRaw := lnk.(cidlink.Link).StringOfBase('z')
-	if errRaw != nil {
-		panic(fmt.Sprintf("Fatal IPLD Error: failed to encode CID for Formula: %s", errRaw))
-	}
+	fid := FormulaID(*formula)
 	rr.FormulaID = fid
 	span.SetAttributes(attribute.String(tracing.AttrKeyWarpforgeFormulaId, fid))
 	logger.Info(LOG_TAG_START, "")
@@ -226,6 +226,31 @@ func (l *Logger) PrintPlotResults(tag string, pr wfapi.PlotResults) {
 	}
 }
 
+func (l *Logger) PrintPlotPlan(tag string, pp wfapi.PlotPlan) {
+	if l.json {
+		out := wfapi.ApiOutput{
+			PlotPlan: &pp,
+		}
+		apiWrite(l.out, out)
+	} else {
+		l.Info(tag, "plan:")
+		for _, step := range pp.Steps {
+			formulaID := "unknown"
+			if step.FormulaID != nil {
+				formulaID = *step.FormulaID
+			}
+			l.Info(tag, "\t%s: %s\t%s = %s",
+				color.HiCyanString(step.Step),
+				color.WhiteString(string(step.Status)),
+				color.HiBlueString("formulaID"),
+				color.WhiteString(formulaID))
+			for _, reason := range step.Reasons {
+				l.Info(tag, "\t\t%s", reason)
+			}
+		}
+	}
+}
+
 type Writer struct {
 	pipe     io.Writer
 	tag      string
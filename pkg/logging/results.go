@@ -0,0 +1,265 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// infoTable logs rows of tab separated columns, aligned as a table.
+func (l *Logger) infoTable(tag string, header string, rows []string) {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, header)
+	for _, row := range rows {
+		fmt.Fprintln(tw, row)
+	}
+	tw.Flush()
+	l.Info(tag, "%s", strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// PrintCatalogSearchResults logs the results of a catalog search, as a table.
+func (l *Logger) PrintCatalogSearchResults(tag string, r wfapi.CatalogSearchResults) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{CatalogSearchResults: &r})
+		return
+	}
+	rows := make([]string, 0, len(r.Results))
+	for _, result := range r.Results {
+		// the catalog of a non-root workspace has no name, so show where it is instead
+		catalogName := result.Catalog
+		if catalogName == "" {
+			catalogName = result.Workspace
+		}
+		rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s\t%s", catalogName,
+			result.Ref.ModuleName, result.Ref.ReleaseName, result.Ref.ItemName, result.WareID.String()))
+	}
+	l.infoTable(tag, "CATALOG\tMODULE\tRELEASE\tITEM\tWARE", rows)
+}
+
+// PrintCatalogDiff logs the differences between two catalogs, one per line.
+func (l *Logger) PrintCatalogDiff(tag string, d wfapi.CatalogDiff) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{CatalogDiff: &d})
+		return
+	}
+	if len(d.Entries) == 0 {
+		l.Info(tag, "no differences between %s and %s", d.Old, d.New)
+		return
+	}
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	for _, e := range d.Entries {
+		release := string(e.Module)
+		if e.Release != nil {
+			release = fmt.Sprintf("%s:%s", e.Module, *e.Release)
+		}
+		item := release
+		if e.Item != nil {
+			item = fmt.Sprintf("%s:%s", release, *e.Item)
+		}
+		switch e.Kind {
+		case wfapi.CatalogDiffKind_ModuleAdded:
+			l.Info(tag, "+ module %s", e.Module)
+		case wfapi.CatalogDiffKind_ModuleRemoved:
+			l.Info(tag, "- module %s", e.Module)
+		case wfapi.CatalogDiffKind_ReleaseAdded:
+			l.Info(tag, "+ release %s", release)
+		case wfapi.CatalogDiffKind_ReleaseRemoved:
+			l.Info(tag, "- release %s", release)
+		case wfapi.CatalogDiffKind_ReleaseChanged:
+			l.Info(tag, "~ release %s: metadata or status changed", release)
+		case wfapi.CatalogDiffKind_ItemAdded:
+			l.Info(tag, "+ item %s %s", item, str(e.New))
+		case wfapi.CatalogDiffKind_ItemRemoved:
+			l.Info(tag, "- item %s %s", item, str(e.Old))
+		case wfapi.CatalogDiffKind_ItemChanged:
+			l.Info(tag, "~ item %s %s -> %s", item, str(e.Old), str(e.New))
+		case wfapi.CatalogDiffKind_MirrorAdded:
+			l.Info(tag, "+ mirror %s: %s", e.Module, str(e.New))
+		case wfapi.CatalogDiffKind_MirrorRemoved:
+			l.Info(tag, "- mirror %s: %s", e.Module, str(e.Old))
+		}
+	}
+}
+
+// PrintReverseDependencies logs the reverse dependencies of a catalog item or ware, as a table.
+func (l *Logger) PrintReverseDependencies(tag string, r wfapi.ReverseDependencies) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{ReverseDependencies: &r})
+		return
+	}
+	if len(r.Dependents) == 0 {
+		l.Info(tag, "no releases depend on %s", r.Target)
+		return
+	}
+	rows := make([]string, 0, len(r.Dependents))
+	for _, d := range r.Dependents {
+		// the catalog of a non-root workspace has no name, so show where it is instead
+		catalogName := d.Catalog
+		if catalogName == "" {
+			catalogName = d.Workspace
+		}
+		release := fmt.Sprintf("%s:%s", d.Module, d.Release)
+		if d.Possible {
+			release += " (possible)"
+		}
+		rows = append(rows, fmt.Sprintf("%d\t%s\t%s\t%s", d.Depth, catalogName, release, strings.Join(d.Via, ", ")))
+	}
+	l.infoTable(tag, "DEPTH\tCATALOG\tRELEASE\tVIA", rows)
+}
+
+// PrintTagIngestResults logs the tags ingested into a catalog, followed by a summary of those skipped.
+func (l *Logger) PrintTagIngestResults(tag string, r wfapi.TagIngestResults) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{TagIngestResults: &r})
+		return
+	}
+	ingested := 0
+	skipped := map[wfapi.TagIngestAction]int{}
+	for _, t := range r.Tags {
+		if !t.Ingested {
+			skipped[t.Action]++
+			continue
+		}
+		ingested++
+		l.Info(tag, "%s -> %s:%s:%s %s", t.Tag, r.Module, t.Release, r.Item, t.WareID.String())
+	}
+	l.Info(tag, "ingested %d tags; skipped %d already in the catalog, %d moved, and %d older than the newest release",
+		ingested, skipped[wfapi.TagIngestAction_Exists], skipped[wfapi.TagIngestAction_Moved], skipped[wfapi.TagIngestAction_Older])
+}
+
+// PrintFetchReport logs what was done to fetch each ware, followed by a count of the wares by status.
+func (l *Logger) PrintFetchReport(tag string, r wfapi.FetchReport) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{FetchReport: &r})
+		return
+	}
+	counts := map[wfapi.FetchStatus]int{}
+	for _, ware := range r.Wares {
+		counts[ware.Status]++
+		name := ware.WareID.String()
+		if ware.Ref != nil {
+			name = fmt.Sprintf("%s (%s)", name, ware.Ref.String())
+		}
+		switch ware.Status {
+		case wfapi.FetchStatus_Fetched:
+			l.Info(tag, "fetched %s from %s", name, *ware.Source)
+		case wfapi.FetchStatus_Unavailable:
+			l.Info(tag, "unavailable: %s is not in any warehouse or mirror", name)
+		case wfapi.FetchStatus_Unsupported:
+			l.Info(tag, "skipped %s: wares of this packtype are fetched during execution", name)
+		case wfapi.FetchStatus_Failed:
+			l.Info(tag, "failed to fetch %s:", name)
+			for _, e := range ware.Errors {
+				l.Info(tag, "\t%s", e)
+			}
+		}
+	}
+	l.Info(tag, "%d wares needed: %d already present, %d fetched, %d skipped, %d unavailable, %d failed",
+		len(r.Wares), counts[wfapi.FetchStatus_Present], counts[wfapi.FetchStatus_Fetched], counts[wfapi.FetchStatus_Unsupported],
+		counts[wfapi.FetchStatus_Unavailable], counts[wfapi.FetchStatus_Failed])
+}
+
+// PrintMirrorReport logs a summary of mirroring to each warehouse, followed by the wares which failed.
+func (l *Logger) PrintMirrorReport(tag string, r wfapi.MirrorReport) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{MirrorReport: &r})
+		return
+	}
+	for _, row := range r.Warehouses {
+		if row.Error != nil {
+			l.Info(tag, "%s: failed: %s", row.Warehouse, *row.Error)
+			continue
+		}
+		l.Info(tag, "%s: pushed %d, already present %d, missing locally %d, failed %d",
+			row.Warehouse, row.Pushed, row.AlreadyPresent, row.MissingLocally, row.Failed)
+		for _, ware := range row.Wares {
+			if ware.Error != nil {
+				l.Info(tag, "\t%s (%s:%s:%s): %s", ware.WareID.String(),
+					ware.Ref.ModuleName, ware.Ref.ReleaseName, ware.Ref.ItemName, *ware.Error)
+			}
+		}
+	}
+}
+
+// PrintMirrorCheckReport logs a summary of checking each warehouse, followed by the wares it lacks.
+func (l *Logger) PrintMirrorCheckReport(tag string, r wfapi.MirrorCheckReport) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{MirrorCheckReport: &r})
+		return
+	}
+	for _, row := range r.Warehouses {
+		if row.Error != nil {
+			l.Info(tag, "%s: failed: %s", row.Warehouse, *row.Error)
+			continue
+		}
+		rehashed := ""
+		if row.Rehashed {
+			rehashed = " (rehashed)"
+		}
+		l.Info(tag, "%s: ok %d%s, missing %d, corrupt %d, failed %d",
+			row.Warehouse, row.Ok, rehashed, row.Missing, row.Corrupt, row.Failed)
+		for _, ware := range row.Wares {
+			item := fmt.Sprintf("%s:%s:%s", ware.Ref.ModuleName, ware.Ref.ReleaseName, ware.Ref.ItemName)
+			switch ware.Status {
+			case wfapi.MirrorCheckStatus_Missing:
+				l.Info(tag, "\tmissing %s (%s)", ware.WareID.String(), item)
+			case wfapi.MirrorCheckStatus_Corrupt, wfapi.MirrorCheckStatus_Failed:
+				if ware.Error != nil {
+					l.Info(tag, "\t%s %s (%s): %s", ware.Status, ware.WareID.String(), item, *ware.Error)
+				}
+			}
+		}
+	}
+}
+
+// PrintReplayVerificationReport logs the outcome of verifying each release's replay, with the items which differ.
+// Releases without replays are left out, unless no release had a replay at all.
+func (l *Logger) PrintReplayVerificationReport(tag string, r wfapi.ReplayVerificationReport) {
+	if l.json {
+		apiWrite(l.out, wfapi.ApiOutput{ReplayVerificationReport: &r})
+		return
+	}
+	replayed := 0
+	for _, row := range r.Releases {
+		if row.Status == wfapi.ReplayVerificationStatus_NoReplay {
+			continue
+		}
+		replayed++
+		name := fmt.Sprintf("%s:%s", row.Module, row.Release)
+		switch row.Status {
+		case wfapi.ReplayVerificationStatus_Verified:
+			matched := 0
+			for _, item := range row.Items {
+				if item.Status == wfapi.ReplayItemStatus_Match {
+					matched++
+				}
+			}
+			l.Info(tag, "%s: verified %d items", name, matched)
+		case wfapi.ReplayVerificationStatus_Mismatch:
+			l.Info(tag, "%s: replay does not reproduce the release", name)
+		case wfapi.ReplayVerificationStatus_Failed:
+			l.Info(tag, "%s: replay failed: %s", name, *row.Error)
+		}
+		for _, item := range row.Items {
+			switch item.Status {
+			case wfapi.ReplayItemStatus_Mismatch:
+				l.Info(tag, "\t%s: expected %s, replay produced %s", item.Item, item.Expected, item.Produced)
+			case wfapi.ReplayItemStatus_Missing:
+				l.Info(tag, "\t%s: expected %s, replay did not produce it", item.Item, item.Expected)
+			case wfapi.ReplayItemStatus_Extra:
+				l.Info(tag, "\t%s: produced %s, which is not an item of the release", item.Item, item.Produced)
+			}
+		}
+	}
+	if replayed == 0 {
+		l.Info(tag, "no releases with replays to verify")
+	}
+}
@@ -0,0 +1,170 @@
+package mirroring
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/fetch"
+	"github.com/warptools/warpforge/pkg/logging"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// CheckOptions controls how CheckWarehouseAddr checks a mirror.
+type CheckOptions struct {
+	Concurrency int        // The most wares to check at once. Values below 1 are treated as 1.
+	Filter      WareFilter // Which of the catalog's wares to check.
+
+	// Verify, if set, is used to rehash each ware the mirror says it has, after downloading it from the mirror.
+	// Only mirrors whose address is readable, as by fetch.Readable, can be downloaded from;
+	// other mirrors are only asked whether they have each ware.
+	Verify fetch.Verifier
+}
+
+// CheckResult is the outcome of checking a mirror for one ware.
+type CheckResult struct {
+	WareID   wfapi.WareID
+	Ref      wfapi.CatalogRef // The first catalog item found with the ware; others may have it too.
+	Status   wfapi.MirrorCheckStatus
+	Rehashed bool  // True if the mirror's copy of the ware was downloaded and rehashed.
+	Err      error // Why the ware is corrupt, or couldn't be checked.
+}
+
+// CheckReport is the outcome of CheckWarehouseAddr, with a result for each ware, in catalog order.
+type CheckReport struct {
+	Results []CheckResult
+}
+
+// Count returns how many wares had the given outcome.
+func (r CheckReport) Count(status wfapi.MirrorCheckStatus) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// CheckWarehouseAddr checks that a mirror has every ware the catalog says it does, without pushing anything.
+//
+// The wares checked are the same ones PushToWarehouseAddr would push,
+// and the mirror is asked for each in the same way, so the configuration for pushing to the mirror is needed.
+// Wares are checked concurrently, as configured by the options, and may be rehashed.
+// Only the wares selected by the options' filter are checked.
+//
+// Errors:
+//
+// 	- warpforge-error-invalid-argument -- when the filter is not valid
+// 	- warpforge-error-io -- when the mirror cannot be accessed at all, or a temporary directory cannot be created
+// 	- warpforge-error-connection -- when a remote mirror cannot be reached at all
+//  - warpforge-error-catalog-invalid -- when the provided catalog contains invalid data
+//  - warpforge-error-catalog-missing-entry -- should never occur, as we iterate over the contents of the catalog
+//  - warpforge-error-catalog-parse -- when the provided catalog cannot be parsed
+func CheckWarehouseAddr(ctx context.Context, cat workspace.Catalog, addr wfapi.WarehouseAddr, cfg wfapi.WarehouseMirroringConfig, opts CheckOptions) (CheckReport, error) {
+	log := logging.Ctx(ctx)
+
+	pusher, err := pusherFromConfig(ctx, cfg)
+	if err != nil {
+		return CheckReport{}, err
+	}
+
+	wares, err := catalogWares(cat, addr, opts.Filter)
+	if err != nil {
+		return CheckReport{}, err
+	}
+	var report CheckReport
+	for _, ware := range wares {
+		report.Results = append(report.Results, CheckResult{WareID: ware.wareId, Ref: ware.ref})
+	}
+
+	verify := opts.Verify
+	if !fetch.Readable(addr) {
+		verify = nil
+	}
+	// wares are downloaded with the same headers they're pushed with, since the mirror may need them to be read too
+	fetchOpts := fetch.Options{Verify: verify}
+	if cfg.PushConfig.Http != nil {
+		fetchOpts.Header = httpHeader(*cfg.PushConfig.Http)
+	}
+	var tmpDir string
+	if verify != nil {
+		tmpDir, err = os.MkdirTemp("", "warpforge-mirror-check-")
+		if err != nil {
+			return CheckReport{}, serum.Errorf(wfapi.ECodeIo, "failed to create temporary directory: %s", err)
+		}
+		defer os.RemoveAll(tmpDir)
+	}
+
+	// each worker takes the next ware until they're all done.
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				result := &report.Results[idx]
+				result.Status, result.Rehashed, result.Err = checkOnce(ctx, pusher, addr, result.WareID, fetchOpts, tmpDir)
+				switch result.Status {
+				case wfapi.MirrorCheckStatus_Missing:
+					log.Info("mirror", "mirror %q is missing wareId %q", addr, result.WareID.String())
+				case wfapi.MirrorCheckStatus_Corrupt:
+					log.Info("mirror", "mirror %q has a corrupt copy of wareId %q: %s", addr, result.WareID.String(), result.Err)
+				case wfapi.MirrorCheckStatus_Failed:
+					log.Info("mirror", "failed to check mirror %q for wareId %q: %s", addr, result.WareID.String(), result.Err)
+				}
+			}
+		}()
+	}
+	for idx := range report.Results {
+		next <- idx
+	}
+	close(next)
+	wg.Wait()
+	return report, nil
+}
+
+// Checks a mirror for a single ware, downloading it into the temporary directory given to rehash it if the fetch options have a verifier.
+// Returns whether the ware was rehashed.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for IO errors while checking, or when a remote mirror refuses a request
+// 	- warpforge-error-connection -- when a remote mirror cannot be reached, or fails in a way that may be transient
+// 	- warpforge-error-ware-corrupt -- when the mirror's copy of the ware doesn't match its WareID
+// 	- warpforge-error-executor-failed -- when the ware cannot be rehashed
+func checkOnce(ctx context.Context, pusher pusher, addr wfapi.WarehouseAddr, wareId wfapi.WareID, fetchOpts fetch.Options, tmpDir string) (wfapi.MirrorCheckStatus, bool, error) {
+	hasWare, err := pusher.hasWare(wareId)
+	if err != nil {
+		return wfapi.MirrorCheckStatus_Failed, false, err
+	}
+	if !hasWare {
+		return wfapi.MirrorCheckStatus_Missing, false, nil
+	}
+	if fetchOpts.Verify == nil {
+		return wfapi.MirrorCheckStatus_Ok, false, nil
+	}
+
+	dest := filepath.Join(tmpDir, string(wareId.Packtype)+"-"+wareId.Hash)
+	found, err := fetch.FetchFrom(ctx, fetchOpts, wareId, addr, dest)
+	switch {
+	case serum.Code(err) == wfapi.ECodeWareCorrupt:
+		return wfapi.MirrorCheckStatus_Corrupt, true, err
+	case err != nil:
+		return wfapi.MirrorCheckStatus_Failed, false, err
+	case !found:
+		// the mirror says it has the ware, but won't give it up
+		return wfapi.MirrorCheckStatus_Missing, false, nil
+	}
+	os.Remove(dest)
+	return wfapi.MirrorCheckStatus_Ok, true, nil
+}
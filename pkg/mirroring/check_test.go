@@ -0,0 +1,149 @@
+package mirroring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestCheckWarehouseAddr(t *testing.T) {
+	ws, _ := newTestRootWorkspace(t)
+	cat, err := ws.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+
+	// the mirror has one ware intact, one corrupt, refuses to say about another, and is missing the last.
+	cas := &casServer{objects: map[string][]byte{}}
+	var refused wfapi.WareID
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+refused.Subpath() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		cas.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	addr := wfapi.WarehouseAddr("ca+" + server.URL)
+	addWare := func(module wfapi.ModuleName, hash string, content string) wfapi.WareID {
+		wareId := wfapi.WareID{Packtype: "tar", Hash: hash}
+		ref := wfapi.CatalogRef{ModuleName: module, ReleaseName: "v1", ItemName: "src"}
+		qt.Assert(t, cat.AddItem(ref, wareId, false), qt.IsNil)
+		qt.Assert(t, cat.AddByModuleMirror(ref, "tar", addr), qt.IsNil)
+		if content != "" {
+			cas.objects["/"+wareId.Subpath()] = []byte(content)
+		}
+		return wareId
+	}
+	intact := addWare("example.com/intact", "aaaaaaaaaa", "aaaaaaaaaa")
+	corrupt := addWare("example.com/corrupt", "bbbbbbbbbb", "garbage")
+	refused = addWare("example.com/refused", "cccccccccc", "cccccccccc")
+	missing := addWare("example.com/missing", "dddddddddd", "")
+
+	// the catalog's module list is read when it's opened
+	cat, err = ws.OpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	var cfg wfapi.WarehouseMirroringConfig
+	cfg.PushConfig.Http = &wfapi.HttpPushConfig{Endpoint: server.URL}
+
+	statuses := func(report CheckReport) map[wfapi.WareID]wfapi.MirrorCheckStatus {
+		result := map[wfapi.WareID]wfapi.MirrorCheckStatus{}
+		for _, r := range report.Results {
+			result[r.WareID] = r.Status
+		}
+		return result
+	}
+
+	// without rehashing, corruption goes unnoticed
+	report, err := CheckWarehouseAddr(context.Background(), cat, addr, cfg, CheckOptions{Concurrency: 2})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, statuses(report), qt.DeepEquals, map[wfapi.WareID]wfapi.MirrorCheckStatus{
+		intact:  wfapi.MirrorCheckStatus_Ok,
+		corrupt: wfapi.MirrorCheckStatus_Ok,
+		refused: wfapi.MirrorCheckStatus_Failed,
+		missing: wfapi.MirrorCheckStatus_Missing,
+	})
+
+	// wares have their hash as their content, which the verifier checks
+	verify := func(ctx context.Context, wareId wfapi.WareID, path string) error {
+		content, err := os.ReadFile(path)
+		qt.Assert(t, err, qt.IsNil)
+		if actual := (wfapi.WareID{Packtype: wareId.Packtype, Hash: string(content)}); actual != wareId {
+			return wfapi.ErrorWareCorrupt(wareId, actual)
+		}
+		return nil
+	}
+	report, err = CheckWarehouseAddr(context.Background(), cat, addr, cfg, CheckOptions{Concurrency: 2, Verify: verify})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, statuses(report), qt.DeepEquals, map[wfapi.WareID]wfapi.MirrorCheckStatus{
+		intact:  wfapi.MirrorCheckStatus_Ok,
+		corrupt: wfapi.MirrorCheckStatus_Corrupt,
+		refused: wfapi.MirrorCheckStatus_Failed,
+		missing: wfapi.MirrorCheckStatus_Missing,
+	})
+	qt.Check(t, report.Count(wfapi.MirrorCheckStatus_Ok), qt.Equals, 1)
+	for _, result := range report.Results {
+		switch result.WareID {
+		case intact:
+			qt.Check(t, result.Rehashed, qt.IsTrue)
+		case corrupt:
+			qt.Check(t, serum.Code(result.Err), qt.Equals, wfapi.ECodeWareCorrupt)
+		case refused:
+			qt.Check(t, serum.Code(result.Err), qt.Equals, wfapi.ECodeIo)
+		}
+	}
+	// nothing was pushed
+	qt.Check(t, cas.objects, qt.HasLen, 3)
+}
+
+func TestCheckWarehouseAddrHeaders(t *testing.T) {
+	ws, _ := newTestRootWorkspace(t)
+	cat, err := ws.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+
+	// the mirror is private, so reading from it needs the same authorization as pushing to it
+	cas := &casServer{objects: map[string][]byte{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		cas.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	addr := wfapi.WarehouseAddr("ca+" + server.URL)
+	wareId := wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+	ref := wfapi.CatalogRef{ModuleName: "example.com/private", ReleaseName: "v1", ItemName: "src"}
+	qt.Assert(t, cat.AddItem(ref, wareId, false), qt.IsNil)
+	qt.Assert(t, cat.AddByModuleMirror(ref, "tar", addr), qt.IsNil)
+	cas.objects["/"+wareId.Subpath()] = []byte(wareId.Hash)
+	cat, err = ws.OpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+
+	var cfg wfapi.WarehouseMirroringConfig
+	cfg.PushConfig.Http = &wfapi.HttpPushConfig{Endpoint: server.URL}
+	cfg.PushConfig.Http.Headers = &struct {
+		Keys   []string
+		Values map[string]string
+	}{
+		Keys:   []string{"Authorization"},
+		Values: map[string]string{"Authorization": "Bearer secret"},
+	}
+	verified := 0
+	verify := func(ctx context.Context, wareId wfapi.WareID, path string) error {
+		verified++
+		return nil
+	}
+	report, err := CheckWarehouseAddr(context.Background(), cat, addr, cfg, CheckOptions{Verify: verify})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, report.Results, qt.HasLen, 1)
+	qt.Check(t, report.Results[0].Err, qt.IsNil)
+	qt.Check(t, report.Results[0].Status, qt.Equals, wfapi.MirrorCheckStatus_Ok)
+	qt.Check(t, report.Results[0].Rehashed, qt.IsTrue)
+	qt.Check(t, verified, qt.Equals, 1)
+}
@@ -0,0 +1,79 @@
+package mirroring
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/serum-errors/go-serum"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// FsPusher pushes wares into a directory laid out like a warehouse,
+// which may be on a local disk or a network mount.
+type FsPusher struct {
+	ctx context.Context
+	cfg wfapi.FsPushConfig
+}
+
+// Errors:
+//
+// 	- warpforge-error-io -- when the directory does not exist or is not a directory
+func newFsPusher(ctx context.Context, cfg wfapi.FsPushConfig) (FsPusher, error) {
+	info, err := os.Stat(cfg.Path)
+	if err != nil {
+		return FsPusher{}, serum.Errorf(wfapi.ECodeIo, "could not access mirror directory %q: %s", cfg.Path, err)
+	}
+	if !info.IsDir() {
+		return FsPusher{}, serum.Errorf(wfapi.ECodeIo, "mirror path %q is not a directory", cfg.Path)
+	}
+	return FsPusher{ctx: ctx, cfg: cfg}, nil
+}
+
+func (p *FsPusher) hasWare(wareId wfapi.WareID) (bool, error) {
+	path := filepath.Join(p.cfg.Path, wareId.Subpath())
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, serum.Errorf(wfapi.ECodeIo, "failed to stat %q: %s", path, err)
+	}
+	return true, nil
+}
+
+func (p *FsPusher) pushWare(wareId wfapi.WareID, localPath string) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to open %q: %s", localPath, err)
+	}
+	defer src.Close()
+
+	// write via a temporary file in the destination directory, then rename it into place,
+	// so that anyone reading from the mirror never sees a partial ware.
+	dest := filepath.Join(p.cfg.Path, wareId.Subpath())
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to create directory %q: %s", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to create temporary file in %q: %s", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return serum.Errorf(wfapi.ECodeIo, "failed to write %q: %s", dest, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to write %q: %s", dest, err)
+	}
+	// temporary files are created private, but a mirror is for sharing.
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to write %q: %s", dest, err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to write %q: %s", dest, err)
+	}
+	return nil
+}
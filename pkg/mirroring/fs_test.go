@@ -0,0 +1,41 @@
+package mirroring
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestFsPusher(t *testing.T) {
+	mirrorPath := t.TempDir()
+	localPath := filepath.Join(t.TempDir(), "ware")
+	qt.Assert(t, os.WriteFile(localPath, []byte("ware contents"), 0600), qt.IsNil)
+	wareId := wfapi.WareID{Packtype: "tar", Hash: "abcdefghijklmnop"}
+
+	pusher, err := newFsPusher(context.Background(), wfapi.FsPushConfig{Path: mirrorPath})
+	qt.Assert(t, err, qt.IsNil)
+	has, err := pusher.hasWare(wareId)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, has, qt.IsFalse)
+
+	qt.Assert(t, pusher.pushWare(wareId, localPath), qt.IsNil)
+	has, err = pusher.hasWare(wareId)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, has, qt.IsTrue)
+	contents, err := os.ReadFile(filepath.Join(mirrorPath, "abc", "def", "abcdefghijklmnop"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, string(contents), qt.Equals, "ware contents")
+	// nothing else is left behind
+	entries, err := os.ReadDir(filepath.Join(mirrorPath, "abc", "def"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, entries, qt.HasLen, 1)
+
+	_, err = newFsPusher(context.Background(), wfapi.FsPushConfig{Path: filepath.Join(mirrorPath, "nonexistent")})
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeIo)
+}
@@ -0,0 +1,125 @@
+package mirroring
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// HttpPusher pushes wares to a generic content-addressed HTTP endpoint,
+// with a PUT of each ware to the endpoint joined with the ware's subpath.
+type HttpPusher struct {
+	ctx    context.Context
+	client *http.Client
+	cfg    wfapi.HttpPushConfig
+}
+
+// Errors:
+//
+// 	- warpforge-error-io -- when the endpoint is not an http or https URL
+func newHttpPusher(ctx context.Context, cfg wfapi.HttpPushConfig) (HttpPusher, error) {
+	if !strings.HasPrefix(cfg.Endpoint, "http://") && !strings.HasPrefix(cfg.Endpoint, "https://") {
+		return HttpPusher{}, serum.Errorf(wfapi.ECodeIo, "mirror endpoint %q is not an http or https URL", cfg.Endpoint)
+	}
+	return HttpPusher{ctx: ctx, client: http.DefaultClient, cfg: cfg}, nil
+}
+
+// httpHeader returns the headers configured to be sent with each request to the mirror.
+func httpHeader(cfg wfapi.HttpPushConfig) http.Header {
+	header := http.Header{}
+	if cfg.Headers != nil {
+		for _, k := range cfg.Headers.Keys {
+			header.Set(k, cfg.Headers.Values[k])
+		}
+	}
+	return header
+}
+
+func (p *HttpPusher) wareUrl(wareId wfapi.WareID) string {
+	return strings.TrimSuffix(p.cfg.Endpoint, "/") + "/" + filepath.ToSlash(wareId.Subpath())
+}
+
+// Errors:
+//
+// 	- warpforge-error-connection -- when the request cannot be made
+func (p *HttpPusher) do(req *http.Request) (*http.Response, error) {
+	for k, v := range httpHeader(p.cfg) {
+		req.Header[k] = v
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, serum.Errorf(wfapi.ECodeConnection, "%s %q failed: %s", req.Method, req.URL, err)
+	}
+	return resp, nil
+}
+
+func (p *HttpPusher) hasWare(wareId wfapi.WareID) (bool, error) {
+	url := p.wareUrl(wareId)
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return false, serum.Errorf(wfapi.ECodeConnection, "invalid mirror URL %q: %s", url, err)
+	}
+	resp, err := p.do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, responseError(resp)
+}
+
+// responseError describes an unexpected response from the server.
+// Server errors, and responses asking the client to try again later, may be transient,
+// so they have the connection error code; other responses have the io error code.
+//
+// Errors:
+//
+// 	- warpforge-error-connection -- when the server failed, and a retry may succeed
+// 	- warpforge-error-io -- when the server refused the request
+func responseError(resp *http.Response) error {
+	code := wfapi.ECodeIo
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests {
+		code = wfapi.ECodeConnection
+	}
+	return serum.Errorf(code, "%s %q: server responded %s", resp.Request.Method, resp.Request.URL, resp.Status)
+}
+
+func (p *HttpPusher) pushWare(wareId wfapi.WareID, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to open %q: %s", localPath, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return serum.Errorf(wfapi.ECodeIo, "failed to stat %q: %s", localPath, err)
+	}
+
+	url := p.wareUrl(wareId)
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodPut, url, file)
+	if err != nil {
+		return serum.Errorf(wfapi.ECodeConnection, "invalid mirror URL %q: %s", url, err)
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := p.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	}
+	return responseError(resp)
+}
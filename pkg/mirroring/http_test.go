@@ -0,0 +1,94 @@
+package mirroring
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// casServer is a minimal content-addressed store, which serves its objects to anyone and accepts PUTs from authorized clients.
+type casServer struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *casServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodHead:
+		if _, ok := s.objects[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case http.MethodGet:
+		body, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodPut:
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = body
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHttpPusher(t *testing.T) {
+	cas := &casServer{objects: map[string][]byte{}}
+	server := httptest.NewServer(cas)
+	defer server.Close()
+
+	localPath := filepath.Join(t.TempDir(), "ware")
+	qt.Assert(t, os.WriteFile(localPath, []byte("ware contents"), 0600), qt.IsNil)
+	wareId := wfapi.WareID{Packtype: "tar", Hash: "abcdefghijklmnop"}
+
+	cfg := wfapi.HttpPushConfig{Endpoint: server.URL + "/wares/"}
+	pusher, err := newHttpPusher(context.Background(), cfg)
+	qt.Assert(t, err, qt.IsNil)
+	has, err := pusher.hasWare(wareId)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, has, qt.IsFalse)
+
+	// without the authorization header, the server refuses the ware
+	err = pusher.pushWare(wareId, localPath)
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeIo)
+
+	cfg.Headers = &struct {
+		Keys   []string
+		Values map[string]string
+	}{
+		Keys:   []string{"Authorization"},
+		Values: map[string]string{"Authorization": "Bearer secret"},
+	}
+	pusher, err = newHttpPusher(context.Background(), cfg)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, pusher.pushWare(wareId, localPath), qt.IsNil)
+	qt.Check(t, string(cas.objects["/wares/abc/def/abcdefghijklmnop"]), qt.Equals, "ware contents")
+	has, err = pusher.hasWare(wareId)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, has, qt.IsTrue)
+
+	_, err = newHttpPusher(context.Background(), wfapi.HttpPushConfig{Endpoint: "ftp://example.com"})
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeIo)
+}
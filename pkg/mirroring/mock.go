@@ -2,6 +2,7 @@ package mirroring
 
 import (
 	"context"
+	"sync"
 
 	"github.com/warptools/warpforge/wfapi"
 )
@@ -11,19 +12,24 @@ import (
 type MockPusher struct {
 	ctx   context.Context
 	cfg   wfapi.MockPushConfig
+	mu    *sync.Mutex
 	wares map[wfapi.WareID]bool
 }
 
 func newMockPusher(ctx context.Context, cfg wfapi.MockPushConfig) (MockPusher, error) {
-	return MockPusher{ctx: ctx, cfg: cfg}, nil
+	return MockPusher{ctx: ctx, cfg: cfg, mu: &sync.Mutex{}, wares: map[wfapi.WareID]bool{}}, nil
 }
 
 func (p *MockPusher) hasWare(wareId wfapi.WareID) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	_, exists := p.wares[wareId]
 	return exists, nil
 }
 
 func (p *MockPusher) pushWare(wareId wfapi.WareID, localPath string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.wares[wareId] = true
 	return nil
 }
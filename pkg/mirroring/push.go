@@ -3,6 +3,8 @@ package mirroring
 import (
 	"context"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/serum-errors/go-serum"
 	"github.com/warptools/warpforge/pkg/logging"
@@ -10,14 +12,17 @@ import (
 	"github.com/warptools/warpforge/wfapi"
 )
 
+// A pusher uploads wares to a mirror. Pushers must be safe for concurrent use.
 type pusher interface {
 	// Errors:
 	//
-	// 	- warpforge-error-io -- for IO errors that occur during push operations
+	// 	- warpforge-error-io -- for IO errors that occur during push operations, or when a remote mirror refuses a request
+	// 	- warpforge-error-connection -- when a remote mirror cannot be reached, or fails in a way that may be transient
 	hasWare(wfapi.WareID) (bool, error)
 	// Errors:
 	//
-	// 	- warpforge-error-io -- for IO errors that occur during push operations
+	// 	- warpforge-error-io -- for IO errors that occur during push operations, or when a remote mirror refuses a request
+	// 	- warpforge-error-connection -- when a remote mirror cannot be reached, or fails in a way that may be transient
 	pushWare(wfapi.WareID, string) error
 }
 
@@ -25,6 +30,12 @@ func pusherFromConfig(ctx context.Context, cfg wfapi.WarehouseMirroringConfig) (
 	if cfg.PushConfig.S3 != nil {
 		pusher, err := newS3Pusher(ctx, *cfg.PushConfig.S3)
 		return &pusher, err
+	} else if cfg.PushConfig.Fs != nil {
+		pusher, err := newFsPusher(ctx, *cfg.PushConfig.Fs)
+		return &pusher, err
+	} else if cfg.PushConfig.Http != nil {
+		pusher, err := newHttpPusher(ctx, *cfg.PushConfig.Http)
+		return &pusher, err
 	} else if cfg.PushConfig.Mock != nil {
 		pusher, err := newMockPusher(ctx, *cfg.PushConfig.Mock)
 		return &pusher, err
@@ -33,83 +44,318 @@ func pusherFromConfig(ctx context.Context, cfg wfapi.WarehouseMirroringConfig) (
 	panic("no supported push configuration provided")
 }
 
+// PushOptions controls how PushToWarehouseAddr pushes wares.
+type PushOptions struct {
+	Concurrency int           // The most wares to push at once. Values below 1 are treated as 1.
+	Retries     int           // How many times to retry a ware after a transient failure, before giving up on it.
+	Backoff     time.Duration // How long to wait before the first retry. The wait doubles for each retry after that.
+	Filter      WareFilter    // Which of the catalog's wares to push.
+}
+
+// WareFilter narrows the wares of a catalog which are mirrored, or checked, to those of some of its items.
+// The zero value selects every ware.
+//
+// Modules and releases which are filtered out are skipped without reading them,
+// so mirroring a single new release of a large catalog only needs to look at that release.
+type WareFilter struct {
+	Modules   []string            // Globs, as per workspace.MatchGlob, for the names of the modules to include. If any are given, a module must match one.
+	Releases  []wfapi.ReleaseName // The names of the releases to include. If empty, every release is included.
+	Items     []wfapi.ItemLabel   // The labels of the items to include. If empty, every item is included.
+	NewerThan wfapi.ReleaseName   // If set, only releases ordered after the release of this name, as by workspace.CompareReleases, are included.
+}
+
+// Validate checks that every glob in the filter is well formed.
+//
+// Errors:
+//
+// 	- warpforge-error-invalid-argument -- when a module glob is not valid
+func (f WareFilter) Validate() error {
+	for _, pattern := range f.Modules {
+		if _, err := workspace.MatchGlob(pattern, ""); err != nil {
+			return serum.Error(wfapi.ECodeArgument,
+				serum.WithMessageTemplate("invalid module glob {{pattern|q}}"),
+				serum.WithDetail("pattern", pattern),
+				serum.WithCause(err),
+			)
+		}
+	}
+	return nil
+}
+
+// Returns true if the filter includes the module. The filter must have been validated already.
+func (f WareFilter) includesModule(moduleName wfapi.ModuleName) bool {
+	if len(f.Modules) == 0 {
+		return true
+	}
+	for _, pattern := range f.Modules {
+		if ok, _ := workspace.MatchGlob(pattern, string(moduleName)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the filter includes the release by name; NewerThan is checked separately, since it needs the release itself.
+func (f WareFilter) includesRelease(releaseName wfapi.ReleaseName) bool {
+	if len(f.Releases) == 0 {
+		return true
+	}
+	for _, r := range f.Releases {
+		if r == releaseName {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the filter includes the item.
+func (f WareFilter) includesItem(itemLabel wfapi.ItemLabel) bool {
+	if len(f.Items) == 0 {
+		return true
+	}
+	for _, i := range f.Items {
+		if i == itemLabel {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultPushOptions are the PushOptions used by the CLI unless told otherwise.
+var DefaultPushOptions = PushOptions{
+	Concurrency: 4,
+	Retries:     3,
+	Backoff:     time.Second,
+}
+
+// PushResult is the outcome of pushing one ware.
+type PushResult struct {
+	WareID wfapi.WareID
+	Ref    wfapi.CatalogRef // The first catalog item found with the ware; others may have it too.
+	Status wfapi.MirrorPushStatus
+	Err    error // The last error, when the status is wfapi.MirrorPushStatus_Failed.
+}
+
+// PushReport is the outcome of PushToWarehouseAddr, with a result for each ware, in catalog order.
+type PushReport struct {
+	Results []PushResult
+}
+
+// Count returns how many wares had the given outcome.
+func (r PushReport) Count(status wfapi.MirrorPushStatus) int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
 // PushToWarehouseAddr puts files into a mirror
 //
 // It requires a workspace and catalog to operate on, and the address and configuration
 // for mirroring. The given catalog will be scanned for wares that can be pushed based on
 // the configuration.
 //
+// Wares are pushed concurrently, as configured by the options, and wares which fail to push
+// don't stop the others from being pushed; the report says what happened to each.
+// Wares the mirror already has are skipped, so a push which was interrupted or partly failed
+// can be resumed by simply running it again.
+// Only the wares selected by the options' filter are pushed.
+//
 // Errors:
 //
-// 	- warpforge-error-io -- for IO errors that occur during push operations
+// 	- warpforge-error-invalid-argument -- when the filter is not valid
+// 	- warpforge-error-io -- when the mirror cannot be accessed at all, or the local warehouse cannot be read
+// 	- warpforge-error-connection -- when a remote mirror cannot be reached at all
 //  - warpforge-error-catalog-invalid -- when the provided catalog contains invalid data
 //  - warpforge-error-catalog-missing-entry -- should never occur, as we iterate over the contents of the catalog
 //  - warpforge-error-catalog-parse -- when the provided catalog cannot be parsed
-func PushToWarehouseAddr(ctx context.Context, ws workspace.Workspace, cat workspace.Catalog, pushAddr wfapi.WarehouseAddr, cfg wfapi.WarehouseMirroringConfig) error {
+func PushToWarehouseAddr(ctx context.Context, ws workspace.Workspace, cat workspace.Catalog, pushAddr wfapi.WarehouseAddr, cfg wfapi.WarehouseMirroringConfig, opts PushOptions) (PushReport, error) {
 	log := logging.Ctx(ctx)
 
 	pusher, err := pusherFromConfig(ctx, cfg)
 	if err != nil {
-		return err
+		return PushReport{}, err
 	}
 
+	wares, err := catalogWares(cat, pushAddr, opts.Filter)
+	if err != nil {
+		return PushReport{}, err
+	}
+	var report PushReport
+	for _, ware := range wares {
+		report.Results = append(report.Results, PushResult{WareID: ware.wareId, Ref: ware.ref})
+	}
+
+	// check which wares we have in our local warehouse;
+	// those we don't have are skipped over.
+	var toPush []int
+	for idx := range report.Results {
+		result := &report.Results[idx]
+		warePath, _ := ws.WarePath(result.WareID)
+		_, err := os.Stat(warePath)
+		if os.IsNotExist(err) {
+			log.Debug("mirror", "no local copy of wareId %q (expected at %q), skipping", result.WareID.String(), warePath)
+			result.Status = wfapi.MirrorPushStatus_MissingLocally
+			continue
+		} else if err != nil {
+			return PushReport{}, serum.Errorf(wfapi.ECodeIo, "failed to stat %q: %s", warePath, err)
+		}
+		toPush = append(toPush, idx)
+	}
+
+	// we have wares to push! each worker takes the next one until they're all done.
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range next {
+				result := &report.Results[idx]
+				warePath, _ := ws.WarePath(result.WareID)
+				result.Status, result.Err = pushWithRetries(ctx, pusher, result.WareID, warePath, opts)
+				switch result.Status {
+				case wfapi.MirrorPushStatus_Pushed:
+					log.Info("mirror", "pushed ware: wareId = %s, warePath = %s, pushAddr = %s", result.WareID.String(), warePath, pushAddr)
+				case wfapi.MirrorPushStatus_AlreadyPresent:
+					log.Debug("mirror", "mirror already has wareId %q, skipping", result.WareID.String())
+				case wfapi.MirrorPushStatus_Failed:
+					log.Info("mirror", "failed to push wareId %q: %s", result.WareID.String(), result.Err)
+				}
+			}
+		}()
+	}
+	for _, idx := range toPush {
+		next <- idx
+	}
+	close(next)
+	wg.Wait()
+	return report, nil
+}
+
+// catalogWare is a ware of a catalog, with the first catalog item found with it.
+type catalogWare struct {
+	wareId wfapi.WareID
+	ref    wfapi.CatalogRef
+}
+
+// Lists the wares of a catalog whose mirror is the given address, and which the filter selects, once each, in catalog order.
+//
+// Errors:
+//
+// 	- warpforge-error-invalid-argument -- when the filter is not valid
+// 	- warpforge-error-io -- when the catalog cannot be read
+// 	- warpforge-error-catalog-invalid -- when the provided catalog contains invalid data
+// 	- warpforge-error-catalog-missing-entry -- should never occur, as we iterate over the contents of the catalog
+// 	- warpforge-error-catalog-parse -- when the provided catalog cannot be parsed
+func catalogWares(cat workspace.Catalog, addr wfapi.WarehouseAddr, filter WareFilter) ([]catalogWare, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	var wares []catalogWare
+	seen := map[wfapi.WareID]bool{}
 	for _, m := range cat.Modules() {
+		if !filter.includesModule(m) {
+			continue
+		}
 		ref := wfapi.CatalogRef{ModuleName: m}
 		module, err := cat.GetModule(ref)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		// the release to compare against may have an explicit version, so it's read too, if the module has it
+		var oldest *wfapi.CatalogRelease
+		if filter.NewerThan != "" {
+			oldest, err = cat.GetRelease(wfapi.CatalogRef{ModuleName: m, ReleaseName: filter.NewerThan})
+			if err != nil {
+				return nil, err
+			}
+			if oldest == nil {
+				oldest = &wfapi.CatalogRelease{ReleaseName: filter.NewerThan}
+			}
 		}
 
 		for _, r := range module.Releases.Keys {
+			if !filter.includesRelease(r) {
+				continue
+			}
 			ref.ReleaseName = r
 			rel, err := cat.GetRelease(ref)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if oldest != nil && workspace.CompareReleases(rel, oldest) <= 0 {
+				continue
 			}
 			for _, i := range rel.Items.Keys {
+				if !filter.includesItem(i) {
+					continue
+				}
 				ref.ItemName = i
 				wareId, warehouseAddr, err := cat.GetWare(ref)
 				if err != nil {
-					return err
+					return nil, err
 				}
-				if warehouseAddr == nil || *warehouseAddr != pushAddr {
-					// this ware's WarehouseAddr does not match the one we're pushing to,
+				if warehouseAddr == nil || *warehouseAddr != addr {
+					// this ware's WarehouseAddr does not match the one we're looking for,
 					// ignore this ware
 					continue
 				}
-
-				// get the path to the data we want to push
-				warePath, _ := ws.WarePath(*wareId)
-
-				{
-					// check if we have the ware in our local warehouse
-					// if we do not, we will just skip over it.
-					_, err := os.Stat(warePath)
-					if os.IsNotExist(err) {
-						log.Debug("mirror", "no local copy of wareId %q (expected at %q), skipping", wareId.String(), warePath)
-						continue
-					} else if err != nil {
-						return serum.Errorf(wfapi.ECodeIo, "failed to stat %q: %s", warePath, err)
-					}
-				}
-
-				// we have a ware to push!
-
-				hasWare, err := pusher.hasWare(*wareId)
-				if err != nil {
-					return err
-				}
-				if !hasWare {
-					log.Info("mirror", "pushing ware: wareId = %s, warePath = %s, pushAddr = %s", wareId.String(), warePath, pushAddr)
-					err := pusher.pushWare(*wareId, warePath)
-					if err != nil {
-						return err
-					}
-				} else {
-					log.Debug("mirror", "bucket already has wareId %q, skipping", wareId.String())
+				if seen[*wareId] {
+					continue
 				}
+				seen[*wareId] = true
+				wares = append(wares, catalogWare{wareId: *wareId, ref: ref})
 			}
 		}
 	}
-	return nil
+	return wares, nil
+}
+
+// pushWithRetries pushes a ware unless the mirror already has it,
+// retrying after transient failures (those with the connection error code) as the options allow.
+// Gives up early if the context is cancelled.
+func pushWithRetries(ctx context.Context, pusher pusher, wareId wfapi.WareID, warePath string, opts PushOptions) (wfapi.MirrorPushStatus, error) {
+	backoff := opts.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return wfapi.MirrorPushStatus_Failed, err
+		}
+		status, err := pushOnce(pusher, wareId, warePath)
+		if err == nil || serum.Code(err) != wfapi.ECodeConnection || attempt >= opts.Retries {
+			return status, err
+		}
+		select {
+		case <-ctx.Done():
+			return wfapi.MirrorPushStatus_Failed, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Errors:
+//
+// 	- warpforge-error-io -- for IO errors that occur during push operations, or when a remote mirror refuses a request
+// 	- warpforge-error-connection -- when a remote mirror cannot be reached, or fails in a way that may be transient
+func pushOnce(pusher pusher, wareId wfapi.WareID, warePath string) (wfapi.MirrorPushStatus, error) {
+	hasWare, err := pusher.hasWare(wareId)
+	if err != nil {
+		return wfapi.MirrorPushStatus_Failed, err
+	}
+	if hasWare {
+		return wfapi.MirrorPushStatus_AlreadyPresent, nil
+	}
+	if err := pusher.pushWare(wareId, warePath); err != nil {
+		return wfapi.MirrorPushStatus_Failed, err
+	}
+	return wfapi.MirrorPushStatus_Pushed, nil
 }
@@ -0,0 +1,213 @@
+package mirroring
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/dab"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// constructs a root workspace in a temporary directory, returning it and its path
+func newTestRootWorkspace(t *testing.T) (*workspace.Workspace, string) {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, ".warpforge"), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, ".warpforge", "root"), nil, 0644), qt.IsNil)
+	root, err := workspace.OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	return root, rootPath
+}
+
+func TestPusherFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	mirrorPath := filepath.Join(dir, "mirror")
+	qt.Assert(t, os.Mkdir(mirrorPath, 0755), qt.IsNil)
+	configPath := filepath.Join(dir, "mirroring.json")
+	qt.Assert(t, os.WriteFile(configPath, []byte(`{
+		"mirroring.v1": {
+			"ca+file://`+mirrorPath+`": {
+				"pushConfig": {"fs": {"path": "`+mirrorPath+`"}}
+			},
+			"ca+https://wares.example.com": {
+				"pushConfig": {"http": {"endpoint": "https://wares.example.com", "headers": {"Authorization": "Bearer secret"}}}
+			}
+		}
+	}`), 0644), qt.IsNil)
+
+	configs, err := dab.MirroringConfigFromFile(os.DirFS("/"), configPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, configs.Keys, qt.HasLen, 2)
+
+	p, err := pusherFromConfig(context.Background(), configs.Values[configs.Keys[0]])
+	qt.Assert(t, err, qt.IsNil)
+	_, ok := p.(*FsPusher)
+	qt.Check(t, ok, qt.IsTrue)
+
+	cfg := configs.Values[configs.Keys[1]]
+	qt.Check(t, cfg.PushConfig.Http.Headers.Values["Authorization"], qt.Equals, "Bearer secret")
+	p, err = pusherFromConfig(context.Background(), cfg)
+	qt.Assert(t, err, qt.IsNil)
+	_, ok = p.(*HttpPusher)
+	qt.Check(t, ok, qt.IsTrue)
+}
+
+func TestPushToWarehouseAddr(t *testing.T) {
+	ws, _ := newTestRootWorkspace(t)
+	cat, err := ws.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+
+	const pushAddr = wfapi.WarehouseAddr("ca+https://wares.example.com")
+	addWare := func(module wfapi.ModuleName, hash string, local bool) wfapi.WareID {
+		wareId := wfapi.WareID{Packtype: "tar", Hash: hash}
+		ref := wfapi.CatalogRef{ModuleName: module, ReleaseName: "v1", ItemName: "src"}
+		qt.Assert(t, cat.AddItem(ref, wareId, false), qt.IsNil)
+		qt.Assert(t, cat.AddByModuleMirror(ref, "tar", pushAddr), qt.IsNil)
+		if local {
+			warePath, err := ws.WarePath(wareId)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, os.MkdirAll(filepath.Dir(warePath), 0755), qt.IsNil)
+			qt.Assert(t, os.WriteFile(warePath, []byte(hash), 0644), qt.IsNil)
+		}
+		return wareId
+	}
+	flaky := addWare("example.com/flaky", "aaaaaaaaaa", true)
+	present := addWare("example.com/present", "bbbbbbbbbb", true)
+	missing := addWare("example.com/missing", "cccccccccc", false)
+	refused := addWare("example.com/refused", "dddddddddd", true)
+	pushed := addWare("example.com/pushed", "eeeeeeeeee", true)
+
+	// the server has one ware already; fails the first upload of another as if overloaded;
+	// and refuses another outright.
+	cas := &casServer{objects: map[string][]byte{"/" + present.Subpath(): []byte("bbbbbbbbbb")}}
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			mu.Lock()
+			attempts[r.URL.Path]++
+			n := attempts[r.URL.Path]
+			mu.Unlock()
+			switch {
+			case r.URL.Path == "/"+flaky.Subpath() && n == 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			case r.URL.Path == "/"+refused.Subpath():
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		cas.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	// the catalog's module list is read when it's opened
+	cat, err = ws.OpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	var cfg wfapi.WarehouseMirroringConfig
+	cfg.PushConfig.Http = &wfapi.HttpPushConfig{Endpoint: server.URL}
+	cfg.PushConfig.Http.Headers = &struct {
+		Keys   []string
+		Values map[string]string
+	}{
+		Keys:   []string{"Authorization"},
+		Values: map[string]string{"Authorization": "Bearer secret"},
+	}
+	opts := PushOptions{Concurrency: 2, Retries: 2, Backoff: time.Millisecond}
+	report, err := PushToWarehouseAddr(context.Background(), *ws, cat, pushAddr, cfg, opts)
+	qt.Assert(t, err, qt.IsNil)
+
+	statuses := map[wfapi.WareID]wfapi.MirrorPushStatus{}
+	for _, result := range report.Results {
+		statuses[result.WareID] = result.Status
+	}
+	qt.Check(t, statuses, qt.DeepEquals, map[wfapi.WareID]wfapi.MirrorPushStatus{
+		flaky:   wfapi.MirrorPushStatus_Pushed,
+		present: wfapi.MirrorPushStatus_AlreadyPresent,
+		missing: wfapi.MirrorPushStatus_MissingLocally,
+		refused: wfapi.MirrorPushStatus_Failed,
+		pushed:  wfapi.MirrorPushStatus_Pushed,
+	})
+	qt.Check(t, report.Count(wfapi.MirrorPushStatus_Pushed), qt.Equals, 2)
+	qt.Check(t, string(cas.objects["/"+flaky.Subpath()]), qt.Equals, "aaaaaaaaaa")
+	// refusals aren't transient, so aren't retried
+	qt.Check(t, attempts["/"+refused.Subpath()], qt.Equals, 1)
+	qt.Check(t, attempts["/"+flaky.Subpath()], qt.Equals, 2)
+
+	// running again only has the refused ware left to do
+	report, err = PushToWarehouseAddr(context.Background(), *ws, cat, pushAddr, cfg, opts)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, report.Count(wfapi.MirrorPushStatus_AlreadyPresent), qt.Equals, 3)
+	qt.Check(t, report.Count(wfapi.MirrorPushStatus_Failed), qt.Equals, 1)
+}
+
+func TestCatalogWaresFilter(t *testing.T) {
+	ws, _ := newTestRootWorkspace(t)
+	cat, err := ws.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+
+	const pushAddr = wfapi.WarehouseAddr("ca+https://wares.example.com")
+	count := 0
+	addWare := func(module wfapi.ModuleName, release wfapi.ReleaseName, item wfapi.ItemLabel) {
+		ref := wfapi.CatalogRef{ModuleName: module, ReleaseName: release, ItemName: item}
+		count++
+		wareId := wfapi.WareID{Packtype: "tar", Hash: fmt.Sprintf("%010d", count)}
+		qt.Assert(t, cat.AddItem(ref, wareId, false), qt.IsNil)
+		qt.Assert(t, cat.AddByModuleMirror(ref, "tar", pushAddr), qt.IsNil)
+	}
+	addWare("example.com/a", "v1.0.0", "src")
+	addWare("example.com/a", "v1.1.0", "src")
+	addWare("example.com/a", "v1.1.0", "bin")
+	addWare("example.com/a", "nightly", "src")
+	addWare("example.com/b", "v1.0.0", "src")
+	addWare("other.org/c", "v2.0.0", "src")
+	// the nightly release is ordered by its version rather than its name
+	qt.Assert(t, cat.SetReleaseMetadata(wfapi.CatalogRef{ModuleName: "example.com/a", ReleaseName: "nightly"}, wfapi.ReleaseMetadataVersion, "v9.0.0"), qt.IsNil)
+	cat, err = ws.OpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+
+	items := func(filter WareFilter) []string {
+		wares, err := catalogWares(cat, pushAddr, filter)
+		qt.Assert(t, err, qt.IsNil)
+		result := []string{}
+		for _, ware := range wares {
+			result = append(result, ware.ref.String())
+		}
+		sort.Strings(result)
+		return result
+	}
+	qt.Check(t, items(WareFilter{}), qt.HasLen, 6)
+	qt.Check(t, items(WareFilter{Modules: []string{"example.com/*"}}), qt.HasLen, 5)
+	qt.Check(t, items(WareFilter{Modules: []string{"*c"}}), qt.DeepEquals, []string{
+		"catalog:other.org/c:v2.0.0:src",
+	})
+	qt.Check(t, items(WareFilter{Modules: []string{"example.com/b", "other.org/*"}}), qt.DeepEquals, []string{
+		"catalog:example.com/b:v1.0.0:src",
+		"catalog:other.org/c:v2.0.0:src",
+	})
+	qt.Check(t, items(WareFilter{Releases: []wfapi.ReleaseName{"v1.1.0"}, Items: []wfapi.ItemLabel{"bin"}}), qt.DeepEquals, []string{
+		"catalog:example.com/a:v1.1.0:bin",
+	})
+	qt.Check(t, items(WareFilter{NewerThan: "v1.0.0"}), qt.DeepEquals, []string{
+		"catalog:example.com/a:nightly:src",
+		"catalog:example.com/a:v1.1.0:bin",
+		"catalog:example.com/a:v1.1.0:src",
+		"catalog:other.org/c:v2.0.0:src",
+	})
+	// the release compared against is looked up, so its version is used
+	qt.Check(t, items(WareFilter{Modules: []string{"example.com/a"}, NewerThan: "nightly"}), qt.HasLen, 0)
+
+	_, err = catalogWares(cat, pushAddr, WareFilter{Modules: []string{"example.com/["}})
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+}
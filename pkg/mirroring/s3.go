@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -48,21 +49,24 @@ func newS3Pusher(ctx context.Context, cfg wfapi.S3PushConfig) (S3Pusher, error)
 		return S3Pusher{}, serum.Errorf(wfapi.ECodeIo, "could not access bucket %q: %s", cfg.Bucket, err)
 	}
 
-	// list all the objects currently in the bucket
-	result, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+	// list all the objects currently in the bucket, a page (of up to 1000 keys) at a time,
+	// and store the existing keys so we can ignore writes for existing WareIDs
+	existingKeys := make(map[string]bool)
+	pages := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(cfg.Bucket),
 	})
-	if err != nil {
-		return S3Pusher{}, serum.Errorf(wfapi.ECodeIo, "could not list contents of bucket %q: %s", cfg.Bucket, err)
-	}
-
-	// store the list of existing keys so we can ignore writes for existing WareIDs
-	existingKeys := make(map[string]bool)
-	for _, object := range result.Contents {
-		existingKeys[*object.Key] = true
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return S3Pusher{}, serum.Errorf(wfapi.ECodeIo, "could not list contents of bucket %q: %s", cfg.Bucket, err)
+		}
+		for _, object := range page.Contents {
+			existingKeys[*object.Key] = true
+		}
 	}
 
 	return S3Pusher{
+		ctx:          ctx,
 		client:       client,
 		cfg:          cfg,
 		existingKeys: existingKeys,
@@ -85,6 +89,7 @@ func (p *S3Pusher) pushWare(wareId wfapi.WareID, localPath string) error {
 	if err != nil {
 		return serum.Errorf(wfapi.ECodeIo, "failed to open %q: %s", localPath, err)
 	}
+	defer file.Close()
 
 	uploader := manager.NewUploader(p.client)
 
@@ -95,8 +100,18 @@ func (p *S3Pusher) pushWare(wareId wfapi.WareID, localPath string) error {
 	})
 
 	if err != nil {
-		return serum.Errorf(wfapi.ECodeIo, "failed to write to S3 bucket %q: %s", p.cfg.Bucket, err)
+		return serum.Errorf(s3ErrorCode(err), "failed to write to S3 bucket %q: %s", p.cfg.Bucket, err)
 	}
 
 	return nil
 }
+
+// s3ErrorCode classifies an error from the S3 client.
+// Those the SDK considers retryable, such as throttling, server errors, and dropped connections,
+// are connection errors, so that pushing is retried after them; the rest are IO errors.
+func s3ErrorCode(err error) string {
+	if retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary {
+		return wfapi.ECodeConnection
+	}
+	return wfapi.ECodeIo
+}
@@ -0,0 +1,181 @@
+package mirroring
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// s3Server is a minimal S3 bucket, which fails the first PUTs of each object with the given status,
+// before it accepts them.
+type s3Server struct {
+	mu       sync.Mutex
+	bucket   string
+	failures int    // How many PUTs of each object fail.
+	status   int    // The status those PUTs fail with.
+	code     string // The S3 error code those PUTs fail with.
+	pageSize int    // How many keys are listed at a time; 1000 (as by S3) if zero.
+	puts     map[string]int
+	objects  map[string][]byte
+}
+
+// list writes a page of a ListObjectsV2 result, starting after the key given as continuation token.
+func (s *s3Server) list(w http.ResponseWriter, r *http.Request) {
+	pageSize := s.pageSize
+	if pageSize == 0 {
+		pageSize = 1000
+	}
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		start = sort.SearchStrings(keys, token) + 1
+	}
+	page := keys[start:]
+	truncated := len(page) > pageSize
+	if truncated {
+		page = page[:pageSize]
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>%s</Name><KeyCount>%d</KeyCount><IsTruncated>%t</IsTruncated>`, s.bucket, len(page), truncated)
+	if truncated {
+		fmt.Fprintf(w, `<NextContinuationToken>%s</NextContinuationToken>`, page[len(page)-1])
+	}
+	for _, key := range page {
+		fmt.Fprintf(w, `<Contents><Key>%s</Key></Contents>`, key)
+	}
+	io.WriteString(w, `</ListBucketResult>`)
+}
+
+func (s *s3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/"+s.bucket)
+	switch {
+	case r.Method == http.MethodHead && key == "":
+	case r.Method == http.MethodGet && key == "":
+		s.list(w, r)
+	case r.Method == http.MethodPut && key != "":
+		key = strings.TrimPrefix(key, "/")
+		s.puts[key]++
+		if s.puts[key] <= s.failures {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(s.status)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>`+s.code+`</Code><Message>try again</Message></Error>`)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = body
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestS3PusherRetries(t *testing.T) {
+	// keep the SDK from looking for real credentials, or retrying by itself
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_MAX_ATTEMPTS", "1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	localPath := filepath.Join(t.TempDir(), "ware")
+	qt.Assert(t, os.WriteFile(localPath, []byte("ware contents"), 0600), qt.IsNil)
+	wareId := wfapi.WareID{Packtype: "tar", Hash: "abcdefghijklmnop"}
+	opts := PushOptions{Retries: 2, Backoff: time.Millisecond}
+
+	for _, tc := range []struct {
+		name     string
+		status   int
+		code     string
+		failures int
+		expected wfapi.MirrorPushStatus
+		errCode  string
+		puts     int
+	}{
+		{"throttled", http.StatusServiceUnavailable, "SlowDown", 2, wfapi.MirrorPushStatus_Pushed, "", 3},
+		{"server-error", http.StatusInternalServerError, "InternalError", 1, wfapi.MirrorPushStatus_Pushed, "", 2},
+		{"gives-up", http.StatusServiceUnavailable, "SlowDown", 3, wfapi.MirrorPushStatus_Failed, wfapi.ECodeConnection, 3},
+		{"refused", http.StatusForbidden, "AccessDenied", 1, wfapi.MirrorPushStatus_Failed, wfapi.ECodeIo, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s3 := &s3Server{
+				bucket:   "wares",
+				failures: tc.failures,
+				status:   tc.status,
+				code:     tc.code,
+				puts:     map[string]int{},
+				objects:  map[string][]byte{},
+			}
+			server := httptest.NewServer(s3)
+			defer server.Close()
+
+			pusher, err := newS3Pusher(context.Background(), wfapi.S3PushConfig{Endpoint: server.URL, Region: "us-east-1", Bucket: "wares"})
+			qt.Assert(t, err, qt.IsNil)
+			status, err := pushWithRetries(context.Background(), &pusher, wareId, localPath, opts)
+			qt.Check(t, status, qt.Equals, tc.expected)
+			if tc.errCode == "" {
+				qt.Assert(t, err, qt.IsNil)
+				qt.Check(t, string(s3.objects[wareId.Subpath()]), qt.Equals, "ware contents")
+			} else {
+				qt.Check(t, serum.Code(err), qt.Equals, tc.errCode)
+			}
+			qt.Check(t, s3.puts[wareId.Subpath()], qt.Equals, tc.puts)
+		})
+	}
+}
+
+func TestS3PusherListsAllPages(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	// more wares than fit in one page of the listing
+	var wareIds []wfapi.WareID
+	objects := map[string][]byte{}
+	for _, hash := range []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc", "dddddddddd", "eeeeeeeeee"} {
+		wareId := wfapi.WareID{Packtype: "tar", Hash: hash}
+		wareIds = append(wareIds, wareId)
+		objects[wareId.Subpath()] = []byte(hash)
+	}
+	server := httptest.NewServer(&s3Server{bucket: "wares", pageSize: 2, puts: map[string]int{}, objects: objects})
+	defer server.Close()
+
+	pusher, err := newS3Pusher(context.Background(), wfapi.S3PushConfig{Endpoint: server.URL, Region: "us-east-1", Bucket: "wares"})
+	qt.Assert(t, err, qt.IsNil)
+	for _, wareId := range wareIds {
+		has, err := pusher.hasWare(wareId)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, has, qt.IsTrue, qt.Commentf("%s", wareId))
+	}
+	has, err := pusher.hasWare(wfapi.WareID{Packtype: "tar", Hash: "ffffffffff"})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, has, qt.IsFalse)
+}
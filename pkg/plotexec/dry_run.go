@@ -0,0 +1,481 @@
+package plotexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/formulaexec"
+	"github.com/warptools/warpforge/pkg/logging"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// plannedInput is the result of resolving a PlotInput without executing anything.
+//
+// If input is nil, the input can't be known until an upstream step is executed.
+// If blocked has any entries, the input can't be resolved at all.
+type plannedInput struct {
+	input   *wfapi.FormulaInput
+	addr    *wfapi.WarehouseAddr
+	ref     *wfapi.CatalogRef // the catalog item the input was resolved from, if any
+	blocked []string
+	notes   []string
+}
+
+type planPipeMap map[wfapi.StepName]map[wfapi.LocalLabel]plannedInput
+
+// planner accumulates a PlotPlan while walking a plot.
+type planner struct {
+	cfg    ExecConfig
+	wss    workspace.WorkspaceSet
+	pltCfg wfapi.PlotExecConfig
+	plan   wfapi.PlotPlan
+	wares  []PlannedWare
+	seen   map[wfapi.WareID]bool
+}
+
+// PlannedWare is a ware which executing a plot will unpack.
+type PlannedWare struct {
+	WareID    wfapi.WareID
+	Ref       *wfapi.CatalogRef // The catalog item the ware was resolved from, if any, with its release name resolved.
+	Available bool              // True if the ware is already in the warehouse or cache, so won't need fetching.
+}
+
+// Records the wares a step will unpack.
+func (p *planner) addWares(inputs []plannedInput) {
+	for _, planned := range inputs {
+		if planned.input == nil || planned.input.Basis().WareID == nil {
+			continue
+		}
+		wareId := *planned.input.Basis().WareID
+		if p.seen[wareId] {
+			continue
+		}
+		p.seen[wareId] = true
+		p.wares = append(p.wares, PlannedWare{
+			WareID:    wareId,
+			Ref:       planned.ref,
+			Available: wareAvailable(p.cfg, p.wss.Root(), wareId),
+		})
+	}
+}
+
+// Returns true if the ware can be used without fetching it from a remote warehouse.
+func wareAvailable(cfg ExecConfig, root *workspace.Workspace, wareId wfapi.WareID) bool {
+	var paths []string
+	if path, err := root.CachePath(wareId); err == nil {
+		paths = append(paths, path)
+	}
+	if path, err := root.WarePath(wareId); err == nil {
+		paths = append(paths, path)
+	}
+	execCfg := formulaexec.ExecConfig(cfg)
+	if override, ok := execCfg.WarehousePathOverride(); ok {
+		paths = append(paths, filepath.Join(override, wareId.Subpath()))
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolves a PlotInput as far as is possible without executing anything.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the provided plot input is invalid
+//    - warpforge-error-io -- when an IO error occurs during conversion
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+func (p *planner) planInput(ctx context.Context, plotInput wfapi.PlotInput, pipeCtx planPipeMap) (plannedInput, error) {
+	var basis wfapi.PlotInputSimple
+	switch {
+	case plotInput.PlotInputSimple != nil:
+		basis = *plotInput.PlotInputSimple
+	case plotInput.PlotInputComplex != nil:
+		basis = plotInput.PlotInputComplex.Basis
+	default:
+		return plannedInput{}, wfapi.ErrorPlotInvalid("plot contains input that is neither PlotInputSimple or PlotInputComplex")
+	}
+
+	result, err := p.planInputSimple(ctx, basis, pipeCtx)
+	if err != nil || result.input == nil {
+		return result, err
+	}
+
+	if plotInput.PlotInputComplex != nil {
+		result.input = &wfapi.FormulaInput{
+			FormulaInputComplex: &wfapi.FormulaInputComplex{
+				Basis:   *result.input.Basis(),
+				Filters: plotInput.PlotInputComplex.Filters,
+			},
+		}
+	}
+	return result, nil
+}
+
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the provided plot input is invalid
+//    - warpforge-error-io -- when an IO error occurs during conversion
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-release-constraint -- when a catalog reference has an invalid version constraint
+func (p *planner) planInputSimple(ctx context.Context, basis wfapi.PlotInputSimple, pipeCtx planPipeMap) (plannedInput, error) {
+	simple := func(input wfapi.FormulaInputSimple) *wfapi.FormulaInput {
+		return &wfapi.FormulaInput{FormulaInputSimple: &input}
+	}
+
+	switch {
+	case basis.WareID != nil:
+		result := plannedInput{input: simple(wfapi.FormulaInputSimple{WareID: basis.WareID})}
+		if !wareAvailable(p.cfg, p.wss.Root(), *basis.WareID) {
+			result.notes = append(result.notes, fmt.Sprintf("ware %q will be fetched", basis.WareID.String()))
+		}
+		return result, nil
+
+	case basis.Mount != nil:
+		return plannedInput{input: simple(wfapi.FormulaInputSimple{Mount: basis.Mount})}, nil
+
+	case basis.Literal != nil:
+		return plannedInput{input: simple(wfapi.FormulaInputSimple{Literal: basis.Literal})}, nil
+
+	case basis.CatalogRef != nil:
+		ref := *basis.CatalogRef
+		resolved, err := resolveConstraint(p.wss, p.pltCfg, ref)
+		if serum.Code(err) == wfapi.ECodeCatalogMissingEntry {
+			return plannedInput{blocked: []string{fmt.Sprintf("no release matches catalog entry %q", ref.String())}}, nil
+		}
+		if err != nil {
+			return plannedInput{}, err
+		}
+		wareId, wareAddr, err := resolveCatalogRef(p.wss, p.pltCfg, ref)
+		if err != nil {
+			return plannedInput{}, err
+		}
+		if wareId == nil {
+			return plannedInput{blocked: []string{fmt.Sprintf("catalog entry %q not found", ref.String())}}, nil
+		}
+		result := plannedInput{
+			input: simple(wfapi.FormulaInputSimple{WareID: wareId}),
+			addr:  wareAddr,
+			ref:   &resolved,
+		}
+		if resolved != ref {
+			result.notes = append(result.notes, fmt.Sprintf("%q resolves to release %q", ref.String(), resolved.ReleaseName))
+		}
+		release, err := p.wss.GetCatalogRelease(resolved)
+		if err != nil {
+			return plannedInput{}, err
+		}
+		if release != nil && release.Status != nil {
+			result.notes = append(result.notes, release.Status.Message(resolved.ModuleName, resolved.ReleaseName))
+		}
+		if wareAddr != nil || wareAvailable(p.cfg, p.wss.Root(), *wareId) {
+			return result, nil
+		}
+		replay, err := p.wss.GetCatalogReplay(resolved)
+		if err != nil {
+			return plannedInput{}, err
+		}
+		switch {
+		case replay == nil:
+			result.blocked = append(result.blocked, fmt.Sprintf("ware %q for %q is not available and has no mirror or replay", wareId.String(), ref.String()))
+		case !p.pltCfg.Recursive:
+			result.blocked = append(result.blocked, fmt.Sprintf("ware %q for %q requires a replay, but recursion is disabled", wareId.String(), ref.String()))
+		default:
+			result.notes = append(result.notes, fmt.Sprintf("replay for %q will be executed", ref.String()))
+		}
+		return result, nil
+
+	case basis.Pipe != nil:
+		step, ok := pipeCtx[basis.Pipe.StepName]
+		if !ok {
+			return plannedInput{}, wfapi.ErrorPlotInvalid(fmt.Sprintf("step %s was expected, but missing from plot", basis.Pipe.StepName))
+		}
+		upstream, ok := step[basis.Pipe.Label]
+		if !ok {
+			if basis.Pipe.StepName == "" {
+				return plannedInput{}, wfapi.ErrorPlotInvalid(fmt.Sprintf("no label '%s' in plot inputs ('pipe::%s' not defined)", basis.Pipe.Label, basis.Pipe.Label))
+			}
+			// the upstream step will be executed, its outputs aren't known yet
+			return plannedInput{notes: []string{fmt.Sprintf("waiting on output %q of step %q", basis.Pipe.Label, basis.Pipe.StepName)}}, nil
+		}
+		return upstream, nil
+
+	case basis.Ingest != nil && basis.Ingest.GitIngest != nil:
+		path, errRaw := filepath.Abs(basis.Ingest.GitIngest.HostPath)
+		if errRaw != nil {
+			return plannedInput{}, wfapi.ErrorIo("failed to convert git host path to absolute path", basis.Ingest.GitIngest.HostPath, errRaw)
+		}
+		wareId, err := resolveGitIngest(ctx, path, basis.Ingest.GitIngest.Ref)
+		if err != nil {
+			return plannedInput{blocked: []string{fmt.Sprintf("git ingest of %q could not be resolved: %s", path, err)}}, nil
+		}
+		return plannedInput{input: simple(wfapi.FormulaInputSimple{WareID: &wareId})}, nil
+
+	case basis.Ingest != nil && basis.Ingest.HostDirIngest != nil:
+		// the WareID isn't known until the directory is packed, which is not done in a dry run
+		path, err := ingestHostPath(p.cfg, basis.Ingest.HostDirIngest.HostPath())
+		if err != nil {
+			return plannedInput{}, err
+		}
+		if info, errRaw := os.Stat(path); errRaw != nil || !info.IsDir() {
+			return plannedInput{blocked: []string{fmt.Sprintf("host directory ingest of %q is not a readable directory", path)}}, nil
+		}
+		return plannedInput{notes: []string{fmt.Sprintf("host directory %q will be packed", path)}}, nil
+
+	case basis.Ingest != nil && basis.Ingest.HttpIngest != nil:
+		digest, err := parseIngestHash(basis.Ingest.HttpIngest.Hash())
+		if err != nil {
+			return plannedInput{blocked: []string{err.Error()}}, nil
+		}
+		if wareId := lookupHttpIngest(p.cfg, p.wss.Root(), digest); wareId != nil {
+			return plannedInput{input: simple(wfapi.FormulaInputSimple{WareID: wareId})}, nil
+		}
+		return plannedInput{notes: []string{fmt.Sprintf("%q will be downloaded", basis.Ingest.HttpIngest.Url())}}, nil
+	}
+	return plannedInput{}, wfapi.ErrorPlotInvalid("invalid type in plot input")
+}
+
+// Plans a single protoformula step, returning the outputs that are known ahead of execution.
+// Outputs are only known if the step is memoized.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the provided plot input is invalid
+//    - warpforge-error-io -- when an IO error occurs
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-serialization -- when a memo cannot be parsed
+func (p *planner) planProtoformula(ctx context.Context, name string, pf wfapi.Protoformula, pipeCtx planPipeMap) (map[wfapi.LocalLabel]plannedInput, error) {
+	stepPlan := wfapi.StepPlan{
+		Step:   name,
+		Status: wfapi.StepPlanStatus_Execute,
+	}
+	formula := wfapi.Formula{
+		Action: pf.Action,
+	}
+	formula.Inputs.Values = make(map[wfapi.SandboxPort]wfapi.FormulaInput)
+	formula.Outputs.Values = make(map[wfapi.OutputName]wfapi.GatherDirective)
+
+	complete := true
+	var inputs []plannedInput
+	for _, sbPort := range pf.Inputs.Keys {
+		planned, err := p.planInput(ctx, pf.Inputs.Values[sbPort], pipeCtx)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, planned)
+		stepPlan.Reasons = append(stepPlan.Reasons, planned.notes...)
+		stepPlan.Reasons = append(stepPlan.Reasons, planned.blocked...)
+		if len(planned.blocked) > 0 {
+			stepPlan.Status = wfapi.StepPlanStatus_Blocked
+		}
+		if planned.input == nil {
+			complete = false
+			continue
+		}
+		formula.Inputs.Keys = append(formula.Inputs.Keys, sbPort)
+		formula.Inputs.Values[sbPort] = *planned.input
+	}
+	for _, label := range pf.Outputs.Keys {
+		outName := wfapi.OutputName(label)
+		formula.Outputs.Keys = append(formula.Outputs.Keys, outName)
+		formula.Outputs.Values[outName] = pf.Outputs.Values[label]
+	}
+
+	outputs := make(map[wfapi.LocalLabel]plannedInput)
+	if complete {
+		fid := formulaexec.FormulaID(formula)
+		stepPlan.FormulaID = &fid
+		if stepPlan.Status != wfapi.StepPlanStatus_Blocked && !p.pltCfg.FormulaExecConfig.DisableMemoization {
+			memo, err := p.wss.Root().LoadMemo(fid)
+			if err != nil {
+				return nil, err
+			}
+			if memo != nil {
+				stepPlan.Status = wfapi.StepPlanStatus_Memoized
+				for label, result := range memo.Results.Values {
+					outputs[wfapi.LocalLabel(label)] = plannedInput{
+						input: &wfapi.FormulaInput{
+							FormulaInputSimple: &wfapi.FormulaInputSimple{
+								WareID:  result.WareID,
+								Literal: result.Literal,
+								Mount:   result.Mount,
+							},
+						},
+					}
+				}
+			}
+		}
+	}
+	if stepPlan.Status == wfapi.StepPlanStatus_Execute {
+		p.addWares(inputs)
+	}
+	if stepPlan.Status == wfapi.StepPlanStatus_Blocked {
+		// propagate the block to anything downstream of this step
+		for _, label := range pf.Outputs.Keys {
+			outputs[label] = plannedInput{blocked: []string{fmt.Sprintf("upstream step %q is blocked", name)}}
+		}
+	}
+	p.plan.Steps = append(p.plan.Steps, stepPlan)
+	return outputs, nil
+}
+
+// Plans a plot, recursing into subplots, and returns the plot's outputs that are known ahead of execution.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the plot contains invalid data
+//    - warpforge-error-io -- when an IO error occurs
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-serialization -- when a memo cannot be parsed
+func (p *planner) planPlot(ctx context.Context, prefix string, plot wfapi.Plot) (map[wfapi.LocalLabel]plannedInput, error) {
+	pipeCtx := make(planPipeMap)
+	pipeCtx[""] = make(map[wfapi.LocalLabel]plannedInput)
+	for _, name := range plot.Inputs.Keys {
+		planned, err := p.planInput(ctx, plot.Inputs.Values[name], pipeCtx)
+		if err != nil {
+			return nil, err
+		}
+		pipeCtx[""][name] = planned
+	}
+
+	stepsOrdered, err := OrderSteps(ctx, plot)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range stepsOrdered {
+		step := plot.Steps.Values[name]
+		var outputs map[wfapi.LocalLabel]plannedInput
+		switch {
+		case step.Protoformula != nil:
+			outputs, err = p.planProtoformula(ctx, prefix+string(name), *step.Protoformula, pipeCtx)
+		case step.Plot != nil:
+			outputs, err = p.planPlot(ctx, prefix+string(name)+"/", *step.Plot)
+		default:
+			return nil, wfapi.ErrorPlotInvalid(fmt.Sprintf("plot step %q does not contain a Protoformula or Plot", name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		pipeCtx[name] = outputs
+	}
+
+	results := make(map[wfapi.LocalLabel]plannedInput)
+	for name, output := range plot.Outputs.Values {
+		if planned, ok := pipeCtx[output.Pipe.StepName][output.Pipe.Label]; ok {
+			results[name] = planned
+		}
+	}
+	return results, nil
+}
+
+// Plan determines what executing a PlotCapsule would do, without executing anything.
+// Every input is resolved as far as possible, each protoformula's formula ID is computed,
+// and the memo store and warehouse are checked to decide whether each step is memoized,
+// needs to be executed, or is blocked.
+//
+// Git ingests are resolved to a hash, but are not checked out to the cache.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-io -- when an IO error occurs
+//    - warpforge-error-plot-invalid -- when the provided plot is invalid
+//    - warpforge-error-serialization -- when a memo cannot be parsed
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+func Plan(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, plotCapsule wfapi.PlotCapsule, pltCfg wfapi.PlotExecConfig) (result wfapi.PlotPlan, err error) {
+	ctx, span := tracing.StartFn(ctx, "Plan")
+	defer func() { tracing.EndWithStatus(span, err) }()
+	if plotCapsule.Plot == nil {
+		return wfapi.PlotPlan{}, wfapi.ErrorPlotInvalid("PlotCapsule does not contain a v1 plot")
+	}
+	p, err := runPlanner(ctx, cfg, wss, *plotCapsule.Plot, pltCfg)
+	if err != nil {
+		return wfapi.PlotPlan{}, err
+	}
+	return p.plan, nil
+}
+
+// PlanWares determines which wares executing a PlotCapsule would unpack, without executing anything, as by Plan.
+// Only the inputs of steps which would be executed are included,
+// since memoized steps don't need their inputs, and blocked steps can't be executed.
+// Inputs which aren't known until an upstream step is executed aren't included either.
+// Each ware is listed once, in the order the plan first uses it.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-io -- when an IO error occurs
+//    - warpforge-error-plot-invalid -- when the provided plot is invalid
+//    - warpforge-error-serialization -- when a memo cannot be parsed
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+func PlanWares(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, plotCapsule wfapi.PlotCapsule, pltCfg wfapi.PlotExecConfig) (result []PlannedWare, err error) {
+	ctx, span := tracing.StartFn(ctx, "PlanWares")
+	defer func() { tracing.EndWithStatus(span, err) }()
+	if plotCapsule.Plot == nil {
+		return nil, wfapi.ErrorPlotInvalid("PlotCapsule does not contain a v1 plot")
+	}
+	p, err := runPlanner(ctx, cfg, wss, *plotCapsule.Plot, pltCfg)
+	if err != nil {
+		return nil, err
+	}
+	return p.wares, nil
+}
+
+// Errors:
+//
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-io -- when an IO error occurs
+//    - warpforge-error-plot-invalid -- when the provided plot is invalid
+//    - warpforge-error-serialization -- when a memo cannot be parsed
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+func runPlanner(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, plot wfapi.Plot, pltCfg wfapi.PlotExecConfig) (*planner, error) {
+	plot, pltCfg, err := prepareTopLevelPlot(ctx, wss, plot, pltCfg)
+	if err != nil {
+		return nil, err
+	}
+	p := &planner{
+		cfg:    cfg,
+		wss:    wss,
+		pltCfg: pltCfg,
+		seen:   map[wfapi.WareID]bool{},
+	}
+	if _, err := p.planPlot(ctx, "", plot); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DryRun is Plan, additionally printing the resulting plan.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-io -- when an IO error occurs
+//    - warpforge-error-plot-invalid -- when the provided plot is invalid
+//    - warpforge-error-serialization -- when a memo cannot be parsed
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+func DryRun(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, plotCapsule wfapi.PlotCapsule, pltCfg wfapi.PlotExecConfig) (wfapi.PlotPlan, error) {
+	plan, err := Plan(ctx, cfg, wss, plotCapsule, pltCfg)
+	if err != nil {
+		return plan, err
+	}
+	logging.Ctx(ctx).PrintPlotPlan(LOG_TAG, plan)
+	return plan, nil
+}
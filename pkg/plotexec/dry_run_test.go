@@ -0,0 +1,151 @@
+package plotexec
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestDryRun(t *testing.T) {
+	serial := `{
+	"plot.v1": {
+		"inputs": {
+			"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static",
+			"missing": "catalog:example.com/missing:v1.0:x86_64"
+		},
+		"steps": {
+			"one": {
+				"protoformula": {
+					"inputs": {
+						"/": "pipe::rootfs"
+					},
+					"action": {
+						"script": {
+							"interpreter": "/bin/sh",
+							"contents": ["mkdir /out", "echo hello > /out/file"]
+						}
+					},
+					"outputs": {
+						"out": {"from": "/out", "packtype": "tar"}
+					}
+				}
+			},
+			"two": {
+				"protoformula": {
+					"inputs": {
+						"/": "pipe::rootfs",
+						"/in": "pipe:one:out"
+					},
+					"action": {
+						"script": {
+							"interpreter": "/bin/sh",
+							"contents": ["cat /in/file"]
+						}
+					},
+					"outputs": {}
+				}
+			},
+			"three": {
+				"protoformula": {
+					"inputs": {
+						"/": "pipe::missing"
+					},
+					"action": {
+						"script": {
+							"interpreter": "/bin/sh",
+							"contents": ["true"]
+						}
+					},
+					"outputs": {}
+				}
+			}
+		},
+		"outputs": {}
+	}
+}`
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(serial), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+
+	wfCfg, wss := newTestConfig(t)
+	pltCfg := wfapi.PlotExecConfig{
+		FormulaExecConfig: wfapi.FormulaExecConfig{DisableMemoization: true},
+	}
+	plan, err := DryRun(context.Background(), wfCfg, wss, plotCapsule, pltCfg)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, plan.Steps, qt.HasLen, 3)
+
+	steps := make(map[string]wfapi.StepPlan)
+	for _, step := range plan.Steps {
+		steps[step.Step] = step
+	}
+
+	qt.Check(t, steps["one"].Status, qt.Equals, wfapi.StepPlanStatus_Execute)
+	qt.Check(t, steps["one"].FormulaID, qt.IsNotNil)
+
+	qt.Check(t, steps["two"].Status, qt.Equals, wfapi.StepPlanStatus_Execute)
+	qt.Check(t, steps["two"].FormulaID, qt.IsNil)
+
+	qt.Check(t, steps["three"].Status, qt.Equals, wfapi.StepPlanStatus_Blocked)
+	qt.Check(t, steps["three"].Reasons, qt.Not(qt.HasLen), 0)
+
+	// only the steps which would be executed need wares, and each is listed once
+	wares, err := PlanWares(context.Background(), wfCfg, wss, plotCapsule, pltCfg)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, wares, qt.HasLen, 1)
+	qt.Check(t, wares[0].Ref, qt.DeepEquals, &wfapi.CatalogRef{
+		ModuleName:  "warpsys.org/busybox",
+		ReleaseName: "v1.35.0",
+		ItemName:    "amd64-static",
+	})
+	qt.Check(t, wares[0].WareID.Packtype, qt.Equals, wfapi.Packtype("tar"))
+
+	// the plan must serialize as API output
+	_, err = ipld.Marshal(json.Encode, &wfapi.ApiOutput{PlotPlan: &plan}, wfapi.TypeSystem.TypeByName("ApiOutput"))
+	qt.Assert(t, err, qt.IsNil)
+
+	t.Run("memoized", func(t *testing.T) {
+		// memos are looked up in the root workspace, so use one of our own rather than the project's
+		root, _ := newTestRootWorkspace(t)
+		wss := workspace.WorkspaceSet{wss[0], root}
+		pltCfg := wfapi.PlotExecConfig{}
+
+		plan, err := DryRun(context.Background(), wfCfg, wss, plotCapsule, pltCfg)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, plan.Steps[0].Step, qt.Equals, "one")
+		qt.Check(t, plan.Steps[0].Status, qt.Equals, wfapi.StepPlanStatus_Execute)
+		fid := plan.Steps[0].FormulaID
+		qt.Assert(t, fid, qt.IsNotNil)
+
+		out := wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+		rr := wfapi.RunRecord{Guid: "guid", FormulaID: *fid}
+		rr.Results.Keys = []wfapi.OutputName{"out"}
+		rr.Results.Values = map[wfapi.OutputName]wfapi.FormulaInputSimple{"out": {WareID: &out}}
+		qt.Assert(t, root.StoreMemo(rr), qt.IsNil)
+
+		plan, err = DryRun(context.Background(), wfCfg, wss, plotCapsule, pltCfg)
+		qt.Assert(t, err, qt.IsNil)
+		steps := make(map[string]wfapi.StepPlan)
+		for _, step := range plan.Steps {
+			steps[step.Step] = step
+		}
+		qt.Check(t, steps["one"].Status, qt.Equals, wfapi.StepPlanStatus_Memoized)
+		qt.Check(t, steps["one"].FormulaID, qt.DeepEquals, fid)
+		// the memoized output is known, so the step using it can be identified too
+		qt.Check(t, steps["two"].Status, qt.Equals, wfapi.StepPlanStatus_Execute)
+		qt.Check(t, steps["two"].FormulaID, qt.IsNotNil)
+
+		// disabling memoization ignores the memo
+		plan, err = DryRun(context.Background(), wfCfg, wss, plotCapsule, wfapi.PlotExecConfig{
+			FormulaExecConfig: wfapi.FormulaExecConfig{DisableMemoization: true},
+		})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, plan.Steps[0].Status, qt.Equals, wfapi.StepPlanStatus_Execute)
+	})
+}
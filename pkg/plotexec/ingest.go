@@ -0,0 +1,233 @@
+package plotexec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/warptools/warpforge/pkg/formulaexec"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// Resolves the host path of an ingest to an absolute path.
+// Relative paths are relative to the module directory, as with mounts.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the path cannot be made absolute
+func ingestHostPath(cfg ExecConfig, hostPath string) (string, error) {
+	if filepath.IsAbs(hostPath) {
+		return hostPath, nil
+	}
+	path, err := filepath.Abs(filepath.Join(cfg.FormulaDirectory, hostPath))
+	if err != nil {
+		return "", wfapi.ErrorIo("failed to convert ingest host path to absolute path", hostPath, err)
+	}
+	return path, nil
+}
+
+// Packs the directory of a host directory ingest into a ware in the root workspace's warehouse.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when the path is not a readable directory
+//    - warpforge-error-executor-failed -- when rio cannot be run
+//    - warpforge-error-ware-pack -- when packing the directory fails
+func ingestHostDir(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, ingest wfapi.HostDirIngest) (wfapi.WareID, error) {
+	ctx, span := tracing.Start(ctx, "ingestHostDir", trace.WithAttributes(attribute.String(tracing.AttrKeyWarpforgeIngestPath, ingest.HostPath())))
+	defer span.End()
+
+	path, err := ingestHostPath(cfg, ingest.HostPath())
+	if err != nil {
+		return wfapi.WareID{}, err
+	}
+	info, errRaw := os.Stat(path)
+	if errRaw != nil {
+		return wfapi.WareID{}, wfapi.ErrorIo("failed to stat host directory ingest", path, errRaw)
+	}
+	if !info.IsDir() {
+		return wfapi.WareID{}, wfapi.ErrorIo("host directory ingest is not a directory", path, fmt.Errorf("not a directory"))
+	}
+	return formulaexec.PackHostPath(ctx, formulaexec.ExecConfig(cfg), wss.Root(), path)
+}
+
+// Parses the expected hash of an HTTP ingest, returning the hex digest.
+// Only "sha256:<hex>" hashes are supported.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the hash is malformed or uses an unsupported algorithm
+func parseIngestHash(hash string) (string, error) {
+	algo, digest, ok := strings.Cut(hash, ":")
+	if !ok || algo != "sha256" {
+		return "", wfapi.ErrorPlotInvalid(fmt.Sprintf("http ingest hash %q must be of the form \"sha256:<hex>\"", hash))
+	}
+	digest = strings.ToLower(digest)
+	if raw, err := hex.DecodeString(digest); err != nil || len(raw) != sha256.Size {
+		return "", wfapi.ErrorPlotInvalid(fmt.Sprintf("http ingest hash %q is not a valid sha256 digest", hash))
+	}
+	return digest, nil
+}
+
+// Returns the path which records the WareID produced by an HTTP ingest with the given sha256 digest.
+func httpIngestRecordPath(ws *workspace.Workspace, digest string) string {
+	return filepath.Join(ws.IngestBasePath(), "http", "sha256", digest)
+}
+
+// Looks up the ware previously produced by an HTTP ingest with the given sha256 digest.
+// Returns nil if the ingest has not been done, or if its ware is no longer in the warehouse.
+func lookupHttpIngest(cfg ExecConfig, ws *workspace.Workspace, digest string) *wfapi.WareID {
+	record, err := os.ReadFile(httpIngestRecordPath(ws, digest))
+	if err != nil {
+		return nil
+	}
+	packtype, hash, ok := strings.Cut(strings.TrimSpace(string(record)), ":")
+	if !ok {
+		return nil
+	}
+	wareId := wfapi.WareID{Packtype: wfapi.Packtype(packtype), Hash: hash}
+	if !wareAvailable(cfg, ws, wareId) {
+		return nil
+	}
+	return &wareId
+}
+
+// Downloads the file of an HTTP ingest into a directory, verifying it against the expected hash.
+// The file is named after the last segment of the URL's path.
+// Returns the path of the downloaded file.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the URL or hash is malformed
+//    - warpforge-error-ingest -- when the download fails or does not match the expected hash
+//    - warpforge-error-io -- when the downloaded file cannot be written
+func downloadHttpIngest(ctx context.Context, ingest wfapi.HttpIngest, dir string) (string, error) {
+	ctx, span := tracing.Start(ctx, "downloadHttpIngest", trace.WithAttributes(attribute.String(tracing.AttrKeyWarpforgeIngestHash, ingest.Hash())))
+	defer span.End()
+
+	digest, err := parseIngestHash(ingest.Hash())
+	if err != nil {
+		return "", err
+	}
+	u, errRaw := url.Parse(ingest.Url())
+	if errRaw != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", wfapi.ErrorPlotInvalid(fmt.Sprintf("http ingest url %q must be an http or https URL", ingest.Url()))
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = "download"
+	}
+
+	req, errRaw := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if errRaw != nil {
+		return "", wfapi.ErrorPlotInvalid(fmt.Sprintf("http ingest url %q is invalid: %s", ingest.Url(), errRaw))
+	}
+	resp, errRaw := http.DefaultClient.Do(req)
+	if errRaw != nil {
+		tracing.EndWithStatus(span, errRaw)
+		return "", serum.Error(wfapi.ECodeIngest, serum.WithCause(errRaw),
+			serum.WithMessageTemplate("failed to download {{url|q}}"),
+			serum.WithDetail("url", ingest.Url()),
+		)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := serum.Error(wfapi.ECodeIngest,
+			serum.WithMessageTemplate("failed to download {{url|q}}: server responded {{status}}"),
+			serum.WithDetail("url", ingest.Url()),
+			serum.WithDetail("status", resp.Status),
+		)
+		tracing.EndWithStatus(span, err)
+		return "", err
+	}
+
+	filePath := filepath.Join(dir, name)
+	f, errRaw := os.Create(filePath)
+	if errRaw != nil {
+		return "", wfapi.ErrorIo("failed to create file for http ingest", filePath, errRaw)
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, errRaw := io.Copy(io.MultiWriter(f, hasher), resp.Body); errRaw != nil {
+		return "", serum.Error(wfapi.ECodeIngest, serum.WithCause(errRaw),
+			serum.WithMessageTemplate("failed to download {{url|q}}"),
+			serum.WithDetail("url", ingest.Url()),
+		)
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != digest {
+		os.Remove(filePath)
+		err := serum.Error(wfapi.ECodeIngest,
+			serum.WithMessageTemplate("download of {{url|q}} does not match expected hash: expected {{expected}}, got {{actual}}"),
+			serum.WithDetail("url", ingest.Url()),
+			serum.WithDetail("expected", "sha256:"+digest),
+			serum.WithDetail("actual", "sha256:"+actual),
+		)
+		tracing.EndWithStatus(span, err)
+		return "", err
+	}
+	if errRaw := f.Close(); errRaw != nil {
+		return "", wfapi.ErrorIo("failed to write file for http ingest", filePath, errRaw)
+	}
+	return filePath, nil
+}
+
+// Resolves an HTTP ingest to a ware in the root workspace's warehouse.
+// The file is only downloaded and packed if no ware was recorded for its hash,
+// or if that ware is no longer in the warehouse.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the URL or hash is malformed
+//    - warpforge-error-ingest -- when the download fails or does not match the expected hash
+//    - warpforge-error-io -- when the download cannot be written or the result cannot be recorded
+//    - warpforge-error-executor-failed -- when rio cannot be run
+//    - warpforge-error-ware-pack -- when packing the download fails
+func ingestHttp(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, ingest wfapi.HttpIngest) (wfapi.WareID, error) {
+	ctx, span := tracing.Start(ctx, "ingestHttp")
+	defer span.End()
+
+	digest, err := parseIngestHash(ingest.Hash())
+	if err != nil {
+		return wfapi.WareID{}, err
+	}
+	root := wss.Root()
+	if wareId := lookupHttpIngest(cfg, root, digest); wareId != nil {
+		return *wareId, nil
+	}
+
+	dir, errRaw := os.MkdirTemp(cfg.RunPathBase, "warpforge-ingest-")
+	if errRaw != nil {
+		return wfapi.WareID{}, wfapi.ErrorIo("failed to create directory for http ingest", cfg.RunPathBase, errRaw)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := downloadHttpIngest(ctx, ingest, dir); err != nil {
+		return wfapi.WareID{}, err
+	}
+	wareId, err := formulaexec.PackHostPath(ctx, formulaexec.ExecConfig(cfg), root, dir)
+	if err != nil {
+		return wfapi.WareID{}, err
+	}
+
+	recordPath := httpIngestRecordPath(root, digest)
+	if errRaw := os.MkdirAll(filepath.Dir(recordPath), 0755); errRaw != nil {
+		return wfapi.WareID{}, wfapi.ErrorIo("failed to create directory for http ingest record", filepath.Dir(recordPath), errRaw)
+	}
+	if errRaw := os.WriteFile(recordPath, []byte(wareId.String()), 0644); errRaw != nil {
+		return wfapi.WareID{}, wfapi.ErrorIo("failed to write http ingest record", recordPath, errRaw)
+	}
+	return wareId, nil
+}
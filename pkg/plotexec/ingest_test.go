@@ -0,0 +1,141 @@
+package plotexec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestDownloadHttpIngest(t *testing.T) {
+	content := []byte("hello from a download\n")
+	sum := sha256.Sum256(content)
+	hash := "sha256:" + hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/hello.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		path, err := downloadHttpIngest(ctx, wfapi.HttpIngest(hash+"@"+server.URL+"/files/hello.txt"), dir)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, path, qt.Equals, filepath.Join(dir, "hello.txt"))
+		data, err := os.ReadFile(path)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, data, qt.DeepEquals, content)
+	})
+	t.Run("hash-mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		wrong := sha256.Sum256([]byte("something else"))
+		_, err := downloadHttpIngest(ctx, wfapi.HttpIngest("sha256:"+hex.EncodeToString(wrong[:])+"@"+server.URL+"/files/hello.txt"), dir)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeIngest)
+		qt.Check(t, err, qt.ErrorMatches, `.*does not match expected hash.*`)
+		_, statErr := os.Stat(filepath.Join(dir, "hello.txt"))
+		qt.Check(t, os.IsNotExist(statErr), qt.IsTrue)
+	})
+	t.Run("not-found", func(t *testing.T) {
+		_, err := downloadHttpIngest(ctx, wfapi.HttpIngest(hash+"@"+server.URL+"/missing"), t.TempDir())
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeIngest)
+		qt.Check(t, err, qt.ErrorMatches, `.*server responded 404.*`)
+	})
+	t.Run("bad-hash", func(t *testing.T) {
+		_, err := downloadHttpIngest(ctx, wfapi.HttpIngest("md5:abcd@"+server.URL+"/files/hello.txt"), t.TempDir())
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodePlotInvalid)
+	})
+}
+
+func TestDryRunIngests(t *testing.T) {
+	dir := t.TempDir()
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(`{"plot.v1": {
+		"inputs": {
+			"src": "ingest:hostdir:`+dir+`",
+			"missing": "ingest:hostdir:`+filepath.Join(dir, "missing")+`",
+			"tarball": "ingest:http:sha256:0000000000000000000000000000000000000000000000000000000000000000@http://localhost:0/foo.tar.gz"
+		},
+		"steps": {
+			"one": {
+				"protoformula": {
+					"inputs": {"/src": "pipe::src", "/tarball": "pipe::tarball"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {}
+				}
+			},
+			"two": {
+				"protoformula": {
+					"inputs": {"/src": "pipe::missing"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {}
+				}
+			}
+		},
+		"outputs": {}
+	}}`), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+
+	cfg, wss := newTestConfig(t)
+	plan, err := Plan(context.Background(), cfg, wss, plotCapsule, wfapi.PlotExecConfig{})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, plan.Steps, qt.HasLen, 2)
+	qt.Check(t, plan.Steps[0].Status, qt.Equals, wfapi.StepPlanStatus_Execute)
+	qt.Check(t, plan.Steps[0].Reasons, qt.Contains, `host directory "`+dir+`" will be packed`)
+	qt.Check(t, plan.Steps[0].Reasons, qt.Contains, `"http://localhost:0/foo.tar.gz" will be downloaded`)
+	qt.Check(t, plan.Steps[1].Status, qt.Equals, wfapi.StepPlanStatus_Blocked)
+}
+
+// Test that host directory and http ingests are packed into wares in the root workspace's warehouse.
+func TestIngestPacking(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	skipWithoutPlugins(t, cfg, "rio")
+	ctx := context.Background()
+	root, _ := newTestRootWorkspace(t)
+	wss := workspace.WorkspaceSet{root}
+
+	t.Run("hostdir", func(t *testing.T) {
+		dir := t.TempDir()
+		qt.Assert(t, os.WriteFile(filepath.Join(dir, "file"), []byte("hello\n"), 0644), qt.IsNil)
+		wareId, err := ingestHostDir(ctx, cfg, wss, wfapi.HostDirIngest(dir))
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId.Packtype, qt.Equals, wfapi.Packtype("tar"))
+		qt.Check(t, root.HasWare(wareId), qt.IsTrue)
+
+		_, err = ingestHostDir(ctx, cfg, wss, wfapi.HostDirIngest(filepath.Join(dir, "file")))
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeIo)
+	})
+	t.Run("http", func(t *testing.T) {
+		content := []byte("hello from a download\n")
+		sum := sha256.Sum256(content)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		}))
+		ingest := wfapi.HttpIngest("sha256:" + hex.EncodeToString(sum[:]) + "@" + server.URL + "/hello.txt")
+		wareId, err := ingestHttp(ctx, cfg, wss, ingest)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId.Packtype, qt.Equals, wfapi.Packtype("tar"))
+		qt.Check(t, root.HasWare(wareId), qt.IsTrue)
+
+		// the ware is recorded, so the same ingest doesn't download again
+		server.Close()
+		again, err := ingestHttp(ctx, cfg, wss, ingest)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, again, qt.Equals, wareId)
+	})
+}
@@ -0,0 +1,198 @@
+package plotexec
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/semver"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// LockPlot resolves every catalog reference used in a plot, producing a PlotLock.
+// Entries are sorted by reference.
+// Entries for references with a version constraint record the release it resolved to.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-missing-entry -- when a catalog reference cannot be resolved
+//    - warpforge-error-release-constraint -- when a catalog reference has an invalid version constraint
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-io -- when reading the catalogs fails
+func LockPlot(ctx context.Context, wss workspace.WorkspaceSet, plot wfapi.Plot) (result wfapi.PlotLock, err error) {
+	_, span := tracing.StartFn(ctx, "LockPlot")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	refs := plot.CatalogRefs()
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+
+	result.CatalogRefs.Values = make(map[wfapi.CatalogRef]wfapi.PlotLockEntry, len(refs))
+	for _, ref := range refs {
+		resolved, err := wss.ResolveCatalogRef(ref)
+		if err != nil {
+			return wfapi.PlotLock{}, err
+		}
+		wareId, wareAddr, err := wss.GetCatalogWare(resolved)
+		if err != nil {
+			return wfapi.PlotLock{}, err
+		}
+		if wareId == nil {
+			return wfapi.PlotLock{}, wfapi.ErrorMissingCatalogEntry(ref, false)
+		}
+		entry := wfapi.PlotLockEntry{
+			Ware: *wareId,
+			Addr: wareAddr,
+		}
+		if resolved != ref {
+			entry.Resolved = &resolved
+		}
+		result.CatalogRefs.Keys = append(result.CatalogRefs.Keys, ref)
+		result.CatalogRefs.Values[ref] = entry
+	}
+	return result, nil
+}
+
+// CheckLock verifies that a plot lock is still accurate for a plot.
+// A lock has drifted if the plot uses a catalog reference that is not locked,
+// or if the catalogs now resolve a locked reference to a different ware.
+// References with a version constraint are checked against the release the lock resolved them to,
+// so newer matching releases, or the locked release being yanked, are not drift.
+// References which the catalogs can no longer resolve at all are not drift:
+// the lock remains the source of truth for those.
+//
+// Errors:
+//
+//    - warpforge-error-plot-lock-drift -- when the lock has drifted
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-io -- when reading the catalogs fails
+func CheckLock(ctx context.Context, wss workspace.WorkspaceSet, plot wfapi.Plot, lock wfapi.PlotLock) (err error) {
+	_, span := tracing.StartFn(ctx, "CheckLock")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	var unlocked, changed []string
+	for _, ref := range plot.CatalogRefs() {
+		entry, ok := lock.CatalogRefs.Values[ref]
+		if !ok {
+			unlocked = append(unlocked, ref.String())
+			continue
+		}
+		wareId, _, err := wss.GetCatalogWare(lockedRef(ref, entry))
+		if err != nil {
+			return err
+		}
+		if wareId != nil && *wareId != entry.Ware {
+			changed = append(changed, ref.String())
+		}
+	}
+	if len(unlocked) == 0 && len(changed) == 0 {
+		return nil
+	}
+	return serum.Error(wfapi.ECodePlotLockDrift,
+		serum.WithMessageTemplate("plot lock is out of date: unlocked references: [{{unlocked}}]; references resolving to different wares: [{{changed}}]"),
+		serum.WithDetail("unlocked", strings.Join(unlocked, ", ")),
+		serum.WithDetail("changed", strings.Join(changed, ", ")),
+	)
+}
+
+// Returns the reference the lock entry was resolved from:
+// the concrete release for a reference with a version constraint, or else the reference itself.
+func lockedRef(ref wfapi.CatalogRef, entry wfapi.PlotLockEntry) wfapi.CatalogRef {
+	if entry.Resolved != nil {
+		return *entry.Resolved
+	}
+	return ref
+}
+
+// Resolves the release of a catalog reference whose release name is a version constraint,
+// preferring the release recorded in the plot lock in the config if there is one.
+// Other references are returned unchanged.
+//
+// Errors:
+//
+//    - warpforge-error-release-constraint -- when the version constraint is invalid
+//    - warpforge-error-catalog-missing-entry -- when no release matches the constraint
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-io -- when reading the catalogs fails
+func resolveConstraint(wss workspace.WorkspaceSet, pltCfg wfapi.PlotExecConfig, ref wfapi.CatalogRef) (wfapi.CatalogRef, error) {
+	if !semver.IsConstraint(string(ref.ReleaseName)) {
+		return ref, nil
+	}
+	if pltCfg.Lock != nil {
+		if entry, ok := pltCfg.Lock.CatalogRefs.Values[ref]; ok && entry.Resolved != nil {
+			return *entry.Resolved, nil
+		}
+	}
+	return wss.ResolveCatalogRef(ref)
+}
+
+// Resolves a catalog reference, preferring the plot lock in the config if there is one.
+// If the ware has been bundled into the warehouse of a non-root workspace, that warehouse is used as its address.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-missing-entry -- when the reference is neither locked nor in a catalog
+//    - warpforge-error-catalog-untrusted -- when the trust policy applies to the release, and it is not signed by a trusted key
+//    - warpforge-error-release-constraint -- when the reference has an invalid version constraint
+//    - warpforge-error-io -- when reading the catalogs fails
+//    - warpforge-error-serialization -- when the trust policy can't be parsed
+//    - warpforge-error-datatoonew -- when the trust policy is from a newer version of warpforge
+func resolveCatalogRef(wss workspace.WorkspaceSet, pltCfg wfapi.PlotExecConfig, ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi.WarehouseAddr, error) {
+	wareId, wareAddr, err := resolveCatalogRefLocked(wss, pltCfg, ref)
+	if err != nil || wareId == nil {
+		return wareId, wareAddr, err
+	}
+	for _, ws := range wss[:len(wss)-1] {
+		if ws.HasWare(*wareId) {
+			addr := ws.GetWarehouseAddress()
+			return wareId, &addr, nil
+		}
+	}
+	return wareId, wareAddr, nil
+}
+
+// Resolves a catalog reference using the plot lock if the reference is locked, and the catalogs otherwise.
+// A locked reference is still looked up in the catalogs, for its address;
+// only if the catalogs are missing it, or have a different ware for it, is the lock used as it is.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-missing-entry -- when the reference is neither locked nor in a catalog
+//    - warpforge-error-catalog-untrusted -- when the trust policy applies to the release, and it is not signed by a trusted key
+//    - warpforge-error-release-constraint -- when the reference has an invalid version constraint
+//    - warpforge-error-io -- when reading the catalogs fails
+//    - warpforge-error-serialization -- when the trust policy can't be parsed
+//    - warpforge-error-datatoonew -- when the trust policy is from a newer version of warpforge
+func resolveCatalogRefLocked(wss workspace.WorkspaceSet, pltCfg wfapi.PlotExecConfig, ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi.WarehouseAddr, error) {
+	if pltCfg.Lock == nil {
+		return wss.GetCatalogWare(ref)
+	}
+	entry, ok := pltCfg.Lock.CatalogRefs.Values[ref]
+	if !ok {
+		return wss.GetCatalogWare(ref)
+	}
+	// a constraint is looked up as the release it was locked to, not resolved again
+	wareId, wareAddr, err := wss.GetCatalogWare(lockedRef(ref, entry))
+	switch {
+	case serum.Code(err) == wfapi.ECodeCatalogMissingEntry, err == nil && (wareId == nil || *wareId != entry.Ware):
+		// the catalogs no longer have the locked ware, but the lock still does
+		ware := entry.Ware
+		return &ware, entry.Addr, nil
+	case err != nil:
+		return nil, nil, err
+	}
+	if entry.Addr != nil {
+		wareAddr = entry.Addr
+	}
+	return wareId, wareAddr, nil
+}
@@ -0,0 +1,146 @@
+package plotexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestLockPlot(t *testing.T) {
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(`{"plot.v1": {
+		"inputs": {
+			"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static",
+			"literal": "literal:foo"
+		},
+		"steps": {
+			"one": {
+				"protoformula": {
+					"inputs": {
+						"/": "pipe::rootfs",
+						"/again": "catalog:warpsys.org/busybox:v1.35.0:amd64-static"
+					},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {}
+				}
+			}
+		},
+		"outputs": {}
+	}}`), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	plot := *plotCapsule.Plot
+	ref := wfapi.CatalogRef{
+		ModuleName:  "warpsys.org/busybox",
+		ReleaseName: "v1.35.0",
+		ItemName:    "amd64-static",
+	}
+	ctx := context.Background()
+	_, wss := newTestConfig(t)
+
+	qt.Check(t, plot.CatalogRefs(), qt.DeepEquals, []wfapi.CatalogRef{ref})
+
+	lock, err := LockPlot(ctx, wss, plot)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, lock.CatalogRefs.Keys, qt.DeepEquals, []wfapi.CatalogRef{ref})
+	wareId, _, err := wss.GetCatalogWare(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, lock.CatalogRefs.Values[ref].Ware, qt.Equals, *wareId)
+
+	t.Run("check-current", func(t *testing.T) {
+		qt.Check(t, CheckLock(ctx, wss, plot, lock), qt.IsNil)
+	})
+	t.Run("check-changed", func(t *testing.T) {
+		stale := wfapi.PlotLock{}
+		stale.CatalogRefs.Keys = []wfapi.CatalogRef{ref}
+		stale.CatalogRefs.Values = map[wfapi.CatalogRef]wfapi.PlotLockEntry{
+			ref: {Ware: wfapi.WareID{Packtype: "tar", Hash: "abcd"}},
+		}
+		err := CheckLock(ctx, wss, plot, stale)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodePlotLockDrift)
+		qt.Check(t, err, qt.ErrorMatches, `.*different wares: \[catalog:warpsys.org/busybox:v1.35.0:amd64-static\].*`)
+	})
+	t.Run("check-unlocked", func(t *testing.T) {
+		err := CheckLock(ctx, wss, plot, wfapi.PlotLock{})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodePlotLockDrift)
+		qt.Check(t, err, qt.ErrorMatches, `.*unlocked references: \[catalog:warpsys.org/busybox:v1.35.0:amd64-static\].*`)
+	})
+	t.Run("resolve-prefers-lock", func(t *testing.T) {
+		missing := wfapi.CatalogRef{ModuleName: "example.com/missing", ReleaseName: "v1.0", ItemName: "x86_64"}
+		locked := wfapi.PlotLock{}
+		locked.CatalogRefs.Keys = []wfapi.CatalogRef{missing}
+		locked.CatalogRefs.Values = map[wfapi.CatalogRef]wfapi.PlotLockEntry{
+			missing: {Ware: wfapi.WareID{Packtype: "tar", Hash: "abcd"}},
+		}
+		wareId, _, err := resolveCatalogRef(wss, wfapi.PlotExecConfig{Lock: &locked}, missing)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "abcd"})
+	})
+}
+
+func TestLockPlotConstraint(t *testing.T) {
+	ws, rootPath := newTestRootWorkspace(t)
+	cat, err := ws.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	wss := workspace.WorkspaceSet{ws}
+	addRelease := func(releaseName wfapi.ReleaseName, hash string) {
+		ref := wfapi.CatalogRef{ModuleName: "example.com/tool", ReleaseName: releaseName, ItemName: "src"}
+		qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: hash}, true), qt.IsNil)
+	}
+	addRelease("v1.0.0", "aaaaaaaaaa")
+
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err = ipld.Unmarshal([]byte(`{"plot.v1": {
+		"inputs": {"tool": "catalog:example.com/tool:^1.0:src"},
+		"steps": {},
+		"outputs": {}
+	}}`), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	plot := *plotCapsule.Plot
+	ref := wfapi.CatalogRef{ModuleName: "example.com/tool", ReleaseName: "^1.0", ItemName: "src"}
+	ctx := context.Background()
+
+	lock, err := LockPlot(ctx, wss, plot)
+	qt.Assert(t, err, qt.IsNil)
+	entry := lock.CatalogRefs.Values[ref]
+	qt.Assert(t, entry.Resolved, qt.IsNotNil)
+	qt.Check(t, entry.Resolved.ReleaseName, qt.Equals, wfapi.ReleaseName("v1.0.0"))
+
+	// a newer matching release, and yanking the locked one, don't make the lock drift
+	addRelease("v1.1.0", "bbbbbbbbbb")
+	qt.Assert(t, cat.SetReleaseStatus(*entry.Resolved, &wfapi.ReleaseStatus{Kind: wfapi.ReleaseStatusKind_Yanked}), qt.IsNil)
+	resolved, err := wss.ResolveCatalogRef(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, resolved.ReleaseName, qt.Equals, wfapi.ReleaseName("v1.1.0"))
+	qt.Check(t, CheckLock(ctx, wss, plot, lock), qt.IsNil)
+
+	// and running with the lock uses the locked release
+	pltCfg := wfapi.PlotExecConfig{Lock: &lock}
+	resolved, err = resolveConstraint(wss, pltCfg, ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, resolved, qt.Equals, *entry.Resolved)
+	wareId, _, err := resolveCatalogRef(wss, pltCfg, ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, wareId, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"})
+
+	// but the locked release changing is still drift
+	addRelease("v1.0.0", "cccccccccc")
+	qt.Check(t, serum.Code(CheckLock(ctx, wss, plot, lock)), qt.Equals, wfapi.ECodePlotLockDrift)
+
+	// which running with the lock ignores, but a broken catalog isn't ignored
+	wareId, _, err = resolveCatalogRef(wss, pltCfg, ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, wareId, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"})
+	releasePath := filepath.Join(rootPath, ".warpforge", "catalogs", "default", "example.com", "tool", "_releases", "v1.0.0.json")
+	qt.Assert(t, os.WriteFile(releasePath, []byte("{"), 0644), qt.IsNil)
+	_, _, err = resolveCatalogRef(wss, pltCfg, ref)
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogParse)
+}
@@ -216,6 +216,113 @@ func orderSteps_visit(
 	return nil
 }
 
+// Returns a copy of the plot reduced to the target steps and the steps they transitively depend on.
+// Plot inputs which aren't used by any of the remaining steps are dropped.
+// The outputs of the returned plot are all outputs of the target steps,
+// labeled as "<step>:<label>".
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when a target step does not exist, or the plot is malformed
+func SelectSteps(ctx context.Context, plot wfapi.Plot, targets []wfapi.StepName) (wfapi.Plot, error) {
+	ctx, span := tracing.Start(ctx, "SelectSteps")
+	defer span.End()
+
+	// visit the targets and their upstream steps
+	selected := make(map[wfapi.StepName]struct{})
+	usedInputs := make(map[wfapi.LocalLabel]struct{})
+	todo := make([]wfapi.StepName, 0, len(targets))
+	for _, target := range targets {
+		if _, ok := plot.Steps.Values[target]; !ok {
+			return wfapi.Plot{}, wfapi.ErrorPlotInvalid(fmt.Sprintf("could not select step: no step named %q", target))
+		}
+		todo = append(todo, target)
+	}
+	for len(todo) > 0 {
+		name := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		if _, ok := selected[name]; ok {
+			continue
+		}
+		selected[name] = struct{}{}
+		for _, pipe := range stepInputPipes(plot.Steps.Values[name]) {
+			if pipe.StepName == "" {
+				usedInputs[pipe.Label] = struct{}{}
+				continue
+			}
+			if _, ok := plot.Steps.Values[pipe.StepName]; !ok {
+				return wfapi.Plot{}, wfapi.ErrorPlotInvalid(fmt.Sprintf("invalid pipe 'pipe:%s:%s', step '%s' does not exist", pipe.StepName, pipe.Label, pipe.StepName))
+			}
+			todo = append(todo, pipe.StepName)
+		}
+	}
+
+	result := wfapi.Plot{}
+	result.Inputs.Values = make(map[wfapi.LocalLabel]wfapi.PlotInput)
+	for _, label := range plot.Inputs.Keys {
+		if _, ok := usedInputs[label]; ok {
+			result.Inputs.Keys = append(result.Inputs.Keys, label)
+			result.Inputs.Values[label] = plot.Inputs.Values[label]
+		}
+	}
+	result.Steps.Values = make(map[wfapi.StepName]wfapi.Step)
+	for _, name := range plot.Steps.Keys {
+		if _, ok := selected[name]; ok {
+			result.Steps.Keys = append(result.Steps.Keys, name)
+			result.Steps.Values[name] = plot.Steps.Values[name]
+		}
+	}
+	result.Outputs.Values = make(map[wfapi.LocalLabel]wfapi.PlotOutput)
+	seen := make(map[wfapi.StepName]struct{})
+	for _, target := range targets {
+		if _, ok := seen[target]; ok {
+			continue
+		}
+		seen[target] = struct{}{}
+		step := plot.Steps.Values[target]
+		var labels []wfapi.LocalLabel
+		switch {
+		case step.Protoformula != nil:
+			labels = step.Protoformula.Outputs.Keys
+		case step.Plot != nil:
+			labels = step.Plot.Outputs.Keys
+		}
+		for _, label := range labels {
+			outLabel := wfapi.LocalLabel(fmt.Sprintf("%s:%s", target, label))
+			result.Outputs.Keys = append(result.Outputs.Keys, outLabel)
+			result.Outputs.Values[outLabel] = wfapi.PlotOutput{
+				Pipe: &wfapi.Pipe{StepName: target, Label: label},
+			}
+		}
+	}
+	return result, nil
+}
+
+// Returns all pipes used as inputs to a step.
+func stepInputPipes(step wfapi.Step) []wfapi.Pipe {
+	var inputs []wfapi.PlotInput
+	switch {
+	case step.Protoformula != nil:
+		for _, i := range step.Protoformula.Inputs.Values {
+			inputs = append(inputs, i)
+		}
+	case step.Plot != nil:
+		for _, i := range step.Plot.Inputs.Values {
+			inputs = append(inputs, i)
+		}
+	}
+	pipes := []wfapi.Pipe{}
+	for _, i := range inputs {
+		switch {
+		case i.PlotInputSimple != nil && i.PlotInputSimple.Pipe != nil:
+			pipes = append(pipes, *i.PlotInputSimple.Pipe)
+		case i.PlotInputComplex != nil && i.PlotInputComplex.Basis.Pipe != nil:
+			pipes = append(pipes, *i.PlotInputComplex.Basis.Pipe)
+		}
+	}
+	return pipes
+}
+
 func labelInList(ls []wfapi.LocalLabel, l wfapi.LocalLabel) bool {
 	for _, v := range ls {
 		if v == l {
@@ -0,0 +1,73 @@
+package plotexec
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestSelectSteps(t *testing.T) {
+	serial := `{
+	"plot.v1": {
+		"inputs": {
+			"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static",
+			"unused": "literal:foo"
+		},
+		"steps": {
+			"one": {
+				"protoformula": {
+					"inputs": {"/": "pipe::rootfs"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {"out": {"from": "/out", "packtype": "tar"}}
+				}
+			},
+			"two": {
+				"protoformula": {
+					"inputs": {"/": "pipe::rootfs", "/in": "pipe:one:out"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {"out": {"from": "/out", "packtype": "tar"}}
+				}
+			},
+			"three": {
+				"protoformula": {
+					"inputs": {"/": "pipe::unused"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {}
+				}
+			}
+		},
+		"outputs": {}
+	}
+}`
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(serial), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	ctx := context.Background()
+
+	t.Run("with-dependencies", func(t *testing.T) {
+		plot, err := SelectSteps(ctx, *plotCapsule.Plot, []wfapi.StepName{"two"})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, plot.Steps.Keys, qt.DeepEquals, []wfapi.StepName{"one", "two"})
+		qt.Check(t, plot.Inputs.Keys, qt.DeepEquals, []wfapi.LocalLabel{"rootfs"})
+		qt.Check(t, plot.Outputs.Keys, qt.DeepEquals, []wfapi.LocalLabel{"two:out"})
+
+		order, err := OrderSteps(ctx, plot)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, order, qt.DeepEquals, []wfapi.StepName{"one", "two"})
+	})
+	t.Run("multiple", func(t *testing.T) {
+		plot, err := SelectSteps(ctx, *plotCapsule.Plot, []wfapi.StepName{"one", "three", "one"})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, plot.Steps.Keys, qt.DeepEquals, []wfapi.StepName{"one", "three"})
+		qt.Check(t, plot.Outputs.Keys, qt.DeepEquals, []wfapi.LocalLabel{"one:out"})
+	})
+	t.Run("missing-step", func(t *testing.T) {
+		_, err := SelectSteps(ctx, *plotCapsule.Plot, []wfapi.StepName{"four"})
+		qt.Check(t, err, qt.ErrorMatches, `.*no step named "four".*`)
+	})
+}
@@ -76,6 +76,9 @@ func (m pipeMap) lookup(stepName wfapi.StepName, label wfapi.LocalLabel) (*wfapi
 //    - warpforge-error-plot-invalid -- when the provided plot input is invalid
 //    - warpforge-error-catalog-missing-entry -- when a referenced catalog reference cannot be found
 //    - warpforge-error-git -- when a git related error occurs during a git ingest
+//    - warpforge-error-ingest -- when an http ingest fails to download or does not match its hash
+//    - warpforge-error-executor-failed -- when rio cannot be run to pack an ingest
+//    - warpforge-error-ware-pack -- when packing an ingest fails
 //    - warpforge-error-io -- when an IO error occurs during conversion
 //    - warpforge-error-catalog-parse -- when parsing of catalog files fails
 //    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
@@ -118,10 +121,14 @@ func plotInputToFormulaInput(ctx context.Context,
 //    - warpforge-error-plot-invalid -- when the provided plot input is invalid
 //    - warpforge-error-catalog-missing-entry -- when a referenced catalog reference cannot be found
 //    - warpforge-error-git -- when a git related error occurs during a git ingest
+//    - warpforge-error-ingest -- when an http ingest fails to download or does not match its hash
+//    - warpforge-error-executor-failed -- when rio cannot be run to pack an ingest
+//    - warpforge-error-ware-pack -- when packing an ingest fails
 //    - warpforge-error-io -- when an IO error occurs during conversion
 //    - warpforge-error-catalog-parse -- when parsing of catalog files fails
 //    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
 //    - warpforge-error-plot-step-failed -- when a replay fails
+//    - warpforge-error-release-constraint -- when a catalog reference has an invalid version constraint
 //    - warpforge-error-workspace-missing -- when home workspace is missing or cannot open
 func plotInputToFormulaInputSimple(ctx context.Context,
 	cfg ExecConfig,
@@ -182,8 +189,31 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 			color.WhiteString(basis.CatalogRef.String()),
 		)
 
+		// a version constraint is resolved to a concrete release, which is needed for the replay
+		resolved, err := resolveConstraint(wss, plotCfg, *basis.CatalogRef)
+		if err != nil {
+			return wfapi.FormulaInputSimple{}, nil, err
+		}
+		if resolved != *basis.CatalogRef {
+			logger.Info(LOG_TAG, "\t\t%s = %s",
+				color.HiBlueString("resolved"),
+				color.WhiteString(resolved.String()),
+			)
+		}
+		// yanked and deprecated releases can still be used, but shouldn't be
+		release, err := wss.GetCatalogRelease(resolved)
+		if err != nil {
+			return wfapi.FormulaInputSimple{}, nil, err
+		}
+		if release != nil && release.Status != nil {
+			logger.Info(LOG_TAG, "\t\t%s: %s",
+				color.HiYellowString("warning"),
+				color.YellowString(release.Status.Message(resolved.ModuleName, resolved.ReleaseName)),
+			)
+		}
+
 		// find the WareID and WareAddress for this catalog item
-		wareId, wareAddr, err := wss.GetCatalogWare(*basis.CatalogRef)
+		wareId, wareAddr, err := resolveCatalogRef(wss, plotCfg, *basis.CatalogRef)
 		if err != nil {
 			return wfapi.FormulaInputSimple{}, nil, serum.Error(wfapi.ECodeCatalogMissingEntry,
 				serum.WithMessageTemplate("could not find {{ catalogRef | q}}"),
@@ -221,7 +251,7 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 				wareId.Hash[0:3], wareId.Hash[3:6], wareId.Hash)
 			if _, err := os.Stat(filepath.Join("/", warehousePath)); os.IsNotExist(err) {
 				// ware not found, run the replay to generate it
-				replay, err := wss.GetCatalogReplay(*basis.CatalogRef)
+				replay, err := wss.GetCatalogReplay(resolved)
 				if err != nil {
 					return wfapi.FormulaInputSimple{}, nil, err
 				}
@@ -231,8 +261,11 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 						return wfapi.FormulaInputSimple{}, nil, wfapi.ErrorMissingCatalogEntry(*basis.CatalogRef, true)
 					}
 					logger.Info(LOG_TAG, "resolving replay for module = %s, release = %s...",
-						basis.CatalogRef.ModuleName, basis.CatalogRef.ReleaseName)
-					result, err := execPlot(ctx, cfg, wss, *replay, plotCfg)
+						resolved.ModuleName, resolved.ReleaseName)
+					// the plot lock only applies to the plot it was made for, not to replays
+					replayCfg := plotCfg
+					replayCfg.Lock = nil
+					result, err := execPlot(ctx, cfg, wss, *replay, replayCfg)
 					if err != nil {
 						return wfapi.FormulaInputSimple{}, nil, wfapi.ErrorPlotStepFailed("replay", err)
 					}
@@ -248,6 +281,11 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 					}
 				}
 			}
+		} else if wareAvailable(cfg, wss.Root(), *wareId) {
+			// the ware is already here, for instance from `warpforge fetch`,
+			// so unpack it from the local warehouse instead of going to the mirror.
+			logger.Debug(LOG_TAG, "ware %q is available locally, not using mirror %q", wareId.String(), *wareAddr)
+			wareAddr = nil
 		}
 
 		return wfapi.FormulaInputSimple{
@@ -284,24 +322,11 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 		}
 
 		// resolve the revision of the git ingest to a hash
-		gitCtx, gitSpan := tracing.Start(ctx, "clone git repository", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitClone))
-		defer gitSpan.End()
-		repo, gitErr := git.CloneContext(gitCtx, memory.NewStorage(), nil, &git.CloneOptions{
-			URL: "file://" + path,
-		})
-		tracing.EndWithStatus(gitSpan, gitErr)
-		if gitErr != nil {
-			return input, nil, wfapi.ErrorGit(fmt.Sprintf("failed to checkout git repository at %q to memory", path), gitErr)
-		}
-
-		hashBytes, gitErr := repo.ResolveRevision(plumbing.Revision(basis.Ingest.GitIngest.Ref))
-		if gitErr != nil {
-			return input, nil, wfapi.ErrorGit(fmt.Sprintf("failed to resolve git revision for repository %q", path), gitErr)
+		wareId, err := resolveGitIngest(ctx, path, basis.Ingest.GitIngest.Ref)
+		if err != nil {
+			return input, nil, err
 		}
-
-		// create our formula ware id using the resolved hash
-		input.WareID.Hash = hashBytes.String()
-		input.WareID.Packtype = "git"
+		input.WareID = &wareId
 
 		// checkout the git repository to the cache path
 		cachePath, _err := homeWs.CachePath(*input.WareID)
@@ -312,7 +337,7 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 		if _, errRaw = os.Stat(cachePath); os.IsNotExist(errRaw) {
 			gitCtx, gitSpan := tracing.Start(ctx, "checkout git ingest", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitClone))
 			defer gitSpan.End()
-			_, gitErr = git.PlainCloneContext(gitCtx, cachePath, false, &git.CloneOptions{
+			_, gitErr := git.PlainCloneContext(gitCtx, cachePath, false, &git.CloneOptions{
 				URL:               "file://" + path,
 				RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
 			})
@@ -324,6 +349,20 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 		}
 		return input, nil, nil
 
+	case basis.Ingest != nil && basis.Ingest.HostDirIngest != nil:
+		wareId, err := ingestHostDir(ctx, cfg, wss, *basis.Ingest.HostDirIngest)
+		if err != nil {
+			return wfapi.FormulaInputSimple{}, nil, err
+		}
+		return wfapi.FormulaInputSimple{WareID: &wareId}, nil, nil
+
+	case basis.Ingest != nil && basis.Ingest.HttpIngest != nil:
+		wareId, err := ingestHttp(ctx, cfg, wss, *basis.Ingest.HttpIngest)
+		if err != nil {
+			return wfapi.FormulaInputSimple{}, nil, err
+		}
+		return wfapi.FormulaInputSimple{WareID: &wareId}, nil, nil
+
 	case basis.Literal != nil:
 		// pass through the literal value
 		return wfapi.FormulaInputSimple{
@@ -333,6 +372,35 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 	return wfapi.FormulaInputSimple{}, nil, wfapi.ErrorPlotInvalid("invalid type in plot input")
 }
 
+// Resolves the revision of a git ingest to a git WareID.
+// The repository is cloned into memory; nothing is written to disk.
+//
+// Errors:
+//
+//    - warpforge-error-git -- when the repository cannot be cloned or the revision cannot be resolved
+func resolveGitIngest(ctx context.Context, path string, ref string) (wfapi.WareID, error) {
+	gitCtx, gitSpan := tracing.Start(ctx, "clone git repository", trace.WithAttributes(tracing.AttrFullExecNameGit, tracing.AttrFullExecOperationGitClone))
+	defer gitSpan.End()
+	repo, gitErr := git.CloneContext(gitCtx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL: "file://" + path,
+	})
+	tracing.EndWithStatus(gitSpan, gitErr)
+	if gitErr != nil {
+		return wfapi.WareID{}, wfapi.ErrorGit(fmt.Sprintf("failed to checkout git repository at %q to memory", path), gitErr)
+	}
+
+	hashBytes, gitErr := repo.ResolveRevision(plumbing.Revision(ref))
+	if gitErr != nil {
+		return wfapi.WareID{}, wfapi.ErrorGit(fmt.Sprintf("failed to resolve git revision for repository %q", path), gitErr)
+	}
+
+	// create our formula ware id using the resolved hash
+	return wfapi.WareID{
+		Hash:     hashBytes.String(),
+		Packtype: "git",
+	}, nil
+}
+
 // Executes a protoformula within a Plot
 //
 // Errors:
@@ -344,6 +412,7 @@ func plotInputToFormulaInputSimple(ctx context.Context,
 //    - warpforge-error-ware-pack -- when a ware pack operation fails for a formula output
 //    - warpforge-error-formula-invalid -- when an invalid formula is provided
 //    - warpforge-error-git -- when an error handing a git ingest occurs
+//    - warpforge-error-ingest -- when an http ingest fails to download or does not match its hash
 //    - warpforge-error-catalog-parse -- when parsing of catalog files fails
 //    - warpforge-error-catalog-missing-entry -- when a referenced catalog entry cannot be found
 //    - warpforge-error-plot-invalid -- when the plot contains invalid data
@@ -407,6 +476,7 @@ func execProtoformula(ctx context.Context,
 //    - warpforge-error-plot-invalid -- when the provided plot input is invalid
 //    - warpforge-error-catalog-missing-entry -- when a referenced catalog reference cannot be found
 //    - warpforge-error-git -- when a git related error occurs during a git ingest
+//    - warpforge-error-ingest -- when an http ingest fails to download or does not match its hash
 //    - warpforge-error-io -- when an IO error occurs during conversion
 //    - warpforge-error-catalog-parse -- when parsing of catalog files fails
 //    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
@@ -543,15 +613,81 @@ func execPlot(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, p
 //    - warpforge-error-catalog-missing-entry -- when a referenced catalog reference cannot be found
 //    - warpforge-error-catalog-parse -- when parsing of catalog files fails
 //    - warpforge-error-git -- when a git related error occurs during a git ingest
+//    - warpforge-error-ingest -- when an http ingest fails to download or does not match its hash
 //    - warpforge-error-io -- when an IO error occurs during conversion
 //    - warpforge-error-plot-invalid -- when the provided plot input is invalid
 //    - warpforge-error-plot-step-failed -- when execution of a plot step fails
 //    - warpforge-error-workspace-missing -- when home workspace is missing or cannot be opened
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
 func Exec(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, plotCapsule wfapi.PlotCapsule, pltCfg wfapi.PlotExecConfig) (result wfapi.PlotResults, err error) {
 	ctx, span := tracing.StartFn(ctx, "Exec")
 	defer func() { tracing.EndWithStatus(span, err) }()
 	if plotCapsule.Plot == nil {
 		return wfapi.PlotResults{}, wfapi.ErrorPlotInvalid("PlotCapsule does not contain a v1 plot")
 	}
-	return execPlot(ctx, cfg, wss, *plotCapsule.Plot, pltCfg)
+	plot, pltCfg, err := prepareTopLevelPlot(ctx, wss, *plotCapsule.Plot, pltCfg)
+	if err != nil {
+		return wfapi.PlotResults{}, err
+	}
+	return execPlot(ctx, cfg, wss, plot, pltCfg)
+}
+
+// Checks the plot lock, and applies the input overrides and step selection of a PlotExecConfig to a top level plot.
+// The returned config has no overrides or step selection, so that it may be passed on to subplots and replays.
+// The lock is checked against the plot as given, before any overrides are applied.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when an override or selected step does not exist, or the plot is malformed
+//    - warpforge-error-plot-lock-drift -- when the plot lock is out of date
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-io -- when reading the catalogs fails
+func prepareTopLevelPlot(ctx context.Context, wss workspace.WorkspaceSet, plot wfapi.Plot, pltCfg wfapi.PlotExecConfig) (wfapi.Plot, wfapi.PlotExecConfig, error) {
+	if pltCfg.Lock != nil {
+		if err := CheckLock(ctx, wss, plot, *pltCfg.Lock); err != nil {
+			return plot, pltCfg, err
+		}
+	}
+	if len(pltCfg.InputOverrides) > 0 {
+		var err error
+		plot, err = OverrideInputs(plot, pltCfg.InputOverrides)
+		if err != nil {
+			return plot, pltCfg, err
+		}
+		pltCfg.InputOverrides = nil
+	}
+	if len(pltCfg.Steps) > 0 {
+		selected, err := SelectSteps(ctx, plot, pltCfg.Steps)
+		if err != nil {
+			return plot, pltCfg, err
+		}
+		plot = selected
+		pltCfg.Steps = nil
+	}
+	return plot, pltCfg, nil
+}
+
+// OverrideInputs returns a copy of the plot with the values of some of its inputs replaced.
+// Only existing inputs may be overridden, and pipes are not permitted as replacements.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the plot has no input with an overridden label, or a replacement is a pipe
+func OverrideInputs(plot wfapi.Plot, overrides map[wfapi.LocalLabel]wfapi.PlotInput) (wfapi.Plot, error) {
+	values := make(map[wfapi.LocalLabel]wfapi.PlotInput, len(plot.Inputs.Values))
+	for label, input := range plot.Inputs.Values {
+		values[label] = input
+	}
+	for label, input := range overrides {
+		if _, ok := values[label]; !ok {
+			return plot, wfapi.ErrorPlotInvalid(fmt.Sprintf("cannot override input %q: plot has no such input", label))
+		}
+		if input.Basis().Pipe != nil {
+			return plot, wfapi.ErrorPlotInvalid(fmt.Sprintf("cannot override input %q: pipes are not valid plot inputs", label))
+		}
+		values[label] = input
+	}
+	plot.Inputs.Values = values
+	return plot, nil
 }
@@ -37,6 +37,25 @@ func newTestConfig(t *testing.T) (ExecConfig, workspace.WorkspaceSet) {
 	}, wss
 }
 
+// constructs a root workspace in a temporary directory, returning it and its path
+func newTestRootWorkspace(t *testing.T) (*workspace.Workspace, string) {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, ".warpforge"), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, ".warpforge", "root"), nil, 0644), qt.IsNil)
+	root, err := workspace.OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	return root, rootPath
+}
+
+// skips a test which runs plugins, such as "rio" or "runc", if they aren't built
+func skipWithoutPlugins(t *testing.T, cfg ExecConfig, plugins ...string) {
+	for _, bin := range plugins {
+		if _, err := os.Stat(filepath.Join(cfg.BinPath, bin)); err != nil {
+			t.Skipf("%s is not available in %s", bin, cfg.BinPath)
+		}
+	}
+}
+
 // Test example plots.
 func TestFormulaExecFixtures(t *testing.T) {
 	doc, err := testmark.ReadFile("../../examples/220-plot-usage/example-plot-exec.md")
@@ -155,3 +174,31 @@ func TestCycleFails(t *testing.T) {
 	_, err = OrderSteps(ctx, p)
 	qt.Assert(t, err, qt.IsNotNil)
 }
+
+func TestOverrideInputs(t *testing.T) {
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(`{"plot.v1": {
+		"inputs": {"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static"},
+		"steps": {},
+		"outputs": {}
+	}}`), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	plot := *plotCapsule.Plot
+
+	ware, err := wfapi.ParsePlotInput("ware:tar:abcd")
+	qt.Assert(t, err, qt.IsNil)
+	pipe, err := wfapi.ParsePlotInput("pipe:one:out")
+	qt.Assert(t, err, qt.IsNil)
+
+	result, err := OverrideInputs(plot, map[wfapi.LocalLabel]wfapi.PlotInput{"rootfs": ware})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, result.Inputs.Values["rootfs"].PlotInputSimple.WareID, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "abcd"})
+	// the original plot must not be modified
+	qt.Check(t, plot.Inputs.Values["rootfs"].PlotInputSimple.CatalogRef, qt.IsNotNil)
+
+	_, err = OverrideInputs(plot, map[wfapi.LocalLabel]wfapi.PlotInput{"missing": ware})
+	qt.Check(t, err, qt.ErrorMatches, `.*plot has no such input.*`)
+
+	_, err = OverrideInputs(plot, map[wfapi.LocalLabel]wfapi.PlotInput{"rootfs": pipe})
+	qt.Check(t, err, qt.ErrorMatches, `.*pipes are not valid plot inputs.*`)
+}
@@ -0,0 +1,74 @@
+package plotexec
+
+import (
+	"context"
+
+	"github.com/warptools/warpforge/pkg/semver"
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// UpgradeConfig controls which catalog references UpgradePlot upgrades.
+type UpgradeConfig struct {
+	// Modules, if not empty, restricts upgrades to references to these modules.
+	Modules []wfapi.ModuleName
+}
+
+// PlotUpgrade is a catalog reference which UpgradePlot changed to a newer release.
+type PlotUpgrade struct {
+	From wfapi.CatalogRef
+	To   wfapi.CatalogRef
+}
+
+// UpgradePlot changes every catalog reference used in a plot, including within protoformulas and subplots,
+// to the newest release of the same module which has the same item, as found by WorkspaceSet.NewerRelease.
+// References with a version constraint, and references to releases without a version, are left alone.
+// The plot is modified in place, so the order of its keys is preserved.
+//
+// The upgrades are returned in order of first use, with each reference appearing once.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+//    - warpforge-error-io -- when reading the catalogs fails
+func UpgradePlot(ctx context.Context, wss workspace.WorkspaceSet, plot *wfapi.Plot, cfg UpgradeConfig) (result []PlotUpgrade, err error) {
+	_, span := tracing.StartFn(ctx, "UpgradePlot")
+	defer func() { tracing.EndWithStatus(span, err) }()
+
+	modules := make(map[wfapi.ModuleName]struct{}, len(cfg.Modules))
+	for _, m := range cfg.Modules {
+		modules[m] = struct{}{}
+	}
+
+	upgrades := make(map[wfapi.CatalogRef]wfapi.CatalogRef)
+	for _, ref := range plot.CatalogRefs() {
+		if _, ok := modules[ref.ModuleName]; len(modules) > 0 && !ok {
+			continue
+		}
+		if semver.IsConstraint(string(ref.ReleaseName)) {
+			continue
+		}
+		newer, err := wss.NewerRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		if newer == nil {
+			continue
+		}
+		to := ref
+		to.ReleaseName = newer.ReleaseName
+		upgrades[ref] = to
+		result = append(result, PlotUpgrade{From: ref, To: to})
+	}
+
+	plot.RewriteCatalogRefs(func(ref wfapi.CatalogRef) wfapi.CatalogRef {
+		if to, ok := upgrades[ref]; ok {
+			return to
+		}
+		return ref
+	})
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package plotexec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+
+	"github.com/warptools/warpforge/pkg/dab"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestUpgradePlot(t *testing.T) {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, dab.MagicFilename_Workspace), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, dab.MagicFilename_Workspace, "root"), nil, 0644), qt.IsNil)
+	root, err := workspace.OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	wss := workspace.WorkspaceSet{root}
+	cat, err := root.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	for _, ref := range []string{
+		"example.com/gcc:v1.0.0:amd64",
+		"example.com/gcc:v1.2.0:amd64",
+		"example.com/gcc:v1.3.0:arm64",
+		"example.com/gcc:v2.0.0-rc1:amd64",
+		"example.com/libc:v2.0:amd64",
+		"example.com/libc:v2.1:amd64",
+	} {
+		parts := strings.Split(ref, ":")
+		qt.Assert(t, cat.AddItem(wfapi.CatalogRef{
+			ModuleName:  wfapi.ModuleName(parts[0]),
+			ReleaseName: wfapi.ReleaseName(parts[1]),
+			ItemName:    wfapi.ItemLabel(parts[2]),
+		}, wfapi.WareID{Packtype: "tar", Hash: strings.ReplaceAll(ref, ":", "")}, false), qt.IsNil)
+	}
+
+	parse := func() wfapi.Plot {
+		plotCapsule := wfapi.PlotCapsule{}
+		_, err := ipld.Unmarshal([]byte(`{"plot.v1": {
+			"inputs": {
+				"zz-gcc": "catalog:example.com/gcc:v1.0.0:amd64",
+				"aa-libc": "catalog:example.com/libc:v2.0:amd64",
+				"floating": "catalog:example.com/gcc:^1:amd64"
+			},
+			"steps": {
+				"one": {
+					"protoformula": {
+						"inputs": {
+							"/": "pipe::zz-gcc",
+							"/gcc": "catalog:example.com/gcc:v1.0.0:amd64"
+						},
+						"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+						"outputs": {}
+					}
+				},
+				"nested": {
+					"plot": {
+						"inputs": {"libc": "catalog:example.com/libc:v2.0:amd64"},
+						"steps": {},
+						"outputs": {}
+					}
+				}
+			},
+			"outputs": {}
+		}}`), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+		qt.Assert(t, err, qt.IsNil)
+		return *plotCapsule.Plot
+	}
+	ref := func(module wfapi.ModuleName, release wfapi.ReleaseName) wfapi.CatalogRef {
+		return wfapi.CatalogRef{ModuleName: module, ReleaseName: release, ItemName: "amd64"}
+	}
+	ctx := context.Background()
+
+	plot := parse()
+	upgrades, err := UpgradePlot(ctx, wss, &plot, UpgradeConfig{})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, upgrades, qt.DeepEquals, []PlotUpgrade{
+		{From: ref("example.com/gcc", "v1.0.0"), To: ref("example.com/gcc", "v1.2.0")},
+		{From: ref("example.com/libc", "v2.0"), To: ref("example.com/libc", "v2.1")},
+	})
+	qt.Check(t, plot.CatalogRefs(), qt.DeepEquals, []wfapi.CatalogRef{
+		ref("example.com/gcc", "v1.2.0"),
+		ref("example.com/libc", "v2.1"),
+		ref("example.com/gcc", "^1"),
+	})
+	serial, err := ipld.Marshal(json.Encode, &plot, wfapi.TypeSystem.TypeByName("Plot"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, strings.Index(string(serial), "zz-gcc") < strings.Index(string(serial), "aa-libc"), qt.IsTrue)
+
+	t.Run("modules", func(t *testing.T) {
+		plot := parse()
+		upgrades, err := UpgradePlot(ctx, wss, &plot, UpgradeConfig{Modules: []wfapi.ModuleName{"example.com/libc"}})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, upgrades, qt.DeepEquals, []PlotUpgrade{
+			{From: ref("example.com/libc", "v2.0"), To: ref("example.com/libc", "v2.1")},
+		})
+		qt.Check(t, plot.CatalogRefs()[0], qt.Equals, ref("example.com/gcc", "v1.0.0"))
+	})
+}
@@ -0,0 +1,99 @@
+package plotexec
+
+import (
+	"context"
+	"sort"
+
+	"github.com/warptools/warpforge/pkg/tracing"
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// ReplayItemResult is the comparison of a single item of a release with the output of its replay.
+type ReplayItemResult struct {
+	Item     wfapi.ItemLabel
+	Status   wfapi.ReplayItemStatus
+	Expected *wfapi.WareID // The WareID recorded in the release; nil for extra outputs.
+	Produced *wfapi.WareID // The WareID the replay produced; nil for missing items.
+}
+
+// ReplayVerification is the result of comparing what a replay produced with the items of its release.
+// Items are in the order of the release, followed by any extra outputs sorted by label.
+type ReplayVerification struct {
+	Items []ReplayItemResult
+}
+
+// Ok is true if the replay reproduced every item of the release.
+// Extra outputs are reported, but don't make a replay fail verification,
+// since a release need not include every output of its plot.
+func (v ReplayVerification) Ok() bool {
+	for _, item := range v.Items {
+		if item.Status == wfapi.ReplayItemStatus_Mismatch || item.Status == wfapi.ReplayItemStatus_Missing {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareReplayResults compares the outputs of a replay with the items of its release.
+func CompareReplayResults(release wfapi.CatalogRelease, results wfapi.PlotResults) ReplayVerification {
+	var v ReplayVerification
+	for _, item := range release.Items.Keys {
+		expected := release.Items.Values[item]
+		result := ReplayItemResult{Item: item, Expected: &expected}
+		produced, ok := results.Values[wfapi.LocalLabel(item)]
+		switch {
+		case !ok:
+			result.Status = wfapi.ReplayItemStatus_Missing
+		case produced == expected:
+			result.Status = wfapi.ReplayItemStatus_Match
+			result.Produced = &produced
+		default:
+			result.Status = wfapi.ReplayItemStatus_Mismatch
+			result.Produced = &produced
+		}
+		v.Items = append(v.Items, result)
+	}
+	var extra []ReplayItemResult
+	for label, produced := range results.Values {
+		if _, ok := release.Items.Values[wfapi.ItemLabel(label)]; ok {
+			continue
+		}
+		produced := produced
+		extra = append(extra, ReplayItemResult{Item: wfapi.ItemLabel(label), Status: wfapi.ReplayItemStatus_Extra, Produced: &produced})
+	}
+	// plot results are collected from a map, so their order means nothing
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Item < extra[j].Item })
+	v.Items = append(v.Items, extra...)
+	return v
+}
+
+// VerifyReplay executes the replay of a release and compares its outputs with the release's items.
+// Memoization is disabled, for the replay and for any replays it runs in turn,
+// so that every step is really executed rather than trusting earlier results.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the replay is invalid
+//    - warpforge-error-catalog-missing-entry -- when a catalog reference in the replay cannot be found
+//    - warpforge-error-git -- when a git related error occurs during a git ingest
+//    - warpforge-error-ingest -- when an http ingest fails to download or does not match its hash
+//    - warpforge-error-io -- when an IO error occurs during execution
+//    - warpforge-error-catalog-parse -- when parsing of catalog files fails
+//    - warpforge-error-catalog-invalid -- when the catalog contains invalid data
+//    - warpforge-error-plot-step-failed -- when execution of a replay step fails
+//    - warpforge-error-workspace-missing -- when home workspace is missing or cannot be opened
+func VerifyReplay(ctx context.Context, cfg ExecConfig, wss workspace.WorkspaceSet, release wfapi.CatalogRelease, replay wfapi.Plot, pltCfg wfapi.PlotExecConfig) (result ReplayVerification, err error) {
+	ctx, span := tracing.StartFn(ctx, "VerifyReplay")
+	defer func() { tracing.EndWithStatus(span, err) }()
+	// replays are stored as they were executed, so there's no lock, overrides or step selection to apply
+	pltCfg.Lock = nil
+	pltCfg.InputOverrides = nil
+	pltCfg.Steps = nil
+	pltCfg.FormulaExecConfig.DisableMemoization = true
+	results, err := execPlot(ctx, cfg, wss, replay, pltCfg)
+	if err != nil {
+		return ReplayVerification{}, err
+	}
+	return CompareReplayResults(release, results), nil
+}
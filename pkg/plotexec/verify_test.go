@@ -0,0 +1,125 @@
+package plotexec
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+
+	"github.com/warptools/warpforge/pkg/workspace"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestCompareReplayResults(t *testing.T) {
+	ware := func(hash string) wfapi.WareID {
+		return wfapi.WareID{Packtype: "tar", Hash: hash}
+	}
+	release := wfapi.CatalogRelease{ReleaseName: "v1"}
+	release.Items.Keys = []wfapi.ItemLabel{"amd64", "arm64", "src"}
+	release.Items.Values = map[wfapi.ItemLabel]wfapi.WareID{
+		"amd64": ware("aaaa"),
+		"arm64": ware("bbbb"),
+		"src":   ware("cccc"),
+	}
+
+	results := wfapi.PlotResults{Values: map[wfapi.LocalLabel]wfapi.WareID{
+		"amd64": ware("aaaa"),
+		"arm64": ware("bbbb"),
+		"src":   ware("cccc"),
+		"logs":  ware("dddd"),
+	}}
+	v := CompareReplayResults(release, results)
+	qt.Check(t, v.Ok(), qt.IsTrue)
+	var statuses []wfapi.ReplayItemStatus
+	for _, item := range v.Items {
+		statuses = append(statuses, item.Status)
+	}
+	qt.Check(t, statuses, qt.DeepEquals, []wfapi.ReplayItemStatus{wfapi.ReplayItemStatus_Match, wfapi.ReplayItemStatus_Match, wfapi.ReplayItemStatus_Match, wfapi.ReplayItemStatus_Extra})
+	qt.Check(t, v.Items[3].Item, qt.Equals, wfapi.ItemLabel("logs"))
+
+	delete(results.Values, "src")
+	results.Values["arm64"] = ware("eeee")
+	v = CompareReplayResults(release, results)
+	qt.Check(t, v.Ok(), qt.IsFalse)
+	qt.Check(t, v.Items[1], qt.DeepEquals, ReplayItemResult{
+		Item:     "arm64",
+		Status:   wfapi.ReplayItemStatus_Mismatch,
+		Expected: &wfapi.WareID{Packtype: "tar", Hash: "bbbb"},
+		Produced: &wfapi.WareID{Packtype: "tar", Hash: "eeee"},
+	})
+	qt.Check(t, v.Items[2].Status, qt.Equals, wfapi.ReplayItemStatus_Missing)
+	qt.Check(t, v.Items[2].Produced, qt.IsNil)
+}
+
+// VerifyReplay must really execute the replay, even when a memo of it says otherwise.
+func TestVerifyReplay(t *testing.T) {
+	wfCfg, projWss := newTestConfig(t)
+	skipWithoutPlugins(t, wfCfg, "rio", "runc")
+	ctx := context.Background()
+
+	// memos are stored in the root workspace, so use one of our own rather than the project's
+	root, _ := newTestRootWorkspace(t)
+	wss := workspace.WorkspaceSet{projWss[0], root}
+
+	serial := `{
+	"inputs": {
+		"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static"
+	},
+	"steps": {
+		"one": {
+			"protoformula": {
+				"inputs": {
+					"/": "pipe::rootfs"
+				},
+				"action": {
+					"script": {
+						"interpreter": "/bin/sh",
+						"contents": ["mkdir /out", "echo hello > /out/file"]
+					}
+				},
+				"outputs": {
+					"out": {"from": "/out", "packtype": "tar"}
+				}
+			}
+		}
+	},
+	"outputs": {
+		"out": "pipe:one:out"
+	}
+}`
+	replay := wfapi.Plot{}
+	_, err := ipld.Unmarshal([]byte(serial), json.Decode, &replay, wfapi.TypeSystem.TypeByName("Plot"))
+	qt.Assert(t, err, qt.IsNil)
+
+	results, err := execPlot(ctx, wfCfg, wss, replay, wfapi.PlotExecConfig{})
+	qt.Assert(t, err, qt.IsNil)
+	produced := results.Values["out"]
+	release := wfapi.CatalogRelease{ReleaseName: "v1"}
+	release.Items.Keys = []wfapi.ItemLabel{"out"}
+	release.Items.Values = map[wfapi.ItemLabel]wfapi.WareID{"out": produced}
+
+	// tamper with the memo of the step, so a memoized run would produce something else
+	memos, err := os.ReadDir(root.MemoBasePath())
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, memos, qt.HasLen, 1)
+	memo, err := root.LoadMemo(strings.TrimSuffix(memos[0].Name(), ".json"))
+	qt.Assert(t, err, qt.IsNil)
+	bogus := wfapi.WareID{Packtype: "tar", Hash: "bogus"}
+	memo.Results.Values["out"] = wfapi.FormulaInputSimple{WareID: &bogus}
+	qt.Assert(t, root.StoreMemo(*memo), qt.IsNil)
+	results, err = execPlot(ctx, wfCfg, wss, replay, wfapi.PlotExecConfig{})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, results.Values["out"], qt.Equals, bogus)
+
+	// the config given doesn't disable memoization, but VerifyReplay must anyway
+	v, err := VerifyReplay(ctx, wfCfg, wss, release, replay, wfapi.PlotExecConfig{})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, v.Ok(), qt.IsTrue)
+	qt.Check(t, v.Items, qt.DeepEquals, []ReplayItemResult{
+		{Item: "out", Status: wfapi.ReplayItemStatus_Match, Expected: &produced, Produced: &produced},
+	})
+}
@@ -0,0 +1,392 @@
+/*
+	Package plotgraph renders the structure of a Plot as a graph,
+	in either graphviz DOT or Mermaid flowchart syntax.
+
+	Steps, subplots, plot inputs, and plot outputs become nodes;
+	pipes become edges. Subplots are drawn as nested clusters.
+	A graph may optionally be annotated with what is known about each step,
+	such as its memo status and the WareIDs it produced when it last ran.
+*/
+package plotgraph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// Format is the syntax a graph is rendered in.
+type Format string
+
+const (
+	Format_Dot     Format = "dot"
+	Format_Mermaid Format = "mermaid"
+)
+
+// StepAnnotation describes what is known about a step beyond the plot itself.
+type StepAnnotation struct {
+	Status    wfapi.StepPlanStatus              // may be empty if unknown.
+	FormulaID string                            // may be empty if unknown.
+	Outputs   map[wfapi.LocalLabel]wfapi.WareID // outputs of the last run of this step, if any.
+}
+
+// Annotations are keyed by step name.
+// Steps within subplots are prefixed by their parents, separated by "/",
+// matching the naming used by wfapi.StepPlan.
+type Annotations map[string]StepAnnotation
+
+type nodeKind int
+
+const (
+	nodeKind_Input nodeKind = iota
+	nodeKind_Step
+	nodeKind_Output
+)
+
+type node struct {
+	id    string
+	kind  nodeKind
+	lines []string
+}
+
+type cluster struct {
+	id       string
+	label    string
+	nodes    []node
+	clusters []*cluster
+}
+
+type edge struct {
+	from  string
+	to    string
+	label []string
+}
+
+// pendingEdge is an edge whose source is a pipe that may not have been visited yet.
+type pendingEdge struct {
+	source string // see pipeKey.
+	to     string
+	label  string
+}
+
+type graph struct {
+	root    cluster
+	edges   []edge
+	pending []pendingEdge
+	sources map[string]string       // pipeKey -> node id
+	wares   map[string]wfapi.WareID // pipeKey -> last known ware
+	next    int
+	ann     Annotations
+}
+
+func pipeKey(prefix string, step wfapi.StepName, label wfapi.LocalLabel) string {
+	return prefix + "|" + string(step) + "|" + string(label)
+}
+
+// Plot outputs are keyed separately from plot inputs, since they may share labels.
+func outputKey(prefix string, label wfapi.LocalLabel) string {
+	return prefix + ">" + string(label)
+}
+
+func (g *graph) newID() string {
+	id := fmt.Sprintf("n%d", g.next)
+	g.next++
+	return id
+}
+
+// Describes a plot input as a kind and a detail string.
+// Pipes have a kind of "pipe".
+func describeInput(input wfapi.PlotInput) (string, string, *wfapi.Pipe) {
+	var basis wfapi.PlotInputSimple
+	switch {
+	case input.PlotInputSimple != nil:
+		basis = *input.PlotInputSimple
+	case input.PlotInputComplex != nil:
+		basis = input.PlotInputComplex.Basis
+	}
+	switch {
+	case basis.WareID != nil:
+		return "ware", basis.WareID.String(), nil
+	case basis.CatalogRef != nil:
+		return "catalog", basis.CatalogRef.String(), nil
+	case basis.Ingest != nil && basis.Ingest.GitIngest != nil:
+		return "ingest", fmt.Sprintf("git:%s:%s", basis.Ingest.GitIngest.HostPath, basis.Ingest.GitIngest.Ref), nil
+	case basis.Ingest != nil && basis.Ingest.HostDirIngest != nil:
+		return "ingest", fmt.Sprintf("hostdir:%s", basis.Ingest.HostDirIngest.HostPath()), nil
+	case basis.Ingest != nil && basis.Ingest.HttpIngest != nil:
+		return "ingest", fmt.Sprintf("http:%s@%s", basis.Ingest.HttpIngest.Hash(), basis.Ingest.HttpIngest.Url()), nil
+	case basis.Mount != nil:
+		return "mount", fmt.Sprintf("%s:%s", basis.Mount.Mode, basis.Mount.HostPath), nil
+	case basis.Literal != nil:
+		return "literal", string(*basis.Literal), nil
+	case basis.Pipe != nil:
+		return "pipe", "", basis.Pipe
+	}
+	return "unknown", "", nil
+}
+
+func portString(port wfapi.SandboxPort) string {
+	switch {
+	case port.SandboxPath != nil:
+		return "/" + string(*port.SandboxPath)
+	case port.SandboxVar != nil:
+		return "$" + string(*port.SandboxVar)
+	}
+	return ""
+}
+
+// Adds an input node to a cluster, or a pending edge if the input is a pipe.
+func (g *graph) addInput(c *cluster, prefix string, name string, input wfapi.PlotInput, to string) {
+	kind, detail, pipe := describeInput(input)
+	if pipe != nil {
+		g.pending = append(g.pending, pendingEdge{
+			source: pipeKey(prefix, pipe.StepName, pipe.Label),
+			to:     to,
+			label:  name,
+		})
+		return
+	}
+	id := g.newID()
+	n := node{id: id, kind: nodeKind_Input, lines: []string{name, kind}}
+	if detail != "" {
+		n.lines = append(n.lines, detail)
+	}
+	c.nodes = append(c.nodes, n)
+	g.edges = append(g.edges, edge{from: id, to: to})
+}
+
+// Adds the contents of a plot to a cluster.
+// The plot's inputs are resolved within the parent scope (parentPrefix),
+// everything else within the plot's own scope (prefix).
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when a step contains neither a protoformula nor a plot
+func (g *graph) addPlot(c *cluster, parentPrefix string, prefix string, plot wfapi.Plot) error {
+	for _, name := range plot.Inputs.Keys {
+		id := g.newID()
+		kind, detail, _ := describeInput(plot.Inputs.Values[name])
+		n := node{id: id, kind: nodeKind_Input, lines: []string{string(name), kind}}
+		if detail != "" {
+			n.lines = append(n.lines, detail)
+		}
+		c.nodes = append(c.nodes, n)
+		g.sources[pipeKey(prefix, "", name)] = id
+		if _, _, pipe := describeInput(plot.Inputs.Values[name]); pipe != nil {
+			g.pending = append(g.pending, pendingEdge{
+				source: pipeKey(parentPrefix, pipe.StepName, pipe.Label),
+				to:     id,
+			})
+		}
+	}
+
+	for _, name := range plot.Steps.Keys {
+		step := plot.Steps.Values[name]
+		path := prefix + string(name)
+		switch {
+		case step.Protoformula != nil:
+			id := g.newID()
+			lines := []string{string(name)}
+			if ann, ok := g.ann[path]; ok {
+				if ann.Status != "" {
+					lines = append(lines, string(ann.Status))
+				}
+				if ann.FormulaID != "" {
+					lines = append(lines, ann.FormulaID)
+				}
+				for label, wareId := range ann.Outputs {
+					g.wares[pipeKey(prefix, name, label)] = wareId
+				}
+			}
+			c.nodes = append(c.nodes, node{id: id, kind: nodeKind_Step, lines: lines})
+			for _, label := range step.Protoformula.Outputs.Keys {
+				g.sources[pipeKey(prefix, name, label)] = id
+			}
+			for _, port := range step.Protoformula.Inputs.Keys {
+				g.addInput(c, prefix, portString(port), step.Protoformula.Inputs.Values[port], id)
+			}
+		case step.Plot != nil:
+			sub := &cluster{id: fmt.Sprintf("cluster_%d", g.next), label: string(name)}
+			g.next++
+			subPrefix := path + "/"
+			if err := g.addPlot(sub, prefix, subPrefix, *step.Plot); err != nil {
+				return err
+			}
+			c.clusters = append(c.clusters, sub)
+			for _, label := range step.Plot.Outputs.Keys {
+				g.sources[pipeKey(prefix, name, label)] = g.sources[outputKey(subPrefix, label)]
+			}
+		default:
+			return wfapi.ErrorPlotInvalid(fmt.Sprintf("plot step %q does not contain a Protoformula or Plot", name))
+		}
+	}
+
+	for _, name := range plot.Outputs.Keys {
+		output := plot.Outputs.Values[name]
+		id := g.newID()
+		c.nodes = append(c.nodes, node{id: id, kind: nodeKind_Output, lines: []string{string(name)}})
+		// outputs of a subplot are sources for the parent plot
+		g.sources[outputKey(prefix, name)] = id
+		if output.Pipe != nil {
+			g.pending = append(g.pending, pendingEdge{
+				source: pipeKey(prefix, output.Pipe.StepName, output.Pipe.Label),
+				to:     id,
+			})
+		}
+	}
+	return nil
+}
+
+// Resolves all pending edges to concrete edges.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when a pipe cannot be resolved
+func (g *graph) resolve() error {
+	for _, p := range g.pending {
+		from, ok := g.sources[p.source]
+		if !ok {
+			parts := strings.SplitN(p.source, "|", 3)
+			return wfapi.ErrorPlotInvalid(fmt.Sprintf("could not resolve pipe 'pipe:%s:%s'", parts[1], parts[2]))
+		}
+		var label []string
+		parts := strings.SplitN(p.source, "|", 3)
+		if parts[1] != "" {
+			label = append(label, parts[2])
+		}
+		if wareId, ok := g.wares[p.source]; ok {
+			label = append(label, wareId.String())
+		}
+		if p.label != "" {
+			label = append(label, "as "+p.label)
+		}
+		g.edges = append(g.edges, edge{from: from, to: p.to, label: label})
+	}
+	g.pending = nil
+	return nil
+}
+
+// Render writes a graph of the plot to w in the given format.
+// Annotations may be nil.
+//
+// Errors:
+//
+//    - warpforge-error-plot-invalid -- when the plot contains unresolvable pipes or invalid steps
+//    - warpforge-error-invalid-argument -- when the format is unknown
+//    - warpforge-error-io -- when writing fails
+func Render(w io.Writer, plot wfapi.Plot, format Format, annotations Annotations) error {
+	g := &graph{
+		sources: make(map[string]string),
+		wares:   make(map[string]wfapi.WareID),
+		ann:     annotations,
+	}
+	if err := g.addPlot(&g.root, "", "", plot); err != nil {
+		return err
+	}
+	if err := g.resolve(); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	switch format {
+	case Format_Dot:
+		g.writeDot(&sb)
+	case Format_Mermaid:
+		g.writeMermaid(&sb)
+	default:
+		return serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageTemplate("unknown graph format {{format|q}}"),
+			serum.WithDetail("format", string(format)),
+		)
+	}
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return wfapi.ErrorIo("failed to write graph", "", err)
+	}
+	return nil
+}
+
+func dotQuote(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, `\`, `\\`)
+		escaped[i] = strings.ReplaceAll(line, `"`, `\"`)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
+
+func (g *graph) writeDot(sb *strings.Builder) {
+	sb.WriteString("digraph plot {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	writeDotCluster(sb, &g.root, "\t")
+	for _, e := range g.edges {
+		if len(e.label) > 0 {
+			fmt.Fprintf(sb, "\t%s -> %s [label=%s];\n", e.from, e.to, dotQuote(e.label))
+		} else {
+			fmt.Fprintf(sb, "\t%s -> %s;\n", e.from, e.to)
+		}
+	}
+	sb.WriteString("}\n")
+}
+
+func writeDotCluster(sb *strings.Builder, c *cluster, indent string) {
+	for _, n := range c.nodes {
+		var shape string
+		switch n.kind {
+		case nodeKind_Input:
+			shape = "shape=parallelogram"
+		case nodeKind_Step:
+			shape = "shape=box, style=rounded"
+		case nodeKind_Output:
+			shape = "shape=ellipse"
+		}
+		fmt.Fprintf(sb, "%s%s [label=%s, %s];\n", indent, n.id, dotQuote(n.lines), shape)
+	}
+	for _, sub := range c.clusters {
+		fmt.Fprintf(sb, "%ssubgraph %s {\n", indent, sub.id)
+		fmt.Fprintf(sb, "%s\tlabel=%s;\n", indent, dotQuote([]string{sub.label}))
+		writeDotCluster(sb, sub, indent+"\t")
+		fmt.Fprintf(sb, "%s}\n", indent)
+	}
+}
+
+func mermaidQuote(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = strings.ReplaceAll(line, `"`, "#quot;")
+	}
+	return `"` + strings.Join(escaped, "<br/>") + `"`
+}
+
+func (g *graph) writeMermaid(sb *strings.Builder) {
+	sb.WriteString("flowchart LR\n")
+	writeMermaidCluster(sb, &g.root, "\t")
+	for _, e := range g.edges {
+		if len(e.label) > 0 {
+			fmt.Fprintf(sb, "\t%s -->|%s| %s\n", e.from, mermaidQuote(e.label), e.to)
+		} else {
+			fmt.Fprintf(sb, "\t%s --> %s\n", e.from, e.to)
+		}
+	}
+}
+
+func writeMermaidCluster(sb *strings.Builder, c *cluster, indent string) {
+	for _, n := range c.nodes {
+		switch n.kind {
+		case nodeKind_Input:
+			fmt.Fprintf(sb, "%s%s[/%s/]\n", indent, n.id, mermaidQuote(n.lines))
+		case nodeKind_Step:
+			fmt.Fprintf(sb, "%s%s(%s)\n", indent, n.id, mermaidQuote(n.lines))
+		case nodeKind_Output:
+			fmt.Fprintf(sb, "%s%s([%s])\n", indent, n.id, mermaidQuote(n.lines))
+		}
+	}
+	for _, sub := range c.clusters {
+		fmt.Fprintf(sb, "%ssubgraph %s [%s]\n", indent, sub.id, mermaidQuote([]string{sub.label}))
+		writeMermaidCluster(sb, sub, indent+"\t")
+		fmt.Fprintf(sb, "%send\n", indent)
+	}
+}
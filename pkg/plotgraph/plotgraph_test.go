@@ -0,0 +1,124 @@
+package plotgraph
+
+import (
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+const testPlot = `{
+	"plot.v1": {
+		"inputs": {
+			"rootfs": "catalog:warpsys.org/busybox:v1.35.0:amd64-static",
+			"src": "ingest:git:.:HEAD"
+		},
+		"steps": {
+			"build": {
+				"protoformula": {
+					"inputs": {
+						"/": "pipe::rootfs",
+						"/src": "pipe::src",
+						"$MSG": "literal:hello"
+					},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {"out": {"from": "/out", "packtype": "tar"}}
+				}
+			},
+			"package": {
+				"plot": {
+					"inputs": {
+						"rootfs": "pipe::rootfs",
+						"built": "pipe:build:out"
+					},
+					"steps": {
+						"tar": {
+							"protoformula": {
+								"inputs": {
+									"/": "pipe::rootfs",
+									"/in": "pipe::built",
+									"/mnt": "mount:ro:/tmp"
+								},
+								"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+								"outputs": {"pkg": {"from": "/pkg", "packtype": "tar"}}
+							}
+						}
+					},
+					"outputs": {"pkg": "pipe:tar:pkg"}
+				}
+			}
+		},
+		"outputs": {
+			"result": "pipe:package:pkg"
+		}
+	}
+}`
+
+func parsePlot(t *testing.T, serial string) wfapi.Plot {
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(serial), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, plotCapsule.Plot, qt.IsNotNil)
+	return *plotCapsule.Plot
+}
+
+func TestRender(t *testing.T) {
+	plot := parsePlot(t, testPlot)
+	annotations := Annotations{
+		"build": StepAnnotation{
+			Status:    wfapi.StepPlanStatus_Memoized,
+			FormulaID: "zFormula",
+			Outputs: map[wfapi.LocalLabel]wfapi.WareID{
+				"out": {Packtype: "tar", Hash: "abcdefg"},
+			},
+		},
+		"package/tar": StepAnnotation{Status: wfapi.StepPlanStatus_Execute},
+	}
+
+	t.Run("dot", func(t *testing.T) {
+		var sb strings.Builder
+		err := Render(&sb, plot, Format_Dot, annotations)
+		qt.Assert(t, err, qt.IsNil)
+		out := sb.String()
+		qt.Check(t, out, qt.Matches, `(?s)digraph plot \{.*\}\n`)
+		qt.Check(t, out, qt.Contains, `[label="rootfs\ncatalog\ncatalog:warpsys.org/busybox:v1.35.0:amd64-static", shape=parallelogram]`)
+		qt.Check(t, out, qt.Contains, `[label="src\ningest\ngit:.:HEAD", shape=parallelogram]`)
+		qt.Check(t, out, qt.Contains, `[label="$MSG\nliteral\nhello", shape=parallelogram]`)
+		qt.Check(t, out, qt.Contains, `[label="/mnt\nmount\nreadonly:/tmp", shape=parallelogram]`)
+		qt.Check(t, out, qt.Contains, `[label="build\nmemoized\nzFormula", shape=box, style=rounded]`)
+		qt.Check(t, out, qt.Contains, `[label="tar\nexecute", shape=box, style=rounded]`)
+		qt.Check(t, out, qt.Contains, `subgraph cluster_`)
+		qt.Check(t, out, qt.Contains, `[label="out\ntar:abcdefg"]`)
+		qt.Check(t, out, qt.Contains, `[label="result", shape=ellipse]`)
+	})
+	t.Run("mermaid", func(t *testing.T) {
+		var sb strings.Builder
+		err := Render(&sb, plot, Format_Mermaid, nil)
+		qt.Assert(t, err, qt.IsNil)
+		out := sb.String()
+		qt.Check(t, strings.HasPrefix(out, "flowchart LR\n"), qt.IsTrue)
+		qt.Check(t, out, qt.Contains, `("build")`)
+		qt.Check(t, out, qt.Contains, `subgraph cluster_`)
+		qt.Check(t, out, qt.Contains, `["package"]`)
+		qt.Check(t, out, qt.Contains, `(["result"])`)
+		qt.Check(t, out, qt.Contains, `-->|"out"|`)
+	})
+	t.Run("unknown-format", func(t *testing.T) {
+		err := Render(&strings.Builder{}, plot, Format("svg"), nil)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+	})
+	t.Run("bad-pipe", func(t *testing.T) {
+		plot := parsePlot(t, `{"plot.v1": {
+			"inputs": {},
+			"steps": {},
+			"outputs": {"result": "pipe:missing:out"}
+		}}`)
+		err := Render(&strings.Builder{}, plot, Format_Dot, nil)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodePlotInvalid)
+	})
+}
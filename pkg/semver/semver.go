@@ -0,0 +1,251 @@
+/*
+Package semver parses and orders release versions, and matches them against version constraints.
+
+Versions are lenient semver: an optional "v" prefix, one to three numeric components
+(missing components are zero), an optional "-prerelease" suffix, and an optional "+build" suffix,
+which is ignored for ordering. "v1.2", "1.2.0" and "1.2.0+abc" are all the same version.
+
+Constraints are one or more comma separated comparisons, all of which must match:
+
+	^1.2     -- at least 1.2.0, and below 2.0.0 (for 0.x versions, below the next minor)
+	~1.2     -- at least 1.2.0, and below 1.3.0 (or below 2.0.0, for "~1")
+	>=1.2    -- also ">", "<=", "<", and "=", with their usual meanings
+	*        -- any version
+
+Prerelease versions only match a constraint which mentions a prerelease of the same version,
+so "^1.2" does not match "1.3.0-rc1", but ">=1.3.0-rc1" does.
+*/
+package semver
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// Version is a parsed release version.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          []string // The dot separated identifiers of the prerelease suffix, if any.
+}
+
+var reVersion = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+
+// Parse parses a version. Returns false if the string is not a version.
+func Parse(s string) (Version, bool) {
+	v, _, ok := parse(s)
+	return v, ok
+}
+
+// parse is Parse, also returning how many numeric components were given.
+func parse(s string) (Version, int, bool) {
+	m := reVersion.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, 0, false
+	}
+	var v Version
+	components := 0
+	for i, p := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return Version{}, 0, false
+		}
+		*p = n
+		components++
+	}
+	if m[4] != "" {
+		v.Prerelease = strings.Split(m[4], ".")
+	}
+	return v, components, true
+}
+
+// String returns the canonical form of the version, without a "v" prefix.
+func (v Version) String() string {
+	s := strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0, or 1 if a is lower than, equal to, or higher than b,
+// using semver precedence rules.
+func Compare(a, b Version) int {
+	if c := compareInt(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	// a version without a prerelease is higher than any prerelease of it
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := compareIdentifier(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a.Prerelease), len(b.Prerelease))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compares prerelease identifiers: numeric identifiers are compared numerically,
+// and are lower than alphanumeric ones, which are compared lexically.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// IsConstraint returns true if a release name is a constraint rather than the name of a release.
+// Constraints start with one of "^~<>=*", which release names do not.
+func IsConstraint(s string) bool {
+	return s != "" && strings.ContainsRune("^~<>=*", rune(s[0]))
+}
+
+// Constraint is a parsed version constraint.
+type Constraint struct {
+	raw         string
+	comparisons []comparison
+}
+
+type comparison struct {
+	op      string // one of ">=", ">", "<=", "<", "=".
+	version Version
+}
+
+// ParseConstraint parses a version constraint, as described in the package documentation.
+//
+// Errors:
+//
+//    - warpforge-error-release-constraint -- when the constraint is not valid
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: s}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" {
+			continue
+		}
+		op := ""
+		for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				break
+			}
+		}
+		versionStr := strings.TrimSpace(strings.TrimPrefix(part, op))
+		v, components, ok := parse(versionStr)
+		if op == "" || !ok {
+			return Constraint{}, serum.Error(wfapi.ECodeReleaseConstraint,
+				serum.WithMessageTemplate("invalid version constraint {{constraint|q}}: {{part|q}} is not a comparison with a version"),
+				serum.WithDetail("constraint", s),
+				serum.WithDetail("part", part),
+			)
+		}
+		switch op {
+		case "^":
+			upper := Version{Major: v.Major + 1}
+			switch {
+			case v.Major == 0 && v.Minor == 0 && components == 3:
+				upper = Version{Patch: v.Patch + 1}
+			case v.Major == 0 && components >= 2:
+				upper = Version{Minor: v.Minor + 1}
+			}
+			c.comparisons = append(c.comparisons, comparison{">=", v}, comparison{"<", lowest(upper)})
+		case "~":
+			upper := Version{Major: v.Major, Minor: v.Minor + 1}
+			if components == 1 {
+				upper = Version{Major: v.Major + 1}
+			}
+			c.comparisons = append(c.comparisons, comparison{">=", v}, comparison{"<", lowest(upper)})
+		default:
+			c.comparisons = append(c.comparisons, comparison{op, v})
+		}
+	}
+	return c, nil
+}
+
+// lowest returns the lowest prerelease of a version, so that "<2.0.0" also excludes prereleases of 2.0.0.
+func lowest(v Version) Version {
+	v.Prerelease = []string{"0"}
+	return v
+}
+
+// String returns the constraint as it was parsed.
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Match returns true if the version satisfies every comparison in the constraint.
+func (c Constraint) Match(v Version) bool {
+	if len(v.Prerelease) > 0 && !c.allowsPrerelease(v) {
+		return false
+	}
+	for _, cmp := range c.comparisons {
+		r := Compare(v, cmp.version)
+		var ok bool
+		switch cmp.op {
+		case ">=":
+			ok = r >= 0
+		case ">":
+			ok = r > 0
+		case "<=":
+			ok = r <= 0
+		case "<":
+			ok = r < 0
+		case "=":
+			ok = r == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// A prerelease version is only allowed if a comparison's version is a prerelease of the same version.
+// The "lowest" bounds made for "^" and "~" don't count.
+func (c Constraint) allowsPrerelease(v Version) bool {
+	for _, cmp := range c.comparisons {
+		if len(cmp.version.Prerelease) == 0 || (cmp.op == "<" && len(cmp.version.Prerelease) == 1 && cmp.version.Prerelease[0] == "0") {
+			continue
+		}
+		if cmp.version.Major == v.Major && cmp.version.Minor == v.Minor && cmp.version.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
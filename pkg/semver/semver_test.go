@@ -0,0 +1,79 @@
+package semver
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"1.2.3", "1.2.3"},
+		{"v1.2", "1.2.0"},
+		{"V2", "2.0.0"},
+		{"1.2.3-rc.1+build.5", "1.2.3-rc.1"},
+	} {
+		v, ok := Parse(tc.input)
+		qt.Check(t, ok, qt.IsTrue, qt.Commentf("%s", tc.input))
+		qt.Check(t, v.String(), qt.Equals, tc.expected)
+	}
+	for _, input := range []string{"", "latest", "1.2.3.4", "v", "1.x"} {
+		_, ok := Parse(input)
+		qt.Check(t, ok, qt.IsFalse, qt.Commentf("%s", input))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// in ascending order
+	ordered := []string{"0.9", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.2", "v1.10.0", "2"}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := Parse(ordered[i])
+			b, _ := Parse(ordered[j])
+			expected := compareInt(i, j)
+			qt.Check(t, Compare(a, b), qt.Equals, expected, qt.Commentf("%s vs %s", ordered[i], ordered[j]))
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"^1.2", []string{"1.2.0", "v1.9.9"}, []string{"1.1.9", "2.0.0", "2.0.0-rc1", "1.3.0-rc1"}},
+		{"^0.2", []string{"0.2.0", "0.2.9"}, []string{"0.3.0", "0.1.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2", []string{"1.2.0", "1.2.7"}, []string{"1.3.0", "1.1.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{">=1.2, <1.5", []string{"1.2.0", "1.4.9"}, []string{"1.5.0", "1.1.0"}},
+		{">1.2", []string{"1.2.1"}, []string{"1.2.0"}},
+		{"=1.2", []string{"v1.2.0"}, []string{"1.2.1"}},
+		{">=1.3.0-rc1", []string{"1.3.0-rc2", "1.3.0", "1.4.0"}, []string{"1.3.0-rc0", "1.4.0-rc1"}},
+		{"*", []string{"0.0.1", "100.0.0"}, []string{"1.0.0-rc1"}},
+	} {
+		c, err := ParseConstraint(tc.constraint)
+		qt.Assert(t, err, qt.IsNil)
+		for _, s := range tc.match {
+			v, _ := Parse(s)
+			qt.Check(t, c.Match(v), qt.IsTrue, qt.Commentf("%s should match %s", tc.constraint, s))
+		}
+		for _, s := range tc.noMatch {
+			v, _ := Parse(s)
+			qt.Check(t, c.Match(v), qt.IsFalse, qt.Commentf("%s should not match %s", tc.constraint, s))
+		}
+	}
+	for _, s := range []string{"^", "^1.x", "1.2", ">=1.2,", "!1.2"} {
+		_, err := ParseConstraint(s)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeReleaseConstraint, qt.Commentf("%s", s))
+	}
+	qt.Check(t, IsConstraint("^1.2"), qt.IsTrue)
+	qt.Check(t, IsConstraint("v1.2"), qt.IsFalse)
+}
@@ -21,6 +21,7 @@ const (
 	AttrValueExecNameGit           = "git"
 	AttrValueExecNameRunc          = "runc"
 	AttrValueExecOperationGitClone = "clone"
+	AttrValueExecOperationGitFetch = "fetch"
 	AttrValueExecOperationGitLs    = "ls"
 )
 
@@ -30,5 +31,6 @@ var (
 	AttrFullExecNameGit           = attribute.String(AttrKeyWarpforgeExecName, AttrValueExecNameGit)
 	AttrFullExecNameRunc          = attribute.String(AttrKeyWarpforgeExecName, AttrValueExecNameRunc)
 	AttrFullExecOperationGitClone = attribute.String(AttrKeyWarpforgeExecOperation, AttrValueExecOperationGitClone)
+	AttrFullExecOperationGitFetch = attribute.String(AttrKeyWarpforgeExecOperation, AttrValueExecOperationGitFetch)
 	AttrFullExecOperationGitLs    = attribute.String(AttrKeyWarpforgeExecOperation, AttrValueExecOperationGitLs)
 )
@@ -16,6 +16,7 @@ import (
 	"github.com/ipld/go-ipld-prime/codec/json"
 	"github.com/serum-errors/go-serum"
 
+	"github.com/warptools/warpforge/pkg/semver"
 	"github.com/warptools/warpforge/wfapi"
 )
 
@@ -201,7 +202,7 @@ func (cat *Catalog) GetRelease(ref wfapi.CatalogRef) (*wfapi.CatalogRelease, err
 	release := wfapi.CatalogRelease{}
 	_, errRaw = ipld.Unmarshal(releaseBytes, json.Decode, &release, wfapi.TypeSystem.TypeByName("CatalogRelease"))
 	if errRaw != nil {
-		return nil, wfapi.ErrorCatalogParse(releasePath, err)
+		return nil, wfapi.ErrorCatalogParse(releasePath, errRaw)
 	}
 
 	// ensure this matches the expected value
@@ -214,6 +215,7 @@ func (cat *Catalog) GetRelease(ref wfapi.CatalogRef) (*wfapi.CatalogRelease, err
 }
 
 // Get a ware from a given catalog.
+// The WarehouseAddr returned is the first of the ware's mirrors, as by GetWareMirrors, or nil if it has none.
 //
 // Errors:
 //
@@ -222,6 +224,26 @@ func (cat *Catalog) GetRelease(ref wfapi.CatalogRef) (*wfapi.CatalogRelease, err
 //     - warpforge-error-catalog-invalid -- when catalog files are not found
 //     - warpforge-error-catalog-missing-entry -- when catalog item is not found
 func (cat *Catalog) GetWare(ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi.WarehouseAddr, error) {
+	wareId, addrs, err := cat.GetWareMirrors(ref)
+	if err != nil || len(addrs) == 0 {
+		return wareId, nil, err
+	}
+	return wareId, &addrs[0], nil
+}
+
+// Get a ware from a given catalog, along with every mirror address the catalog gives for it,
+// in the order they should be tried:
+// first the ByWare mirrors for the WareID, then the ByModule mirrors for the ware's packtype.
+// Note that a ByModule mirror may not actually contain the ware.
+// Returns a nil WareID if the release does not exist.
+//
+// Errors:
+//
+//     - warpforge-error-io -- when reading of lineage or mirror files fails
+//     - warpforge-error-catalog-parse -- when ipld parsing of lineage or mirror files fails
+//     - warpforge-error-catalog-invalid -- when catalog files are not found
+//     - warpforge-error-catalog-missing-entry -- when catalog item is not found
+func (cat *Catalog) GetWareMirrors(ref wfapi.CatalogRef) (*wfapi.WareID, []wfapi.WarehouseAddr, error) {
 	release, err := cat.GetRelease(ref)
 	if err != nil {
 		return nil, nil, err
@@ -238,41 +260,33 @@ func (cat *Catalog) GetWare(ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi.Warehou
 		return nil, nil, wfapi.ErrorMissingCatalogEntry(ref, false)
 	}
 
-	// item found, check for a matching mirror
+	// item found, check for matching mirrors
 	mirror, err := cat.GetMirror(ref)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	// resolve which WarehouseAddr will get returned for this particular ware
-	// this is done by first looking for a specific ByWare mirror for the WareID
-	// if none exists, the ByModule address is returned if it exists
-	// TODO: handling of multiple mirrors
 	if mirror == nil {
-		// no mirror exists at all, return nil
+		// no mirror exists at all
 		return &wareId, nil, nil
 	}
 
-	// check ByWare for a matching WareId
-	if mirror.ByWare != nil {
-		if addrs, exists := mirror.ByWare.Values[wareId]; exists {
-			// match found, return it
-			return &wareId, &addrs[0], nil
+	var addrs []wfapi.WarehouseAddr
+	seen := map[wfapi.WarehouseAddr]bool{}
+	add := func(candidates []wfapi.WarehouseAddr) {
+		for _, addr := range candidates {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
 		}
 	}
-
-	// check if we can return a ByModule WarehouseAddress
-	// note, this address may not actually contain the ware we're looking for
+	if mirror.ByWare != nil {
+		add(mirror.ByWare.Values[wareId])
+	}
 	if mirror.ByModule != nil {
-		if len(mirror.ByModule.Values[ref.ModuleName].Values[wareId.Packtype]) > 0 {
-			return &wareId, &mirror.ByModule.Values[ref.ModuleName].Values[wareId.Packtype][0], nil
-		}
-		return &wareId, nil, nil
+		add(mirror.ByModule.Values[ref.ModuleName].Values[wareId.Packtype])
 	}
-
-	// we have exhausted our options, no mirror exists
-	// this is not an error, but we will return a nil WarehouseAddr
-	return &wareId, nil, nil
+	return &wareId, addrs, nil
 }
 
 // Get a catalog mirror for a given catalog reference.
@@ -353,7 +367,7 @@ func (cat *Catalog) GetModule(ref wfapi.CatalogRef) (*wfapi.CatalogModule, error
 //    - warpforge-error-catalog-parse -- when parsing of the lineage file fails
 //    - warpforge-error-io -- when reading or writing the lineage file fails
 //    - warpforge-error-serialization -- when serializing the lineage fails
-//    - warpforge-error-catalog-invalid -- when an error occurs while searching for module or release
+//    - warpforge-error-catalog-invalid -- when an error occurs while searching for module or release, or the release name is a version constraint
 //    - warpforge-error-already-exists -- when trying to insert an already existing item
 func (cat *Catalog) AddItem(
 	ref wfapi.CatalogRef,
@@ -364,6 +378,10 @@ func (cat *Catalog) AddItem(
 	moduleFilePath := filepath.Join("/", cat.moduleFilePath(ref))
 	releaseFilePath := filepath.Join("/", cat.releaseFilePath(ref))
 	releasesPath := filepath.Dir(releaseFilePath)
+	if semver.IsConstraint(string(ref.ReleaseName)) {
+		return wfapi.ErrorCatalogInvalid(releaseFilePath,
+			fmt.Sprintf("release name %q would be mistaken for a version constraint", ref.ReleaseName))
+	}
 
 	// attempt to load the release
 	release, err := cat.GetRelease(ref)
@@ -478,6 +496,236 @@ func (cat *Catalog) AddItem(
 	return nil
 }
 
+// Adds a complete release to the catalog, along with its replay if it has one.
+// Unlike AddItem, the release is written exactly as given, so its CID and metadata are preserved.
+// The module gives the module name, and the metadata to use if the module does not exist yet;
+// its list of releases is ignored.
+// Adding a release which is already present and identical does nothing.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-parse -- when parsing of existing catalog files fails
+//    - warpforge-error-catalog-invalid -- when the replay does not match the release, or the release name is a version constraint
+//    - warpforge-error-already-exists -- when a different release with the same name exists and overwrite is not set
+//    - warpforge-error-io -- when reading or writing catalog files fails
+//    - warpforge-error-serialization -- when serializing the release, module or replay fails
+func (cat *Catalog) AddRelease(
+	module wfapi.CatalogModule,
+	release wfapi.CatalogRelease,
+	replay *wfapi.Plot,
+	overwrite bool) error {
+
+	ref := wfapi.CatalogRef{ModuleName: module.Name, ReleaseName: release.ReleaseName}
+	moduleFilePath := filepath.Join("/", cat.moduleFilePath(ref))
+	releaseFilePath := filepath.Join("/", cat.releaseFilePath(ref))
+	if semver.IsConstraint(string(ref.ReleaseName)) {
+		return wfapi.ErrorCatalogInvalid(releaseFilePath,
+			fmt.Sprintf("release name %q would be mistaken for a version constraint", ref.ReleaseName))
+	}
+
+	replayCid, hasReplay := release.Metadata.Values["replay"]
+	if replay != nil && (!hasReplay || replay.Cid() != wfapi.PlotCID(replayCid)) {
+		return wfapi.ErrorCatalogInvalid(releaseFilePath,
+			fmt.Sprintf("replay with CID %q does not match release replay %q", replay.Cid(), replayCid))
+	}
+
+	existing, err := cat.GetModule(ref)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		existing = &wfapi.CatalogModule{
+			Name:     module.Name,
+			Metadata: module.Metadata,
+		}
+		existing.Releases.Values = map[wfapi.ReleaseName]wfapi.CatalogReleaseCID{}
+	}
+	existingCid, releaseExists := existing.Releases.Values[release.ReleaseName]
+	if releaseExists && existingCid == release.Cid() {
+		return nil
+	}
+	if releaseExists && !overwrite {
+		return serum.Error(wfapi.ECodeAlreadyExists,
+			serum.WithMessageTemplate("release {{release|q}} already exists in {{path|q}} with different contents"),
+			serum.WithDetail("release", string(release.ReleaseName)),
+			serum.WithDetail("path", releaseFilePath),
+		)
+	}
+	if !releaseExists {
+		existing.Releases.Keys = append(existing.Releases.Keys, release.ReleaseName)
+	}
+	existing.Releases.Values[release.ReleaseName] = release.Cid()
+
+	// sort the release list
+	releaseList := []string{}
+	for _, r := range existing.Releases.Keys {
+		releaseList = append(releaseList, string(r))
+	}
+	natsort.Sort(releaseList)
+	existing.Releases.Keys = []wfapi.ReleaseName{}
+	for _, r := range releaseList {
+		existing.Releases.Keys = append(existing.Releases.Keys, wfapi.ReleaseName(r))
+	}
+
+	// serialize everything before writing anything
+	modCapsule := wfapi.CatalogModuleCapsule{CatalogModule: existing}
+	moduleSerial, errRaw := ipld.Marshal(json.Encode, &modCapsule, wfapi.TypeSystem.TypeByName("CatalogModuleCapsule"))
+	if errRaw != nil {
+		return wfapi.ErrorSerialization("failed to serialize module", errRaw)
+	}
+	releaseSerial, errRaw := ipld.Marshal(json.Encode, &release, wfapi.TypeSystem.TypeByName("CatalogRelease"))
+	if errRaw != nil {
+		return wfapi.ErrorSerialization("failed to serialize release", errRaw)
+	}
+
+	// the replay is written first, so the release never points at a missing replay
+	if replay != nil {
+		replayPath := filepath.Join(filepath.Dir(moduleFilePath), "_replays", replayCid+".json")
+		plotCapsule := wfapi.PlotCapsule{Plot: replay}
+		replaySerial, errRaw := ipld.Marshal(json.Encode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+		if errRaw != nil {
+			return wfapi.ErrorSerialization("failed to serialize replay", errRaw)
+		}
+		if errRaw := os.MkdirAll(filepath.Dir(replayPath), 0755); errRaw != nil {
+			return wfapi.ErrorIo("failed to create replays directory", filepath.Dir(replayPath), errRaw)
+		}
+		if errRaw := os.WriteFile(replayPath, replaySerial, 0644); errRaw != nil {
+			return wfapi.ErrorIo("failed to write replay file", replayPath, errRaw)
+		}
+	}
+	if errRaw := os.MkdirAll(filepath.Dir(releaseFilePath), 0755); errRaw != nil {
+		return wfapi.ErrorIo("failed to create releases directory", filepath.Dir(releaseFilePath), errRaw)
+	}
+	if errRaw := os.WriteFile(releaseFilePath, releaseSerial, 0644); errRaw != nil {
+		return wfapi.ErrorIo("failed to write release file", releaseFilePath, errRaw)
+	}
+	if errRaw := os.WriteFile(moduleFilePath, moduleSerial, 0644); errRaw != nil {
+		return wfapi.ErrorIo("failed to write module file", moduleFilePath, errRaw)
+	}
+	if err := cat.updateModuleList(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Sets the status of a release, marking it as yanked or deprecated, or clears it if status is nil.
+// The replacement named by the status, if any, must be another release of the same module in this catalog.
+// Since the status is part of the release, this changes the release CID,
+// so any existing signatures of the release must be renewed.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-missing-entry -- when the release or its replacement does not exist
+//    - warpforge-error-catalog-invalid -- when the release does not match its CID, or is its own replacement
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-io -- when reading or writing catalog files fails
+//    - warpforge-error-serialization -- when serializing the release or module fails
+func (cat *Catalog) SetReleaseStatus(ref wfapi.CatalogRef, status *wfapi.ReleaseStatus) error {
+	release, err := cat.GetRelease(ref)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return wfapi.ErrorMissingCatalogEntry(ref, false)
+	}
+	if status != nil && status.Replacement != nil {
+		replacementRef := wfapi.CatalogRef{ModuleName: ref.ModuleName, ReleaseName: *status.Replacement}
+		if replacementRef.ReleaseName == ref.ReleaseName {
+			return wfapi.ErrorCatalogInvalid(filepath.Join("/", cat.releaseFilePath(ref)),
+				fmt.Sprintf("release %q cannot be its own replacement", ref.ReleaseName))
+		}
+		replacement, err := cat.GetRelease(replacementRef)
+		if err != nil {
+			return err
+		}
+		if replacement == nil {
+			return wfapi.ErrorMissingCatalogEntry(replacementRef, false)
+		}
+	}
+	release.Status = status
+	// AddRelease keeps the module as it is, only updating the release CID.
+	return cat.AddRelease(wfapi.CatalogModule{Name: ref.ModuleName}, *release, nil, true)
+}
+
+// Sets a metadata value of a release, adding the key if the release doesn't have it yet.
+// The "replay" key can't be set this way, since it must match the replay file; use AddReplay instead.
+// As with SetReleaseStatus, this changes the release CID, so any existing signatures of the release must be renewed.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when the key is "replay", or the value of a well-known key is invalid
+//    - warpforge-error-catalog-missing-entry -- when the release does not exist
+//    - warpforge-error-catalog-invalid -- when the release does not match its CID
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-io -- when reading or writing catalog files fails
+//    - warpforge-error-serialization -- when serializing the release or module fails
+func (cat *Catalog) SetReleaseMetadata(ref wfapi.CatalogRef, key string, value string) error {
+	if key == "replay" {
+		return serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageLiteral("the replay metadata of a release can only be set by adding a replay"),
+		)
+	}
+	if err := ValidateMetadataValue(key, value); err != nil {
+		return err
+	}
+	release, err := cat.GetRelease(ref)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return wfapi.ErrorMissingCatalogEntry(ref, false)
+	}
+	if release.Metadata.Values == nil {
+		release.Metadata.Values = map[string]string{}
+	}
+	if _, exists := release.Metadata.Values[key]; !exists {
+		release.Metadata.Keys = append(release.Metadata.Keys, key)
+	}
+	release.Metadata.Values[key] = value
+	return cat.AddRelease(wfapi.CatalogModule{Name: ref.ModuleName}, *release, nil, true)
+}
+
+// Sets a metadata value of a module, adding the key if the module doesn't have it yet.
+// Module metadata isn't part of any release, so release CIDs and signatures are unaffected.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when the value of a well-known key is invalid
+//    - warpforge-error-catalog-missing-entry -- when the module does not exist
+//    - warpforge-error-catalog-parse -- when parsing the module fails
+//    - warpforge-error-io -- when reading or writing the module file fails
+//    - warpforge-error-serialization -- when serializing the module fails
+func (cat *Catalog) SetModuleMetadata(ref wfapi.CatalogRef, key string, value string) error {
+	if err := ValidateMetadataValue(key, value); err != nil {
+		return err
+	}
+	module, err := cat.GetModule(ref)
+	if err != nil {
+		return err
+	}
+	if module == nil {
+		return wfapi.ErrorMissingCatalogEntry(wfapi.CatalogRef{ModuleName: ref.ModuleName}, false)
+	}
+	if module.Metadata.Values == nil {
+		module.Metadata.Values = map[string]string{}
+	}
+	if _, exists := module.Metadata.Values[key]; !exists {
+		module.Metadata.Keys = append(module.Metadata.Keys, key)
+	}
+	module.Metadata.Values[key] = value
+
+	moduleFilePath := filepath.Join("/", cat.moduleFilePath(ref))
+	modCapsule := wfapi.CatalogModuleCapsule{CatalogModule: module}
+	moduleSerial, errRaw := ipld.Marshal(json.Encode, &modCapsule, wfapi.TypeSystem.TypeByName("CatalogModuleCapsule"))
+	if errRaw != nil {
+		return wfapi.ErrorSerialization("failed to serialize module", errRaw)
+	}
+	if errRaw := os.WriteFile(moduleFilePath, moduleSerial, 0644); errRaw != nil {
+		return wfapi.ErrorIo("failed to write module file", moduleFilePath, errRaw)
+	}
+	return nil
+}
+
 // Adds a ByWare mirror to a catalog entry
 //
 // Errors:
@@ -692,7 +940,7 @@ func (cat *Catalog) GetReplay(ref wfapi.CatalogRef) (*wfapi.Plot, error) {
 	replayCapsule := wfapi.ReplayCapsule{}
 	_, errRaw = ipld.Unmarshal(replayBytes, json.Decode, &replayCapsule, wfapi.TypeSystem.TypeByName("ReplayCapsule"))
 	if errRaw != nil {
-		return nil, wfapi.ErrorCatalogParse(replayPath, err)
+		return nil, wfapi.ErrorCatalogParse(replayPath, errRaw)
 	}
 	if replayCapsule.Plot == nil {
 		return nil, wfapi.ErrorCatalogParse(replayPath, fmt.Errorf("no v1 Plot in ReplayCapsule"))
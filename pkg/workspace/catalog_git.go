@@ -0,0 +1,204 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// OpenCatalogAtRevision opens a catalog as it was at a revision of the git repository containing it,
+// such as "HEAD~1", a tag, or a commit hash.
+// The path is the catalog's path on the local filesystem, which must be within a git repository's worktree.
+// The catalog is read from the git object store, so it's unaffected by uncommitted changes,
+// and it must not be written to.
+//
+// Errors:
+//
+//    - warpforge-error-git -- when the repository or revision cannot be found
+//    - warpforge-error-io -- when building the module list fails
+//    - warpforge-error-catalog-invalid -- when the catalog cannot be opened
+func OpenCatalogAtRevision(path string, revision string) (Catalog, error) {
+	repo, err := git.PlainOpenWithOptions(path, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return Catalog{}, wfapi.ErrorGit("failed to open git repository for catalog at "+path, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return Catalog{}, wfapi.ErrorGit("failed to open git worktree for catalog at "+path, err)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return Catalog{}, wfapi.ErrorGit("failed to resolve revision "+revision, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return Catalog{}, wfapi.ErrorGit("failed to read commit "+hash.String(), err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return Catalog{}, wfapi.ErrorGit("failed to read tree of commit "+hash.String(), err)
+	}
+
+	// the catalog may be a subdirectory of the repository
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Catalog{}, wfapi.ErrorIo("failed to get absolute path of catalog", path, err)
+	}
+	rel, err := filepath.Rel(wt.Filesystem.Root(), absPath)
+	if err == nil && strings.HasPrefix(rel, "..") {
+		err = fmt.Errorf("%q is outside of %q", absPath, wt.Filesystem.Root())
+	}
+	if err != nil {
+		return Catalog{}, wfapi.ErrorIo("catalog is not within its git worktree", path, err)
+	}
+	return OpenCatalog(gitTreeFS{tree: tree}, filepath.ToSlash(rel))
+}
+
+// gitTreeFS is a read-only fs.FS of the files in a git tree.
+type gitTreeFS struct {
+	tree *object.Tree
+}
+
+func (gfs gitTreeFS) Open(name string) (fs.File, error) {
+	info, tree, err := gfs.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if tree != nil {
+		return &gitTreeDir{info: info, tree: tree}, nil
+	}
+	file, err := gfs.tree.File(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &gitTreeFile{info: info, Reader: bytes.NewReader([]byte(contents))}, nil
+}
+
+func (gfs gitTreeFS) Stat(name string) (fs.FileInfo, error) {
+	info, _, err := gfs.lookup("stat", name)
+	return info, err
+}
+
+func (gfs gitTreeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	_, tree, err := gfs.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return readTreeDir(tree), nil
+}
+
+// lookup finds a path in the tree, returning the subtree as well if it's a directory.
+func (gfs gitTreeFS) lookup(op string, name string) (fs.FileInfo, *object.Tree, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return gitFileInfo{name: ".", mode: fs.ModeDir | 0555}, gfs.tree, nil
+	}
+	entry, err := gfs.tree.FindEntry(name)
+	if err != nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode == filemode.Dir {
+		tree, err := gfs.tree.Tree(name)
+		if err != nil {
+			return nil, nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		return gitFileInfo{name: entry.Name, mode: fs.ModeDir | 0555}, tree, nil
+	}
+	size, err := gfs.tree.Size(name)
+	if err != nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return gitFileInfo{name: entry.Name, size: size, mode: 0444}, nil, nil
+}
+
+func readTreeDir(tree *object.Tree) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		var mode fs.FileMode = 0444
+		switch e.Mode {
+		case filemode.Dir:
+			mode = fs.ModeDir | 0555
+		case filemode.Submodule, filemode.Symlink:
+			mode = fs.ModeIrregular
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(gitFileInfo{name: e.Name, mode: mode}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
+
+type gitFileInfo struct {
+	name string
+	size int64
+	mode fs.FileMode
+}
+
+func (fi gitFileInfo) Name() string       { return fi.name }
+func (fi gitFileInfo) Size() int64        { return fi.size }
+func (fi gitFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi gitFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi gitFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi gitFileInfo) Sys() interface{}   { return nil }
+
+type gitTreeFile struct {
+	info fs.FileInfo
+	*bytes.Reader
+}
+
+func (f *gitTreeFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *gitTreeFile) Close() error               { return nil }
+
+type gitTreeDir struct {
+	info    fs.FileInfo
+	tree    *object.Tree
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *gitTreeDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *gitTreeDir) Close() error               { return nil }
+func (d *gitTreeDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *gitTreeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		d.entries = readTreeDir(d.tree)
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
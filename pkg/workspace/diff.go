@@ -0,0 +1,232 @@
+package workspace
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/facette/natsort"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// CatalogDiffEntry is a single difference between two catalogs.
+type CatalogDiffEntry struct {
+	Kind wfapi.CatalogDiffKind
+	// Ref is the module which changed, with the release and item names set where the change is to one of those.
+	Ref wfapi.CatalogRef
+	// Old and New are the WareIDs for item changes, and descriptions of the mirror for mirror changes.
+	// Only the side which exists is set; for other kinds of change, both are empty.
+	Old string
+	New string
+}
+
+// DiffCatalogs compares two catalogs, returning what changed from the old catalog to the new one,
+// ordered by module, then release, then item.
+//
+// Added and removed modules are reported without listing their releases.
+// For releases in both catalogs with different CIDs, the items are compared;
+// if the items are the same, the release is reported as changed, since only its metadata or status can differ.
+// Mirrors are compared for every module in either catalog.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a release does not match its CID
+func DiffCatalogs(old, new *Catalog) ([]CatalogDiffEntry, error) {
+	var result []CatalogDiffEntry
+	for _, moduleName := range unionModuleNames(old.Modules(), new.Modules()) {
+		ref := wfapi.CatalogRef{ModuleName: moduleName}
+		oldModule, err := old.GetModule(ref)
+		if err != nil {
+			return nil, err
+		}
+		newModule, err := new.GetModule(ref)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case oldModule == nil && newModule != nil:
+			result = append(result, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ModuleAdded, Ref: ref})
+		case oldModule != nil && newModule == nil:
+			result = append(result, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ModuleRemoved, Ref: ref})
+		case oldModule != nil && newModule != nil:
+			entries, err := diffReleases(old, new, oldModule, newModule)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, entries...)
+		}
+
+		entries, err := diffMirrors(old, new, ref)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entries...)
+	}
+	return result, nil
+}
+
+// Compares the releases of a module which is in both catalogs, for DiffCatalogs.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading release files fails
+//    - warpforge-error-catalog-parse -- when parsing release files fails
+//    - warpforge-error-catalog-invalid -- when a release does not match its CID
+func diffReleases(old, new *Catalog, oldModule, newModule *wfapi.CatalogModule) ([]CatalogDiffEntry, error) {
+	var result []CatalogDiffEntry
+	// the same order modules keep their releases in
+	var releaseList []string
+	for _, name := range oldModule.Releases.Keys {
+		releaseList = append(releaseList, string(name))
+	}
+	for _, name := range newModule.Releases.Keys {
+		if _, ok := oldModule.Releases.Values[name]; !ok {
+			releaseList = append(releaseList, string(name))
+		}
+	}
+	natsort.Sort(releaseList)
+
+	for _, r := range releaseList {
+		name := wfapi.ReleaseName(r)
+		ref := wfapi.CatalogRef{ModuleName: newModule.Name, ReleaseName: name}
+		oldCid, inOld := oldModule.Releases.Values[name]
+		newCid, inNew := newModule.Releases.Values[name]
+		switch {
+		case !inOld:
+			result = append(result, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ReleaseAdded, Ref: ref})
+			continue
+		case !inNew:
+			result = append(result, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ReleaseRemoved, Ref: ref})
+			continue
+		case oldCid == newCid:
+			continue
+		}
+
+		oldRelease, err := old.GetRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		newRelease, err := new.GetRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		if oldRelease == nil || newRelease == nil {
+			// the module lists a release without a file; fsck reports that, there's nothing to compare.
+			continue
+		}
+		var items []CatalogDiffEntry
+		for _, item := range oldRelease.Items.Keys {
+			ref.ItemName = item
+			oldWare := oldRelease.Items.Values[item]
+			newWare, ok := newRelease.Items.Values[item]
+			switch {
+			case !ok:
+				items = append(items, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ItemRemoved, Ref: ref, Old: oldWare.String()})
+			case newWare != oldWare:
+				items = append(items, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ItemChanged, Ref: ref, Old: oldWare.String(), New: newWare.String()})
+			}
+		}
+		for _, item := range newRelease.Items.Keys {
+			if _, ok := oldRelease.Items.Values[item]; !ok {
+				ref.ItemName = item
+				items = append(items, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ItemAdded, Ref: ref, New: newRelease.Items.Values[item].String()})
+			}
+		}
+		if len(items) == 0 {
+			ref.ItemName = ""
+			items = append(items, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_ReleaseChanged, Ref: ref})
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Ref.ItemName < items[j].Ref.ItemName
+		})
+		result = append(result, items...)
+	}
+	return result, nil
+}
+
+// Compares the mirrors of a module, for DiffCatalogs.
+// A module missing from one of the catalogs is treated as having no mirrors there.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading mirror files fails
+//    - warpforge-error-catalog-parse -- when parsing mirror files fails
+func diffMirrors(old, new *Catalog, ref wfapi.CatalogRef) ([]CatalogDiffEntry, error) {
+	oldMirrors, err := old.GetMirror(ref)
+	if err != nil {
+		return nil, err
+	}
+	newMirrors, err := new.GetMirror(ref)
+	if err != nil {
+		return nil, err
+	}
+	oldSet := describeMirrors(oldMirrors)
+	newSet := describeMirrors(newMirrors)
+
+	var result []CatalogDiffEntry
+	for _, m := range oldSet {
+		if !containsString(newSet, m) {
+			result = append(result, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_MirrorRemoved, Ref: ref, Old: m})
+		}
+	}
+	for _, m := range newSet {
+		if !containsString(oldSet, m) {
+			result = append(result, CatalogDiffEntry{Kind: wfapi.CatalogDiffKind_MirrorAdded, Ref: ref, New: m})
+		}
+	}
+	return result, nil
+}
+
+// describeMirrors lists every mirror address as a string saying what it's a mirror for,
+// e.g. "tar:abcd at https://example.com/" or "all tar wares of example.com/foo at https://example.com/".
+func describeMirrors(mirrors *wfapi.CatalogMirrors) []string {
+	var result []string
+	if mirrors == nil {
+		return result
+	}
+	if mirrors.ByWare != nil {
+		for _, wareId := range mirrors.ByWare.Keys {
+			for _, addr := range mirrors.ByWare.Values[wareId] {
+				result = append(result, fmt.Sprintf("%s at %s", wareId.String(), addr))
+			}
+		}
+	}
+	if mirrors.ByModule != nil {
+		for _, moduleName := range mirrors.ByModule.Keys {
+			byPacktype := mirrors.ByModule.Values[moduleName]
+			for _, packtype := range byPacktype.Keys {
+				for _, addr := range byPacktype.Values[packtype] {
+					result = append(result, fmt.Sprintf("all %s wares of %s at %s", packtype, moduleName, addr))
+				}
+			}
+		}
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unionModuleNames returns every module name in either list, sorted.
+func unionModuleNames(a, b []wfapi.ModuleName) []wfapi.ModuleName {
+	seen := make(map[wfapi.ModuleName]struct{}, len(a)+len(b))
+	var result []wfapi.ModuleName
+	for _, list := range [][]wfapi.ModuleName{a, b} {
+		for _, name := range list {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
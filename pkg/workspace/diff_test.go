@@ -0,0 +1,77 @@
+package workspace
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestDiffCatalogs(t *testing.T) {
+	path := t.TempDir()
+	repo, err := git.PlainInit(path, false)
+	qt.Assert(t, err, qt.IsNil)
+	wt, err := repo.Worktree()
+	qt.Assert(t, err, qt.IsNil)
+	commit := func() {
+		_, err := wt.Add(".")
+		qt.Assert(t, err, qt.IsNil)
+		_, err = wt.Commit("update", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		qt.Assert(t, err, qt.IsNil)
+	}
+	open := func() Catalog {
+		cat, err := OpenCatalog(os.DirFS("/"), path[1:])
+		qt.Assert(t, err, qt.IsNil)
+		return cat
+	}
+	addItem := func(module wfapi.ModuleName, release wfapi.ReleaseName, item wfapi.ItemLabel, hash string) {
+		cat := open()
+		ref := wfapi.CatalogRef{ModuleName: module, ReleaseName: release, ItemName: item}
+		qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: hash}, true), qt.IsNil)
+	}
+
+	addItem("example.com/gcc", "v1", "amd64", "aaaa")
+	addItem("example.com/gcc", "v1", "arm64", "bbbb")
+	addItem("example.com/gcc", "v2", "amd64", "cccc")
+	addItem("example.com/old", "v1", "amd64", "dddd")
+	commit()
+
+	addItem("example.com/gcc", "v1", "amd64", "eeee")
+	addItem("example.com/gcc", "v3", "amd64", "ffff")
+	addItem("example.com/new", "v1", "amd64", "gggg")
+	cat := open()
+	qt.Assert(t, cat.SetReleaseStatus(wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: "v2"},
+		&wfapi.ReleaseStatus{Kind: wfapi.ReleaseStatusKind_Deprecated, Reason: "old"}), qt.IsNil)
+	qt.Assert(t, cat.AddByWareMirror(wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: "v3", ItemName: "amd64"},
+		wfapi.WareID{Packtype: "tar", Hash: "ffff"}, "https://example.com/warehouse"), qt.IsNil)
+	qt.Assert(t, os.RemoveAll(path+"/example.com/old"), qt.IsNil)
+
+	old, err := OpenCatalogAtRevision(path, "HEAD")
+	qt.Assert(t, err, qt.IsNil)
+	cat = open()
+	diff, err := DiffCatalogs(&old, &cat)
+	qt.Assert(t, err, qt.IsNil)
+	ref := func(module wfapi.ModuleName, release wfapi.ReleaseName, item wfapi.ItemLabel) wfapi.CatalogRef {
+		return wfapi.CatalogRef{ModuleName: module, ReleaseName: release, ItemName: item}
+	}
+	qt.Check(t, diff, qt.DeepEquals, []CatalogDiffEntry{
+		{Kind: wfapi.CatalogDiffKind_ItemChanged, Ref: ref("example.com/gcc", "v1", "amd64"), Old: "tar:aaaa", New: "tar:eeee"},
+		{Kind: wfapi.CatalogDiffKind_ReleaseChanged, Ref: ref("example.com/gcc", "v2", "")},
+		{Kind: wfapi.CatalogDiffKind_ReleaseAdded, Ref: ref("example.com/gcc", "v3", "")},
+		{Kind: wfapi.CatalogDiffKind_MirrorAdded, Ref: ref("example.com/gcc", "", ""), New: "tar:ffff at https://example.com/warehouse"},
+		{Kind: wfapi.CatalogDiffKind_ModuleAdded, Ref: ref("example.com/new", "", "")},
+		{Kind: wfapi.CatalogDiffKind_ModuleRemoved, Ref: ref("example.com/old", "", "")},
+	})
+
+	// comparing a catalog with itself finds nothing
+	diff, err = DiffCatalogs(&old, &old)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, diff, qt.HasLen, 0)
+}
@@ -0,0 +1,286 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/facette/natsort"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// FsckConfig controls what Fsck does about the problems it finds.
+type FsckConfig struct {
+	// Repair rewrites module release indexes to match the release files which exist:
+	// dangling entries are dropped, mismatched CIDs are updated, and unlisted release files are added.
+	// Release files which name a different release than they're stored as are only reported.
+	Repair bool
+}
+
+// FsckProblem is a single inconsistency found in a catalog.
+type FsckProblem struct {
+	Module   wfapi.ModuleName
+	Err      error // Always a serum error; the code tells what kind of problem this is.
+	Repaired bool  // Whether the problem was fixed by rewriting the module's release index.
+}
+
+// Fsck checks the consistency of every module in the catalog, and returns the problems found.
+//
+// For each module, it checks that:
+//   - the module file parses, and names the module it's stored under;
+//   - every release listed in the module has a release file, which parses,
+//     names the same release, and matches the listed CID;
+//   - every release file is listed in the module;
+//   - every replay referenced by a release exists, parses, and matches its CID;
+//   - the signatures and mirrors files parse, if present;
+//   - every ByWare mirror is for a ware used by one of the module's releases,
+//     and ByModule mirrors are only for the module itself.
+//
+// Problems are not errors; the returned error is only for failing to do the check at all.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading the catalog fails
+//    - warpforge-error-serialization -- when a repaired module index can't be serialized
+func (cat *Catalog) Fsck(cfg FsckConfig) ([]FsckProblem, error) {
+	var problems []FsckProblem
+	for _, moduleName := range cat.Modules() {
+		found, err := cat.fsckModule(moduleName, cfg)
+		if err != nil {
+			return problems, err
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// Checks a single module for Fsck.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading the module fails
+//    - warpforge-error-serialization -- when a repaired module index can't be serialized
+func (cat *Catalog) fsckModule(moduleName wfapi.ModuleName, cfg FsckConfig) ([]FsckProblem, error) {
+	var problems []FsckProblem
+	report := func(err error, repaired bool) {
+		problems = append(problems, FsckProblem{Module: moduleName, Err: err, Repaired: repaired})
+	}
+	ref := wfapi.CatalogRef{ModuleName: moduleName}
+	modulePath := cat.moduleFilePath(ref)
+	moduleDir := filepath.Dir(modulePath)
+
+	modCapsule := wfapi.CatalogModuleCapsule{}
+	if ok, err := cat.fsckParse(modulePath, &modCapsule, "CatalogModuleCapsule", report); err != nil || !ok {
+		return problems, err
+	}
+	module := modCapsule.CatalogModule
+	if module == nil {
+		report(wfapi.ErrorCatalogParse(modulePath, fmt.Errorf("no v1 CatalogModule in CatalogModuleCapsule")), false)
+		return problems, nil
+	}
+	if module.Releases.Values == nil {
+		module.Releases.Values = map[wfapi.ReleaseName]wfapi.CatalogReleaseCID{}
+	}
+	if module.Name != moduleName {
+		report(wfapi.ErrorCatalogInvalid(modulePath,
+			fmt.Sprintf("module is named %q, but is stored as %q", module.Name, moduleName)), false)
+	}
+
+	// check the releases which the module lists
+	dirty := false
+	wares := map[wfapi.WareID]struct{}{}
+	listed := map[wfapi.ReleaseName]struct{}{}
+	releaseNames := module.Releases.Keys[:0]
+	for _, releaseName := range module.Releases.Keys {
+		listed[releaseName] = struct{}{}
+		releaseRef := wfapi.CatalogRef{ModuleName: moduleName, ReleaseName: releaseName}
+		releasePath := cat.releaseFilePath(releaseRef)
+		if _, err := fs.Stat(cat.fsys, releasePath); os.IsNotExist(err) {
+			report(wfapi.ErrorCatalogInvalid(modulePath,
+				fmt.Sprintf("release %q is listed, but its release file does not exist", releaseName)), cfg.Repair)
+			delete(module.Releases.Values, releaseName)
+			dirty = true
+			continue
+		}
+		releaseNames = append(releaseNames, releaseName)
+		release := wfapi.CatalogRelease{}
+		if ok, err := cat.fsckParse(releasePath, &release, "CatalogRelease", report); err != nil {
+			return problems, err
+		} else if !ok {
+			continue
+		}
+		// a misnamed release file may not be the release the module means at all, so its CID is never adopted
+		misnamed := release.ReleaseName != releaseName
+		if misnamed {
+			report(wfapi.ErrorCatalogInvalid(releasePath,
+				fmt.Sprintf("release is named %q, but is listed as %q", release.ReleaseName, releaseName)), false)
+		}
+		if cid := release.Cid(); cid != module.Releases.Values[releaseName] {
+			report(wfapi.ErrorCatalogInvalid(releasePath,
+				fmt.Sprintf("expected CID %q for release, actual CID is %q", module.Releases.Values[releaseName], cid)), cfg.Repair && !misnamed)
+			if !misnamed {
+				module.Releases.Values[releaseName] = cid
+				dirty = true
+			}
+		}
+		for _, wareId := range release.Items.Values {
+			wares[wareId] = struct{}{}
+		}
+		if replayCid, ok := release.Metadata.Values["replay"]; ok {
+			if err := cat.fsckReplay(moduleDir, replayCid, report); err != nil {
+				return problems, err
+			}
+		}
+		sigCapsule := wfapi.CatalogReleaseSignaturesCapsule{}
+		if _, err := cat.fsckParse(cat.signaturesFilePath(releaseRef), &sigCapsule, "CatalogReleaseSignaturesCapsule", report); err != nil {
+			return problems, err
+		}
+	}
+	module.Releases.Keys = releaseNames
+
+	// check for release files which the module does not list
+	releasesDir := filepath.Join(moduleDir, "_releases")
+	entries, errRaw := fs.ReadDir(cat.fsys, releasesDir)
+	if errRaw != nil && !os.IsNotExist(errRaw) {
+		return problems, wfapi.ErrorIo("failed to read releases directory", releasesDir, errRaw)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		releaseName := wfapi.ReleaseName(strings.TrimSuffix(entry.Name(), ".json"))
+		if _, ok := listed[releaseName]; ok {
+			continue
+		}
+		releasePath := filepath.Join(releasesDir, entry.Name())
+		release := wfapi.CatalogRelease{}
+		ok, err := cat.fsckParse(releasePath, &release, "CatalogRelease", report)
+		if err != nil {
+			return problems, err
+		}
+		repairable := ok && release.ReleaseName == releaseName
+		report(wfapi.ErrorCatalogInvalid(releasePath, "release file is not listed in the module"), cfg.Repair && repairable)
+		if repairable {
+			module.Releases.Keys = append(module.Releases.Keys, releaseName)
+			module.Releases.Values[releaseName] = release.Cid()
+			dirty = true
+			for _, wareId := range release.Items.Values {
+				wares[wareId] = struct{}{}
+			}
+		}
+	}
+
+	// check the mirrors
+	mirrorsPath := cat.mirrorFilePath(ref)
+	mirrorsCapsule := wfapi.CatalogMirrorsCapsule{}
+	if ok, err := cat.fsckParse(mirrorsPath, &mirrorsCapsule, "CatalogMirrorsCapsule", report); err != nil {
+		return problems, err
+	} else if ok && mirrorsCapsule.CatalogMirrors != nil {
+		mirrors := mirrorsCapsule.CatalogMirrors
+		if mirrors.ByWare != nil {
+			for _, wareId := range mirrors.ByWare.Keys {
+				if _, ok := wares[wareId]; !ok {
+					report(wfapi.ErrorCatalogInvalid(mirrorsPath,
+						fmt.Sprintf("mirror for ware %q, which no release of the module uses", wareId.String())), false)
+				}
+			}
+		}
+		if mirrors.ByModule != nil {
+			for _, name := range mirrors.ByModule.Keys {
+				if name != moduleName {
+					report(wfapi.ErrorCatalogInvalid(mirrorsPath,
+						fmt.Sprintf("mirror for module %q in the mirrors of another module", name)), false)
+				}
+			}
+		}
+	}
+
+	if dirty && cfg.Repair {
+		if err := cat.writeModule(ref, module); err != nil {
+			return problems, err
+		}
+	}
+	return problems, nil
+}
+
+// Checks a replay referenced by a release for Fsck.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading the replay fails
+func (cat *Catalog) fsckReplay(moduleDir string, replayCid string, report func(error, bool)) error {
+	replayPath := filepath.Join(moduleDir, "_replays", replayCid+".json")
+	if _, err := fs.Stat(cat.fsys, replayPath); os.IsNotExist(err) {
+		report(wfapi.ErrorCatalogInvalid(replayPath, "referenced replay file does not exist"), false)
+		return nil
+	}
+	replayCapsule := wfapi.ReplayCapsule{}
+	ok, err := cat.fsckParse(replayPath, &replayCapsule, "ReplayCapsule", report)
+	if err != nil || !ok {
+		return err
+	}
+	if replayCapsule.Plot == nil {
+		report(wfapi.ErrorCatalogParse(replayPath, fmt.Errorf("no v1 Plot in ReplayCapsule")), false)
+		return nil
+	}
+	if cid := replayCapsule.Plot.Cid(); cid != wfapi.PlotCID(replayCid) {
+		report(wfapi.ErrorCatalogInvalid(replayPath,
+			fmt.Sprintf("expected CID %q for plot, actual CID is %q", replayCid, cid)), false)
+	}
+	return nil
+}
+
+// Parses a catalog file for Fsck, reporting it if it can't be parsed.
+// Returns false if the file does not exist or can't be parsed.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading the file fails
+func (cat *Catalog) fsckParse(path string, into interface{}, typeName string, report func(error, bool)) (bool, error) {
+	data, errRaw := fs.ReadFile(cat.fsys, path)
+	if os.IsNotExist(errRaw) {
+		return false, nil
+	}
+	if errRaw != nil {
+		return false, wfapi.ErrorIo("failed to read catalog file", path, errRaw)
+	}
+	if _, errRaw := ipld.Unmarshal(data, json.Decode, into, wfapi.TypeSystem.TypeByName(typeName)); errRaw != nil {
+		report(wfapi.ErrorCatalogParse(path, errRaw), false)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Writes the module file for a reference, with its releases sorted in natural order.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when writing the module file fails
+//    - warpforge-error-serialization -- when serializing the module fails
+func (cat *Catalog) writeModule(ref wfapi.CatalogRef, module *wfapi.CatalogModule) error {
+	releaseList := []string{}
+	for _, r := range module.Releases.Keys {
+		releaseList = append(releaseList, string(r))
+	}
+	natsort.Sort(releaseList)
+	module.Releases.Keys = []wfapi.ReleaseName{}
+	for _, r := range releaseList {
+		module.Releases.Keys = append(module.Releases.Keys, wfapi.ReleaseName(r))
+	}
+
+	moduleFilePath := filepath.Join("/", cat.moduleFilePath(ref))
+	modCapsule := wfapi.CatalogModuleCapsule{CatalogModule: module}
+	moduleSerial, errRaw := ipld.Marshal(json.Encode, &modCapsule, wfapi.TypeSystem.TypeByName("CatalogModuleCapsule"))
+	if errRaw != nil {
+		return wfapi.ErrorSerialization("failed to serialize module", errRaw)
+	}
+	if errRaw := os.WriteFile(moduleFilePath, moduleSerial, 0644); errRaw != nil {
+		return wfapi.ErrorIo("failed to write module file", moduleFilePath, errRaw)
+	}
+	return nil
+}
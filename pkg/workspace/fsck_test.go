@@ -0,0 +1,110 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestCatalogFsck(t *testing.T) {
+	catPath := t.TempDir()
+	cat, err := OpenCatalog(os.DirFS("/"), catPath)
+	qt.Assert(t, err, qt.IsNil)
+
+	goodRef := wfapi.CatalogRef{ModuleName: "example.com/good", ReleaseName: "v1", ItemName: "x86_64"}
+	qt.Assert(t, cat.AddItem(goodRef, wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}, false), qt.IsNil)
+	qt.Assert(t, cat.AddByWareMirror(goodRef, wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}, "https://example.com/"), qt.IsNil)
+
+	badRef := wfapi.CatalogRef{ModuleName: "example.com/bad", ReleaseName: "v1", ItemName: "x86_64"}
+	for _, releaseName := range []wfapi.ReleaseName{"v1", "v2", "v3"} {
+		ref := badRef
+		ref.ReleaseName = releaseName
+		qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: "bbbbbbbbbb" + string(releaseName)}, false), qt.IsNil)
+	}
+	qt.Assert(t, cat.AddByWareMirror(badRef, wfapi.WareID{Packtype: "tar", Hash: "cccccccccc"}, "https://example.com/"), qt.IsNil)
+	releasesDir := filepath.Join(catPath, "example.com/bad/_releases")
+	// v1 is dangling
+	qt.Assert(t, os.Remove(filepath.Join(releasesDir, "v1.json")), qt.IsNil)
+	// v2 no longer matches its CID
+	qt.Assert(t, os.WriteFile(filepath.Join(releasesDir, "v2.json"),
+		[]byte(`{"releaseName": "v2", "items": {"x86_64": "tar:dddddddddd"}, "metadata": {}}`), 0644), qt.IsNil)
+	// v3 is unparsable
+	qt.Assert(t, os.WriteFile(filepath.Join(releasesDir, "v3.json"), []byte(`{`), 0644), qt.IsNil)
+	// v4 is not listed
+	qt.Assert(t, os.WriteFile(filepath.Join(releasesDir, "v4.json"),
+		[]byte(`{"releaseName": "v4", "items": {"x86_64": "tar:cccccccccc"}, "metadata": {}}`), 0644), qt.IsNil)
+	qt.Assert(t, cat.updateModuleList(), qt.IsNil)
+
+	problems, err := cat.Fsck(FsckConfig{})
+	qt.Assert(t, err, qt.IsNil)
+	var codes []string
+	for _, p := range problems {
+		qt.Check(t, p.Module, qt.Equals, wfapi.ModuleName("example.com/bad"))
+		qt.Check(t, p.Repaired, qt.IsFalse)
+		codes = append(codes, serum.Code(p.Err))
+	}
+	qt.Check(t, codes, qt.DeepEquals, []string{
+		wfapi.ECodeCatalogInvalid, // v1 dangling
+		wfapi.ECodeCatalogInvalid, // v2 mismatched
+		wfapi.ECodeCatalogParse,   // v3 unparsable
+		wfapi.ECodeCatalogInvalid, // v4 unlisted
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		problems, err := cat.Fsck(FsckConfig{Repair: true})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, problems, qt.HasLen, 4)
+		qt.Check(t, problems[0].Repaired, qt.IsTrue)
+		qt.Check(t, problems[1].Repaired, qt.IsTrue)
+		qt.Check(t, problems[2].Repaired, qt.IsFalse)
+		qt.Check(t, problems[3].Repaired, qt.IsTrue)
+
+		module, err := cat.GetModule(badRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, module.Releases.Keys, qt.DeepEquals, []wfapi.ReleaseName{"v2", "v3", "v4"})
+		wareId, _, err := cat.GetWare(wfapi.CatalogRef{ModuleName: "example.com/bad", ReleaseName: "v2", ItemName: "x86_64"})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "dddddddddd"})
+
+		// only the unparsable release remains, and the mirror is now for a ware v4 uses
+		problems, err = cat.Fsck(FsckConfig{})
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, problems, qt.HasLen, 1)
+		qt.Check(t, serum.Code(problems[0].Err), qt.Equals, wfapi.ECodeCatalogParse)
+	})
+}
+
+// A release file naming a different release than it's listed as is never adopted by a repair.
+func TestCatalogFsckMisnamedRelease(t *testing.T) {
+	catPath := t.TempDir()
+	cat, err := OpenCatalog(os.DirFS("/"), catPath)
+	qt.Assert(t, err, qt.IsNil)
+	ref := wfapi.CatalogRef{ModuleName: "example.com/foo", ReleaseName: "v2", ItemName: "x86_64"}
+	qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}, false), qt.IsNil)
+	before, err := cat.GetModule(ref)
+	qt.Assert(t, err, qt.IsNil)
+	cid := before.Releases.Values["v2"]
+
+	releasePath := filepath.Join(catPath, "example.com/foo/_releases/v2.json")
+	qt.Assert(t, os.WriteFile(releasePath,
+		[]byte(`{"releaseName": "v1", "items": {"x86_64": "tar:bbbbbbbbbb"}, "metadata": {}}`), 0644), qt.IsNil)
+	qt.Assert(t, cat.updateModuleList(), qt.IsNil)
+
+	problems, err := cat.Fsck(FsckConfig{Repair: true})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, problems, qt.HasLen, 2)
+	for _, p := range problems {
+		qt.Check(t, serum.Code(p.Err), qt.Equals, wfapi.ECodeCatalogInvalid)
+		qt.Check(t, p.Repaired, qt.IsFalse)
+	}
+	qt.Check(t, problems[0].Err, qt.ErrorMatches, `.*release is named "v1", but is listed as "v2".*`)
+
+	after, err := cat.GetModule(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, after.Releases.Values["v2"], qt.Equals, cid)
+}
@@ -0,0 +1,104 @@
+package workspace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// MatchGlob reports whether a string, such as a module name, matches a glob.
+//
+// The syntax is that of path.Match, except that "*" matches any sequence of characters including "/",
+// since module names are made of "/"-separated segments and a glob like "*gcc*" is expected to find "warpsys.org/gcc".
+// So "?" matches any single character, "[...]" matches a character class (negated by a leading "^" or "!"),
+// and "\" escapes the character following it.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when the glob is malformed
+func MatchGlob(pattern, s string) (bool, error) {
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return false, serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageTemplate("invalid glob {{pattern|q}}: {{reason}}"),
+			serum.WithDetail("pattern", pattern),
+			serum.WithDetail("reason", err.Error()),
+		)
+	}
+	return re.MatchString(s), nil
+}
+
+// compileGlob translates a glob, as accepted by MatchGlob, into an anchored regular expression.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString(`(?s)^`)
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			sb.WriteString(`.*`)
+		case '?':
+			sb.WriteString(`.`)
+		case '\\':
+			i++
+			if i == len(runes) {
+				return nil, fmt.Errorf("trailing escape")
+			}
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end, class, err := compileGlobClass(runes, i+1)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(class)
+			i = end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString(`$`)
+	return regexp.Compile(sb.String())
+}
+
+// compileGlobClass translates the character class starting just after a "[" at runes[start],
+// returning the index of its closing "]" and the equivalent regular expression.
+func compileGlobClass(runes []rune, start int) (int, string, error) {
+	var sb strings.Builder
+	sb.WriteString(`[`)
+	i := start
+	if i < len(runes) && (runes[i] == '^' || runes[i] == '!') {
+		sb.WriteString(`^`)
+		i++
+	}
+	empty := true
+	for ; i < len(runes); i++ {
+		c := runes[i]
+		switch c {
+		case ']':
+			if empty {
+				return 0, "", fmt.Errorf("empty character class")
+			}
+			sb.WriteString(`]`)
+			return i, sb.String(), nil
+		case '-':
+			if empty || i+1 == len(runes) || runes[i+1] == ']' {
+				return 0, "", fmt.Errorf("character range missing an end")
+			}
+			sb.WriteString(`-`)
+			continue
+		case '\\':
+			i++
+			if i == len(runes) {
+				return 0, "", fmt.Errorf("trailing escape")
+			}
+			c = runes[i]
+		}
+		empty = false
+		sb.WriteString(regexp.QuoteMeta(string(c)))
+	}
+	return 0, "", fmt.Errorf("unterminated character class")
+}
@@ -0,0 +1,39 @@
+package workspace
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"*gcc*", "warpsys.org/gcc", true},
+		{"*/gcc", "warpsys.org/gcc-libs", false},
+		{"warpsys.org/*", "warpsys.org/a/b", true},
+		{"warpsys.org/gc?", "warpsys.org/gcc", true},
+		{"v1.?", "v1.10", false},
+		{"v[0-9]", "v7", true},
+		{"v[!0-9]", "v7", false},
+		{"v[^0-9]", "vx", true},
+		{"a\\*", "a*", true},
+		{"a\\*", "ab", false},
+		{"[\\]]", "]", true},
+		{"a.b", "axb", false},
+	} {
+		match, err := MatchGlob(tc.pattern, tc.s)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, match, qt.Equals, tc.match, qt.Commentf("%q against %q", tc.pattern, tc.s))
+	}
+	for _, pattern := range []string{"[gcc", "[]", "a\\", "[a-]", "[-a]"} {
+		_, err := MatchGlob(pattern, "")
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument, qt.Commentf("%q", pattern))
+	}
+}
@@ -0,0 +1,121 @@
+package workspace
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// ValidateMetadataValue checks a metadata value which is about to be written,
+// if its key is well-known (see wfapi.WellKnownMetadata). Other keys may have any value.
+// Only values being written are checked: catalogs may already hold values written by hand,
+// which are kept as they are.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when the key is well-known and the value is invalid for it
+func ValidateMetadataValue(key, value string) error {
+	if problem := metadataProblem(key, value); problem != "" {
+		return serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageTemplate("invalid value for metadata {{key|q}}: {{problem}}"),
+			serum.WithDetail("key", key),
+			serum.WithDetail("problem", problem),
+		)
+	}
+	return nil
+}
+
+// metadataProblem describes what's wrong with the value of a metadata key, or returns "" if nothing is.
+func metadataProblem(key, value string) string {
+	switch key {
+	case wfapi.MetadataHomepage, wfapi.MetadataSource, wfapi.MetadataDescription,
+		wfapi.MetadataLicense, wfapi.MetadataMaintainers, wfapi.MetadataReleaseDate:
+		if strings.TrimSpace(value) == "" {
+			return "must not be empty"
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return "must be a single line"
+		}
+	default:
+		return ""
+	}
+
+	switch key {
+	case wfapi.MetadataHomepage, wfapi.MetadataSource:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Sprintf("%q is not an absolute URL", value)
+		}
+	case wfapi.MetadataLicense:
+		return licenseExpressionProblem(value)
+	case wfapi.MetadataMaintainers:
+		for _, m := range strings.Split(value, ",") {
+			if strings.TrimSpace(m) == "" {
+				return "must be a comma separated list without empty entries"
+			}
+		}
+	case wfapi.MetadataReleaseDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Sprintf("%q is not a date in the form YYYY-MM-DD", value)
+		}
+	}
+	return ""
+}
+
+// spdxIdPattern matches an SPDX license identifier, optionally followed by "+" for "or any later version".
+// Custom identifiers (LicenseRef-...) have the same form.
+var spdxIdPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.\-]*\+?$`)
+
+// licenseExpressionProblem checks the syntax of an SPDX license expression,
+// such as "MIT" or "(Apache-2.0 OR MIT) AND BSD-3-Clause WITH LLVM-exception",
+// and describes what's wrong with it, or returns "" if nothing is.
+// Identifiers are only checked to be well formed, not to be on the SPDX license list,
+// since that list grows over time.
+func licenseExpressionProblem(expr string) string {
+	tokens := strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr))
+	depth := 0
+	expectLicense := true // whether the next token must be a license (or an open parenthesis)
+	afterWith := false    // an exception follows WITH, which can't be parenthesized
+	for _, tok := range tokens {
+		switch tok {
+		case "(":
+			if !expectLicense || afterWith {
+				return "unexpected \"(\""
+			}
+			depth++
+		case ")":
+			if expectLicense || depth == 0 {
+				return "unexpected \")\""
+			}
+			depth--
+		case "AND", "OR", "WITH":
+			if expectLicense {
+				return fmt.Sprintf("unexpected operator %q", tok)
+			}
+			expectLicense = true
+			afterWith = tok == "WITH"
+		default:
+			if !expectLicense {
+				return fmt.Sprintf("expected AND, OR or WITH before %q", tok)
+			}
+			if !spdxIdPattern.MatchString(tok) {
+				return fmt.Sprintf("%q is not a valid SPDX license identifier", tok)
+			}
+			expectLicense = false
+			afterWith = false
+		}
+	}
+	if expectLicense {
+		return "incomplete license expression"
+	}
+	if depth != 0 {
+		return "unbalanced parentheses"
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package workspace
+
+import (
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestMetadataProblem(t *testing.T) {
+	for _, tc := range []struct {
+		key   string
+		value string
+		valid bool
+	}{
+		{wfapi.MetadataHomepage, "https://example.com/foo", true},
+		{wfapi.MetadataHomepage, "example.com/foo", false},
+		{wfapi.MetadataSource, "git://example.com/foo.git", true},
+		{wfapi.MetadataSource, "", false},
+		{wfapi.MetadataDescription, "A compiler", true},
+		{wfapi.MetadataDescription, "A compiler\nfor C", false},
+		{wfapi.MetadataLicense, "MIT", true},
+		{wfapi.MetadataLicense, "GPL-2.0+", true},
+		{wfapi.MetadataLicense, "(Apache-2.0 OR MIT) AND BSD-3-Clause", true},
+		{wfapi.MetadataLicense, "Apache-2.0 WITH LLVM-exception", true},
+		{wfapi.MetadataLicense, "LicenseRef-custom", true},
+		{wfapi.MetadataLicense, "MIT and GPL-2.0", false},
+		{wfapi.MetadataLicense, "MIT OR", false},
+		{wfapi.MetadataLicense, "(MIT OR GPL-2.0", false},
+		{wfapi.MetadataLicense, "MIT)", false},
+		{wfapi.MetadataLicense, "MIT WITH (GPL-2.0)", false},
+		{wfapi.MetadataLicense, "MIT/GPL", false},
+		{wfapi.MetadataMaintainers, "Jane Doe <jane@example.com>, John Doe", true},
+		{wfapi.MetadataMaintainers, "Jane Doe,,John Doe", false},
+		{wfapi.MetadataReleaseDate, "2022-10-19", true},
+		{wfapi.MetadataReleaseDate, "19/10/2022", false},
+		{"anything-else", "", true},
+	} {
+		problem := metadataProblem(tc.key, tc.value)
+		qt.Check(t, problem == "", qt.Equals, tc.valid, qt.Commentf("%s=%q: %s", tc.key, tc.value, problem))
+	}
+}
+
+func TestSetMetadata(t *testing.T) {
+	path := t.TempDir()
+	cat, err := OpenCatalog(os.DirFS("/"), path[1:])
+	qt.Assert(t, err, qt.IsNil)
+	ref := wfapi.CatalogRef{ModuleName: "example.com/foo", ReleaseName: "v1", ItemName: "src"}
+	qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: "abcd"}, false), qt.IsNil)
+
+	qt.Assert(t, cat.SetModuleMetadata(ref, wfapi.MetadataLicense, "MIT"), qt.IsNil)
+	qt.Assert(t, cat.SetReleaseMetadata(ref, wfapi.MetadataReleaseDate, "2022-10-19"), qt.IsNil)
+	err = cat.SetModuleMetadata(ref, wfapi.MetadataHomepage, "not a url")
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+	err = cat.SetReleaseMetadata(ref, wfapi.MetadataReleaseDate, "yesterday")
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+	err = cat.SetReleaseMetadata(ref, "replay", "bafy")
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+
+	module, err := cat.GetModule(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, module.Metadata.Keys, qt.DeepEquals, []string{wfapi.MetadataLicense})
+	release, err := cat.GetRelease(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, release.Metadata.Values[wfapi.MetadataReleaseDate], qt.Equals, "2022-10-19")
+
+	// adding more items keeps the metadata
+	ref.ItemName = "bin"
+	qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: "ef01"}, false), qt.IsNil)
+	release, err = cat.GetRelease(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, release.Metadata.Values[wfapi.MetadataReleaseDate], qt.Equals, "2022-10-19")
+}
+
+func TestLegacyMetadata(t *testing.T) {
+	path := t.TempDir()
+	cat, err := OpenCatalog(os.DirFS("/"), path[1:])
+	qt.Assert(t, err, qt.IsNil)
+	ref := wfapi.CatalogRef{ModuleName: "example.com/foo", ReleaseName: "v1", ItemName: "src"}
+
+	// metadata written by hand before it was validated, which must not keep the catalog from being changed
+	release := wfapi.CatalogRelease{ReleaseName: ref.ReleaseName}
+	release.Items.Values = map[wfapi.ItemLabel]wfapi.WareID{}
+	release.Metadata.Keys = []string{wfapi.MetadataLicense, wfapi.MetadataDescription}
+	release.Metadata.Values = map[string]string{
+		wfapi.MetadataLicense:     "GPL v2",
+		wfapi.MetadataDescription: "A compiler\nfor C",
+	}
+	module := wfapi.CatalogModule{Name: ref.ModuleName}
+	module.Metadata.Keys = []string{wfapi.MetadataLicense}
+	module.Metadata.Values = map[string]string{wfapi.MetadataLicense: "GPL v2"}
+	qt.Assert(t, cat.AddRelease(module, release, nil, false), qt.IsNil)
+
+	qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: "abcd"}, false), qt.IsNil)
+	qt.Assert(t, cat.SetReleaseMetadata(ref, wfapi.MetadataReleaseDate, "2022-10-19"), qt.IsNil)
+	qt.Assert(t, cat.SetModuleMetadata(ref, wfapi.MetadataHomepage, "https://example.com/foo"), qt.IsNil)
+	got, err := cat.GetRelease(ref)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, got.Metadata.Values[wfapi.MetadataLicense], qt.Equals, "GPL v2")
+}
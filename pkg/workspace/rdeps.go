@@ -0,0 +1,350 @@
+package workspace
+
+import (
+	"path/filepath"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/semver"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// ReverseDependencyQuery selects what to find the dependents of.
+// Exactly one of Ref and WareID should be set.
+//
+// A WareID matches replay inputs using it directly, and catalog refs to items which are that ware.
+// A Ref with an item name matches replay inputs referring to that item, and inputs using its ware as above.
+// A Ref without an item name does the same for every item of the release.
+type ReverseDependencyQuery struct {
+	Ref    *wfapi.CatalogRef
+	WareID *wfapi.WareID
+}
+
+// ReverseDependency is a release whose replay depends on the queried ware or ref.
+type ReverseDependency struct {
+	Workspace string           // Path of the workspace containing the catalog.
+	Catalog   string           // Name of the catalog; empty for the catalog of a non-root workspace.
+	Ref       wfapi.CatalogRef // The dependent release; the item name is empty.
+	// Depth is 1 for releases whose replays use the queried ware or ref directly,
+	// 2 for releases whose replays use one of those releases, and so on.
+	Depth int
+	// Via lists the inputs of the replay which matched, as they're written in plots:
+	// catalog refs (as written in the replay, even if they're version constraints) and WareIDs.
+	Via []string
+	// Possible is true if the release only may depend on the queried ware or ref:
+	// its replay uses a version constraint which the queried release matches, but which now resolves to a newer release,
+	// so which of them the release was built with isn't known.
+	// Releases which depend on a possible dependency are also only possible dependencies.
+	Possible bool
+}
+
+// replayDeps is what a release's replay uses, for ReverseDependencies.
+type replayDeps struct {
+	dep   ReverseDependency
+	wares []wfapi.WareID // The wares of the release's own items.
+	refs  []wfapi.CatalogRef
+	// resolved holds the refs with version constraints resolved, in the same order,
+	// and refWares their wares, or nil for refs which aren't in any catalog.
+	resolved []wfapi.CatalogRef
+	refWares []*wfapi.WareID
+	// matching holds, for refs with version constraints, every item the constraint matches,
+	// any of which the replay may have used when the release was built.
+	matching [][]matchedItem
+	inputs   []wfapi.WareID
+}
+
+// matchedItem is a catalog item matched by a version constraint, and its ware.
+type matchedItem struct {
+	ref    wfapi.CatalogRef
+	wareId wfapi.WareID
+}
+
+// ReverseDependencies finds the releases in every catalog of the workspace set
+// whose replays consume the queried ware or ref, directly or transitively.
+// A release depends on another transitively if its replay uses any item of a release which depends on it.
+//
+// Results are ordered by depth, then in the order SearchCatalogs would visit them.
+// A release found in more than one catalog is reported for each of them.
+// Version constraints in replays are resolved against the current catalogs, as for any other plot;
+// releases whose replays only use the queried release through a constraint which now resolves to another release
+// are reported as possible dependencies, since replays don't record which release they were built with.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when the query does not have exactly one of Ref and WareID
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog, release or replay is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+//    - warpforge-error-release-constraint -- when a replay has an invalid version constraint
+func (wsSet WorkspaceSet) ReverseDependencies(query ReverseDependencyQuery) ([]ReverseDependency, error) {
+	if (query.Ref == nil) == (query.WareID == nil) {
+		return nil, serum.Error(wfapi.ECodeArgument,
+			serum.WithMessageLiteral("a reverse dependency query needs exactly one of a catalog ref or a ware ID"),
+		)
+	}
+	index, err := wsSet.indexReplays()
+	if err != nil {
+		return nil, err
+	}
+
+	// the targets of each round: wares, item refs, and releases (refs without an item name),
+	// each noting whether it's only a possible dependency.
+	wares := map[wfapi.WareID]bool{}
+	refs := map[wfapi.CatalogRef]bool{}
+	releases := map[wfapi.CatalogRef]bool{}
+	// releases which have already been targets, so cycles and diamonds are only followed once
+	visited := map[wfapi.CatalogRef]struct{}{}
+
+	if query.WareID != nil {
+		wares[*query.WareID] = false
+	} else {
+		ref := *query.Ref
+		release, err := wsSet.GetCatalogRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			// constraints are resolved, so the release's real name is what replays are matched against
+			ref.ReleaseName = release.ReleaseName
+			for _, item := range release.Items.Keys {
+				if ref.ItemName == "" || ref.ItemName == item {
+					wares[release.Items.Values[item]] = false
+				}
+			}
+		}
+		if ref.ItemName == "" {
+			releases[ref] = false
+		} else {
+			refs[ref] = false
+		}
+		visited[wfapi.CatalogRef{ModuleName: ref.ModuleName, ReleaseName: ref.ReleaseName}] = struct{}{}
+	}
+
+	// matches reports whether an item is a target, and if so, whether it's only a possible one
+	matches := func(ref wfapi.CatalogRef, wareId *wfapi.WareID) (matched bool, possible bool) {
+		possible = true
+		if p, ok := refs[ref]; ok {
+			matched, possible = true, possible && p
+		}
+		if p, ok := releases[wfapi.CatalogRef{ModuleName: ref.ModuleName, ReleaseName: ref.ReleaseName}]; ok {
+			matched, possible = true, possible && p
+		}
+		if wareId != nil {
+			if p, ok := wares[*wareId]; ok {
+				matched, possible = true, possible && p
+			}
+		}
+		return matched, possible
+	}
+
+	var results []ReverseDependency
+	found := make([]bool, len(index))
+	for depth := 1; len(wares)+len(refs)+len(releases) > 0; depth++ {
+		next := map[wfapi.CatalogRef]bool{}
+		nextWares := map[wfapi.WareID]bool{}
+		for i, entry := range index {
+			if found[i] {
+				continue
+			}
+			var via []string
+			possible := true
+			for j, ref := range entry.refs {
+				matched, p := matches(entry.resolved[j], entry.refWares[j])
+				if !matched {
+					// the constraint may have resolved to an older release when this one was built
+					for _, item := range entry.matching[j] {
+						if m, _ := matches(item.ref, &item.wareId); m {
+							matched, p = true, true
+							break
+						}
+					}
+				}
+				if matched {
+					via = append(via, ref.String())
+					possible = possible && p
+				}
+			}
+			for _, wareId := range entry.inputs {
+				if p, ok := wares[wareId]; ok {
+					via = append(via, wareId.String())
+					possible = possible && p
+				}
+			}
+			if len(via) == 0 {
+				continue
+			}
+			found[i] = true
+			dep := entry.dep
+			dep.Depth = depth
+			dep.Via = via
+			dep.Possible = possible
+			results = append(results, dep)
+			if _, ok := visited[dep.Ref]; !ok {
+				visited[dep.Ref] = struct{}{}
+				next[dep.Ref] = possible
+			}
+			// the same release may differ between catalogs, so each copy's wares are followed
+			for _, wareId := range entry.wares {
+				if p, ok := nextWares[wareId]; !ok || p {
+					nextWares[wareId] = possible
+				}
+			}
+		}
+
+		wares = nextWares
+		refs = map[wfapi.CatalogRef]bool{}
+		releases = next
+	}
+	return results, nil
+}
+
+// indexReplays reads the replay of every release in every catalog of the workspace set,
+// in the order SearchCatalogs visits them, skipping releases without a replay.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog, release or replay is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+//    - warpforge-error-release-constraint -- when a replay has an invalid version constraint
+func (wsSet WorkspaceSet) indexReplays() ([]replayDeps, error) {
+	var index []replayDeps
+	matchingCache := map[wfapi.CatalogRef][]matchedItem{}
+	for _, ws := range wsSet {
+		cats, err := ws.ListCatalogs()
+		if err != nil {
+			return nil, err
+		}
+		_, wsPath := ws.Path()
+		for _, c := range cats {
+			cat, err := ws.OpenCatalog(c)
+			if err != nil {
+				return nil, err
+			}
+			for _, moduleName := range cat.Modules() {
+				ref := wfapi.CatalogRef{ModuleName: moduleName}
+				module, err := cat.GetModule(ref)
+				if err != nil {
+					return nil, err
+				}
+				if module == nil {
+					continue
+				}
+				for _, releaseName := range module.Releases.Keys {
+					ref.ReleaseName = releaseName
+					release, err := cat.GetRelease(ref)
+					if err != nil {
+						return nil, err
+					}
+					if release == nil {
+						continue
+					}
+					replay, err := cat.GetReplay(ref)
+					if err != nil {
+						return nil, err
+					}
+					if replay == nil {
+						continue
+					}
+					entry := replayDeps{
+						dep: ReverseDependency{
+							Workspace: filepath.Join("/", wsPath),
+							Catalog:   c,
+							Ref:       ref,
+						},
+						refs:   replay.CatalogRefs(),
+						inputs: replay.WareIDs(),
+					}
+					for _, item := range release.Items.Keys {
+						entry.wares = append(entry.wares, release.Items.Values[item])
+					}
+					for _, r := range entry.refs {
+						resolved, err := wsSet.ResolveCatalogRef(r)
+						if err != nil && serum.Code(err) != wfapi.ECodeCatalogMissingEntry {
+							return nil, err
+						}
+						// Error Codes -= warpforge-error-catalog-missing-entry
+						// a constraint nothing matches can't depend on anything, so it's kept as written
+						entry.resolved = append(entry.resolved, resolved)
+						// the release is looked up rather than the ware, since trust policies don't matter here
+						var wareId *wfapi.WareID
+						refRelease, err := wsSet.GetCatalogRelease(resolved)
+						if err != nil {
+							return nil, err
+						}
+						if refRelease != nil {
+							if w, ok := refRelease.Items.Values[resolved.ItemName]; ok {
+								wareId = &w
+							}
+						}
+						entry.refWares = append(entry.refWares, wareId)
+						matching, err := wsSet.matchingItems(r, matchingCache)
+						if err != nil {
+							return nil, err
+						}
+						entry.matching = append(entry.matching, matching)
+					}
+					index = append(index, entry)
+				}
+			}
+		}
+	}
+	return index, nil
+}
+
+// matchingItems lists every item in the workspace set which a catalog ref with a version constraint matches,
+// including yanked releases, since they may have been used before they were yanked.
+// Returns nil for refs without a constraint. Results are cached in the given map.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog or release is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+//    - warpforge-error-release-constraint -- when the constraint is invalid
+func (wsSet WorkspaceSet) matchingItems(ref wfapi.CatalogRef, cache map[wfapi.CatalogRef][]matchedItem) ([]matchedItem, error) {
+	if !semver.IsConstraint(string(ref.ReleaseName)) {
+		return nil, nil
+	}
+	if items, ok := cache[ref]; ok {
+		return items, nil
+	}
+	constraint, err := semver.ParseConstraint(string(ref.ReleaseName))
+	if err != nil {
+		return nil, err
+	}
+	var items []matchedItem
+	for _, ws := range wsSet {
+		cats, err := ws.ListCatalogs()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cats {
+			cat, err := ws.OpenCatalog(c)
+			if err != nil {
+				return nil, err
+			}
+			releases, err := cat.OrderedReleases(ref.ModuleName)
+			if err != nil {
+				return nil, err
+			}
+			for _, release := range releases {
+				wareId, ok := release.Items.Values[ref.ItemName]
+				if !ok {
+					continue
+				}
+				if v, ok := ReleaseVersion(&release); ok && constraint.Match(v) {
+					items = append(items, matchedItem{
+						ref:    wfapi.CatalogRef{ModuleName: ref.ModuleName, ReleaseName: release.ReleaseName, ItemName: ref.ItemName},
+						wareId: wareId,
+					})
+				}
+			}
+		}
+	}
+	cache[ref] = items
+	return items, nil
+}
@@ -0,0 +1,121 @@
+package workspace
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestReverseDependencies(t *testing.T) {
+	root, rootPath := newTestRootWorkspace(t)
+	wss := WorkspaceSet{root}
+
+	ware := func(hash string) wfapi.WareID {
+		return wfapi.WareID{Packtype: "tar", Hash: hash}
+	}
+	ref := func(module wfapi.ModuleName, release wfapi.ReleaseName, item wfapi.ItemLabel) wfapi.CatalogRef {
+		return wfapi.CatalogRef{ModuleName: module, ReleaseName: release, ItemName: item}
+	}
+	// adds a release with a single item, whose replay has the given inputs
+	addRelease := func(r wfapi.CatalogRef, wareId wfapi.WareID, inputs ...wfapi.PlotInputSimple) {
+		cat, err := root.CreateOrOpenCatalog("main")
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, cat.AddItem(r, wareId, false), qt.IsNil)
+		if len(inputs) == 0 {
+			return
+		}
+		var replay wfapi.Plot
+		replay.Inputs.Values = map[wfapi.LocalLabel]wfapi.PlotInput{}
+		for i := range inputs {
+			label := wfapi.LocalLabel(string(rune('a' + i)))
+			replay.Inputs.Keys = append(replay.Inputs.Keys, label)
+			replay.Inputs.Values[label] = wfapi.PlotInput{PlotInputSimple: &inputs[i]}
+		}
+		cat, err = root.OpenCatalog("main")
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, cat.AddReplay(r, replay, false), qt.IsNil)
+	}
+	useRef := func(r wfapi.CatalogRef) wfapi.PlotInputSimple {
+		return wfapi.PlotInputSimple{CatalogRef: &r}
+	}
+	useWare := func(w wfapi.WareID) wfapi.PlotInputSimple {
+		return wfapi.PlotInputSimple{WareID: &w}
+	}
+
+	addRelease(ref("example.com/base", "v1.0.0", "amd64"), ware("base1"))
+	addRelease(ref("example.com/base", "v2.0.0", "amd64"), ware("base2"))
+	addRelease(ref("example.com/lib", "v1", "amd64"), ware("lib1"), useRef(ref("example.com/base", "v1.0.0", "amd64")))
+	addRelease(ref("example.com/tool", "v1", "amd64"), ware("tool1"), useWare(ware("base1")))
+	addRelease(ref("example.com/app", "v1", "amd64"), ware("app1"), useRef(ref("example.com/lib", "v1", "amd64")), useWare(ware("tool1")))
+	addRelease(ref("example.com/new", "v1", "amd64"), ware("new1"), useRef(ref("example.com/base", "^2", "amd64")))
+
+	rdeps := func(query ReverseDependencyQuery) []string {
+		results, err := wss.ReverseDependencies(query)
+		qt.Assert(t, err, qt.IsNil)
+		var found []string
+		for _, r := range results {
+			qt.Check(t, r.Workspace, qt.Equals, rootPath)
+			qt.Check(t, r.Catalog, qt.Equals, "main")
+			name := string(r.Ref.ModuleName) + ":" + string(r.Ref.ReleaseName)
+			if r.Possible {
+				name += " (possible)"
+			}
+			found = append(found, name)
+			for _, via := range r.Via {
+				found = append(found, "  "+via)
+			}
+		}
+		return found
+	}
+
+	base1 := ref("example.com/base", "v1.0.0", "amd64")
+	expected := []string{
+		"example.com/lib:v1",
+		"  catalog:example.com/base:v1.0.0:amd64",
+		"example.com/tool:v1",
+		"  tar:base1",
+		"example.com/app:v1",
+		"  catalog:example.com/lib:v1:amd64",
+		"  tar:tool1",
+	}
+	qt.Check(t, rdeps(ReverseDependencyQuery{Ref: &base1}), qt.DeepEquals, expected)
+	qt.Check(t, rdeps(ReverseDependencyQuery{Ref: &wfapi.CatalogRef{ModuleName: "example.com/base", ReleaseName: "v1.0.0"}}), qt.DeepEquals, expected)
+	qt.Check(t, rdeps(ReverseDependencyQuery{WareID: &wfapi.WareID{Packtype: "tar", Hash: "base1"}}), qt.DeepEquals, expected)
+
+	// version constraints are matched against the release they resolve to
+	qt.Check(t, rdeps(ReverseDependencyQuery{Ref: &wfapi.CatalogRef{ModuleName: "example.com/base", ReleaseName: "v2.0.0"}}), qt.DeepEquals, []string{
+		"example.com/new:v1",
+		"  catalog:example.com/base:^2:amd64",
+	})
+
+	// depths count the releases in between
+	results, err := wss.ReverseDependencies(ReverseDependencyQuery{Ref: &base1})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, []int{results[0].Depth, results[1].Depth, results[2].Depth}, qt.DeepEquals, []int{1, 1, 2})
+
+	qt.Check(t, rdeps(ReverseDependencyQuery{Ref: &wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v1"}}), qt.HasLen, 0)
+
+	// once a newer release matches the constraint, the replay may have used either, and so may its dependents
+	addRelease(ref("example.com/base", "v2.1.0", "amd64"), ware("base21"))
+	addRelease(ref("example.com/top", "v1", "amd64"), ware("top1"), useRef(ref("example.com/new", "v1", "amd64")))
+	qt.Check(t, rdeps(ReverseDependencyQuery{Ref: &wfapi.CatalogRef{ModuleName: "example.com/base", ReleaseName: "v2.0.0"}}), qt.DeepEquals, []string{
+		"example.com/new:v1 (possible)",
+		"  catalog:example.com/base:^2:amd64",
+		"example.com/top:v1 (possible)",
+		"  catalog:example.com/new:v1:amd64",
+	})
+	qt.Check(t, rdeps(ReverseDependencyQuery{WareID: &wfapi.WareID{Packtype: "tar", Hash: "base2"}}), qt.DeepEquals, []string{
+		"example.com/new:v1 (possible)",
+		"  catalog:example.com/base:^2:amd64",
+		"example.com/top:v1 (possible)",
+		"  catalog:example.com/new:v1:amd64",
+	})
+	qt.Check(t, rdeps(ReverseDependencyQuery{Ref: &wfapi.CatalogRef{ModuleName: "example.com/base", ReleaseName: "v2.1.0"}}), qt.DeepEquals, []string{
+		"example.com/new:v1",
+		"  catalog:example.com/base:^2:amd64",
+		"example.com/top:v1",
+		"  catalog:example.com/new:v1:amd64",
+	})
+}
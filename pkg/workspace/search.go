@@ -0,0 +1,167 @@
+package workspace
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// CatalogQuery selects catalog items for a search.
+//
+// Patterns are globs (as per MatchGlob, so "*" also matches "/") if they contain any of "*?[",
+// and otherwise match any string containing them. An empty pattern matches everything.
+type CatalogQuery struct {
+	Module   string            // Pattern for module names.
+	Metadata map[string]string // Release metadata which must be present; values are patterns.
+	Items    []string          // Patterns for item labels; an item must match at least one, if any are given.
+}
+
+// Validate checks that every pattern in the query is well formed.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when a pattern is not a valid glob
+func (q CatalogQuery) Validate() error {
+	patterns := append([]string{q.Module}, q.Items...)
+	for _, v := range q.Metadata {
+		patterns = append(patterns, v)
+	}
+	for _, p := range patterns {
+		if _, err := MatchGlob(p, ""); err != nil {
+			return serum.Error(wfapi.ECodeArgument,
+				serum.WithMessageTemplate("invalid search pattern {{pattern|q}}"),
+				serum.WithDetail("pattern", p),
+				serum.WithCause(err),
+			)
+		}
+	}
+	return nil
+}
+
+// matchPattern matches a string against a CatalogQuery pattern.
+// Patterns must have been validated already.
+func matchPattern(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := MatchGlob(pattern, s)
+		return ok
+	}
+	return strings.Contains(s, pattern)
+}
+
+// Search finds the items in this catalog which match a query.
+// Results are in catalog order: by module, then release, then item.
+// The Workspace and Catalog fields of the results are left empty.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when a pattern is not a valid glob
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a release does not match its CID
+func (cat *Catalog) Search(query CatalogQuery) ([]wfapi.CatalogSearchResult, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	var results []wfapi.CatalogSearchResult
+	for _, moduleName := range cat.Modules() {
+		if !matchPattern(query.Module, string(moduleName)) {
+			continue
+		}
+		ref := wfapi.CatalogRef{ModuleName: moduleName}
+		module, err := cat.GetModule(ref)
+		if err != nil {
+			return nil, err
+		}
+		if module == nil {
+			continue
+		}
+	releases:
+		for _, releaseName := range module.Releases.Keys {
+			ref.ReleaseName = releaseName
+			release, err := cat.GetRelease(ref)
+			if err != nil {
+				return nil, err
+			}
+			if release == nil {
+				continue
+			}
+			for k, pattern := range query.Metadata {
+				v, ok := release.Metadata.Values[k]
+				if !ok || !matchPattern(pattern, v) {
+					continue releases
+				}
+			}
+			for _, itemName := range release.Items.Keys {
+				if !matchAnyPattern(query.Items, string(itemName)) {
+					continue
+				}
+				ref.ItemName = itemName
+				results = append(results, wfapi.CatalogSearchResult{
+					Ref:    ref,
+					WareID: release.Items.Values[itemName],
+				})
+			}
+		}
+	}
+	return results, nil
+}
+
+// matchAnyPattern is true if the string matches any of the patterns, or there are no patterns.
+func matchAnyPattern(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if matchPattern(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchCatalogs finds the items matching a query in every catalog of the workspace set.
+// Workspaces are searched in stack order, and the catalogs of the root workspace in alphabetical order.
+// Unlike GetCatalogWare, every match is returned, not just the first.
+//
+// Errors:
+//
+//    - warpforge-error-invalid-argument -- when a pattern is not a valid glob
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog or release is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+func (wsSet WorkspaceSet) SearchCatalogs(query CatalogQuery) ([]wfapi.CatalogSearchResult, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	var results []wfapi.CatalogSearchResult
+	for _, ws := range wsSet {
+		cats, err := ws.ListCatalogs()
+		if err != nil {
+			return nil, err
+		}
+		_, wsPath := ws.Path()
+		for _, c := range cats {
+			cat, err := ws.OpenCatalog(c)
+			if err != nil {
+				return nil, err
+			}
+			found, err := cat.Search(query)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range found {
+				r.Workspace = filepath.Join("/", wsPath)
+				r.Catalog = c
+				results = append(results, r)
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,73 @@
+package workspace
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestSearchCatalogs(t *testing.T) {
+	root, rootPath := newTestRootWorkspace(t)
+	wss := WorkspaceSet{root}
+
+	addRelease := func(catalogName string, module wfapi.ModuleName, releaseName wfapi.ReleaseName, items []wfapi.ItemLabel, metadata map[string]string) {
+		cat, err := root.CreateOrOpenCatalog(catalogName)
+		qt.Assert(t, err, qt.IsNil)
+		release := wfapi.CatalogRelease{ReleaseName: releaseName}
+		release.Items.Values = map[wfapi.ItemLabel]wfapi.WareID{}
+		for _, item := range items {
+			release.Items.Keys = append(release.Items.Keys, item)
+			release.Items.Values[item] = wfapi.WareID{Packtype: "tar", Hash: string(module) + string(releaseName) + string(item)}
+		}
+		release.Metadata.Values = metadata
+		for k := range metadata {
+			release.Metadata.Keys = append(release.Metadata.Keys, k)
+		}
+		qt.Assert(t, cat.AddRelease(wfapi.CatalogModule{Name: module}, release, nil, false), qt.IsNil)
+	}
+	addRelease("main", "warpsys.org/gcc", "v11", []wfapi.ItemLabel{"linux-amd64", "linux-arm64"}, map[string]string{"channel": "stable"})
+	addRelease("main", "warpsys.org/gcc", "v12", []wfapi.ItemLabel{"linux-amd64"}, map[string]string{"channel": "beta"})
+	addRelease("main", "warpsys.org/gcc-libs", "v12", []wfapi.ItemLabel{"linux-amd64"}, nil)
+	addRelease("mirror", "warpsys.org/gcc", "v11", []wfapi.ItemLabel{"linux-amd64"}, map[string]string{"channel": "stable"})
+	addRelease("mirror", "warpsys.org/busybox", "v1", []wfapi.ItemLabel{"linux-amd64"}, nil)
+
+	search := func(query CatalogQuery) []string {
+		results, err := wss.SearchCatalogs(query)
+		qt.Assert(t, err, qt.IsNil)
+		var found []string
+		for _, r := range results {
+			qt.Check(t, r.Workspace, qt.Equals, rootPath)
+			found = append(found, r.Catalog+" "+r.Ref.String())
+		}
+		return found
+	}
+
+	qt.Check(t, search(CatalogQuery{Module: "warpsys.org/gcc", Items: []string{"amd64"}}), qt.DeepEquals, []string{
+		"main catalog:warpsys.org/gcc:v11:linux-amd64",
+		"main catalog:warpsys.org/gcc:v12:linux-amd64",
+		"main catalog:warpsys.org/gcc-libs:v12:linux-amd64",
+		"mirror catalog:warpsys.org/gcc:v11:linux-amd64",
+	})
+	qt.Check(t, search(CatalogQuery{Module: "*/gcc", Metadata: map[string]string{"channel": "stable"}}), qt.DeepEquals, []string{
+		"main catalog:warpsys.org/gcc:v11:linux-amd64",
+		"main catalog:warpsys.org/gcc:v11:linux-arm64",
+		"mirror catalog:warpsys.org/gcc:v11:linux-amd64",
+	})
+	qt.Check(t, search(CatalogQuery{Metadata: map[string]string{"channel": ""}, Items: []string{"*-arm64"}}), qt.DeepEquals, []string{
+		"main catalog:warpsys.org/gcc:v11:linux-arm64",
+	})
+	qt.Check(t, search(CatalogQuery{Module: "busybox"}), qt.DeepEquals, []string{
+		"mirror catalog:warpsys.org/busybox:v1:linux-amd64",
+	})
+	qt.Check(t, search(CatalogQuery{Module: "clang"}), qt.IsNil)
+	// "*" spans the "/" of module names
+	qt.Check(t, search(CatalogQuery{Module: "*gcc*", Metadata: map[string]string{"channel": "beta"}}), qt.DeepEquals, []string{
+		"main catalog:warpsys.org/gcc:v12:linux-amd64",
+	})
+
+	_, err := wss.SearchCatalogs(CatalogQuery{Module: "[gcc"})
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeArgument)
+}
@@ -0,0 +1,214 @@
+package workspace
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// Get the path for a CatalogReleaseSignatures file.
+// This will be [catalog path]/[module name]/_signatures/[release name].json
+func (cat *Catalog) signaturesFilePath(ref wfapi.CatalogRef) string {
+	base := filepath.Dir(cat.moduleFilePath(ref))
+	return filepath.Join(base, "_signatures", string(ref.ReleaseName)+".json")
+}
+
+// Get the signatures of a release.
+// Returns nil if the release has never been signed.
+// The signatures may be stale; callers should compare their CID with the release's.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading the signatures file fails
+//    - warpforge-error-catalog-parse -- when parsing the signatures file fails
+func (cat *Catalog) GetSignatures(ref wfapi.CatalogRef) (*wfapi.CatalogReleaseSignatures, error) {
+	sigPath := cat.signaturesFilePath(ref)
+	sigBytes, errRaw := fs.ReadFile(cat.fsys, sigPath)
+	if os.IsNotExist(errRaw) {
+		return nil, nil
+	}
+	if errRaw != nil {
+		return nil, wfapi.ErrorIo("failed to read signatures file", sigPath, errRaw)
+	}
+	sigCapsule := wfapi.CatalogReleaseSignaturesCapsule{}
+	_, errRaw = ipld.Unmarshal(sigBytes, json.Decode, &sigCapsule, wfapi.TypeSystem.TypeByName("CatalogReleaseSignaturesCapsule"))
+	if errRaw != nil {
+		return nil, wfapi.ErrorCatalogParse(sigPath, errRaw)
+	}
+	if sigCapsule.CatalogReleaseSignatures == nil {
+		return nil, wfapi.ErrorCatalogParse(sigPath, fmt.Errorf("no v1 CatalogReleaseSignatures in capsule"))
+	}
+	return sigCapsule.CatalogReleaseSignatures, nil
+}
+
+// Write the signatures of a release, replacing any existing ones.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when writing the signatures file fails
+//    - warpforge-error-serialization -- when serializing the signatures fails
+func (cat *Catalog) PutSignatures(ref wfapi.CatalogRef, sigs wfapi.CatalogReleaseSignatures) error {
+	sigPath := filepath.Join("/", cat.signaturesFilePath(ref))
+	sigCapsule := wfapi.CatalogReleaseSignaturesCapsule{CatalogReleaseSignatures: &sigs}
+	sigSerial, errRaw := ipld.Marshal(json.Encode, &sigCapsule, wfapi.TypeSystem.TypeByName("CatalogReleaseSignaturesCapsule"))
+	if errRaw != nil {
+		return wfapi.ErrorSerialization("failed to serialize signatures", errRaw)
+	}
+	if errRaw := os.MkdirAll(filepath.Dir(sigPath), 0755); errRaw != nil {
+		return wfapi.ErrorIo("failed to create signatures directory", filepath.Dir(sigPath), errRaw)
+	}
+	if errRaw := os.WriteFile(sigPath, sigSerial, 0644); errRaw != nil {
+		return wfapi.ErrorIo("failed to write signatures file", sigPath, errRaw)
+	}
+	return nil
+}
+
+// Sign the current CID of a release with an ed25519 key.
+// An existing signature by the same key is replaced, and signatures of an older CID are dropped.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-missing-entry -- when the release does not exist
+//    - warpforge-error-catalog-invalid -- when the release does not match its CID
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-io -- when reading or writing catalog files fails
+//    - warpforge-error-serialization -- when serializing the signatures fails
+func (cat *Catalog) SignRelease(ref wfapi.CatalogRef, key ed25519.PrivateKey) error {
+	release, err := cat.GetRelease(ref)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return wfapi.ErrorMissingCatalogEntry(ref, false)
+	}
+	releaseCid := release.Cid()
+
+	sigs, err := cat.GetSignatures(ref)
+	if err != nil {
+		return err
+	}
+	if sigs == nil || sigs.ReleaseCid != releaseCid {
+		sigs = &wfapi.CatalogReleaseSignatures{ReleaseCid: releaseCid}
+	}
+	sig := wfapi.CatalogSignature{
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(releaseCid))),
+	}
+	replaced := false
+	for i := range sigs.Signatures {
+		if sigs.Signatures[i].PublicKey == sig.PublicKey {
+			sigs.Signatures[i] = sig
+			replaced = true
+		}
+	}
+	if !replaced {
+		sigs.Signatures = append(sigs.Signatures, sig)
+	}
+	return cat.PutSignatures(ref, *sigs)
+}
+
+// Verify that the current CID of a release is signed by at least one of the trusted keys.
+// Keys are base64 encoded ed25519 public keys.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-untrusted -- when no trusted key has signed the release
+//    - warpforge-error-catalog-missing-entry -- when the release does not exist
+//    - warpforge-error-catalog-invalid -- when the release does not match its CID
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-io -- when reading catalog files fails
+func (cat *Catalog) VerifyRelease(ref wfapi.CatalogRef, trustedKeys []string) error {
+	release, err := cat.GetRelease(ref)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return wfapi.ErrorMissingCatalogEntry(ref, false)
+	}
+	releaseCid := release.Cid()
+
+	sigs, err := cat.GetSignatures(ref)
+	if err != nil {
+		return err
+	}
+	if sigs != nil && sigs.ReleaseCid == releaseCid {
+		trusted := make(map[string]struct{}, len(trustedKeys))
+		for _, k := range trustedKeys {
+			trusted[k] = struct{}{}
+		}
+		for _, sig := range sigs.Signatures {
+			if _, ok := trusted[sig.PublicKey]; !ok {
+				continue
+			}
+			pub, errRaw := base64.StdEncoding.DecodeString(sig.PublicKey)
+			if errRaw != nil || len(pub) != ed25519.PublicKeySize {
+				continue
+			}
+			raw, errRaw := base64.StdEncoding.DecodeString(sig.Signature)
+			if errRaw != nil {
+				continue
+			}
+			if ed25519.Verify(ed25519.PublicKey(pub), []byte(releaseCid), raw) {
+				return nil
+			}
+		}
+	}
+	return serum.Error(wfapi.ECodeCatalogUntrusted,
+		serum.WithMessageTemplate("release \"{{module}}:{{release}}\" is not signed by any trusted key"),
+		serum.WithDetail("module", string(ref.ModuleName)),
+		serum.WithDetail("release", string(ref.ReleaseName)),
+		serum.WithDetail("releaseCid", string(releaseCid)),
+	)
+}
+
+// Check that changing a release in the named catalog of this root workspace won't make it untrusted.
+// Any change to a release changes its CID, so its signatures no longer apply afterwards;
+// if the trust policy requires the release to be signed, and it currently is, it would be untrusted until signed again.
+// Releases which the policy doesn't apply to, or which it already doesn't trust, may be changed,
+// as may releases which don't exist yet.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-untrusted -- when the change would make a trusted release untrusted
+//    - warpforge-error-catalog-name -- when the catalog name is invalid
+//    - warpforge-error-catalog-invalid -- when the release does not match its CID
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-io -- when reading the trust policy or catalog files fails
+//    - warpforge-error-serialization -- when the trust policy cannot be parsed
+//    - warpforge-error-datatoonew -- if the trust policy is from a newer version of warpforge
+func (ws *Workspace) CheckReleaseChange(catalogName string, ref wfapi.CatalogRef) error {
+	policy, err := ws.GetTrustPolicy()
+	if err != nil || policy == nil {
+		return err
+	}
+	keys := policy.TrustedKeys(catalogName, ref.ModuleName)
+	if len(keys) == 0 {
+		return nil
+	}
+	cat, err := ws.OpenCatalog(catalogName)
+	if err != nil {
+		return err
+	}
+	err = cat.VerifyRelease(ref, keys)
+	switch serum.Code(err) {
+	case "":
+	case wfapi.ECodeCatalogUntrusted, wfapi.ECodeCatalogMissingEntry:
+		return nil
+	default:
+		return err
+	}
+	return serum.Error(wfapi.ECodeCatalogUntrusted,
+		serum.WithMessageTemplate("changing release \"{{module}}:{{release}}\" would invalidate the signatures the trust policy requires of it"),
+		serum.WithDetail("module", string(ref.ModuleName)),
+		serum.WithDetail("release", string(ref.ReleaseName)),
+	)
+}
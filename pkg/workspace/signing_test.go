@@ -0,0 +1,134 @@
+package workspace
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/pkg/dab"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestCatalogSigning(t *testing.T) {
+	root, rootPath := newTestRootWorkspace(t)
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, magicWorkspaceDirname, "config"), 0755), qt.IsNil)
+	wss := WorkspaceSet{root}
+
+	pub, key, err := ed25519.GenerateKey(nil)
+	qt.Assert(t, err, qt.IsNil)
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	qt.Assert(t, err, qt.IsNil)
+	pubStr := base64.StdEncoding.EncodeToString(pub)
+
+	ref := wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v1", ItemName: "x86_64"}
+	otherRef := wfapi.CatalogRef{ModuleName: "other.org/lib", ReleaseName: "v1", ItemName: "x86_64"}
+	cat, err := root.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}, false), qt.IsNil)
+	qt.Assert(t, cat.AddItem(otherRef, wfapi.WareID{Packtype: "tar", Hash: "bbbbbbbbbb"}, false), qt.IsNil)
+
+	t.Run("verify", func(t *testing.T) {
+		err := cat.VerifyRelease(ref, []string{pubStr})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+
+		qt.Assert(t, cat.SignRelease(ref, otherKey), qt.IsNil)
+		err = cat.VerifyRelease(ref, []string{pubStr})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+
+		qt.Assert(t, cat.SignRelease(ref, key), qt.IsNil)
+		qt.Check(t, cat.VerifyRelease(ref, []string{pubStr}), qt.IsNil)
+		// signing again replaces the signature rather than adding another
+		qt.Assert(t, cat.SignRelease(ref, key), qt.IsNil)
+		sigs, err := cat.GetSignatures(ref)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, sigs.Signatures, qt.HasLen, 2)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		sigs, err := cat.GetSignatures(ref)
+		qt.Assert(t, err, qt.IsNil)
+		tampered := *sigs
+		tampered.Signatures = []wfapi.CatalogSignature{{
+			PublicKey: pubStr,
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(otherKey, []byte(sigs.ReleaseCid))),
+		}}
+		qt.Assert(t, cat.PutSignatures(ref, tampered), qt.IsNil)
+		err = cat.VerifyRelease(ref, []string{pubStr})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+		qt.Assert(t, cat.PutSignatures(ref, *sigs), qt.IsNil)
+	})
+	t.Run("stale", func(t *testing.T) {
+		staleRef := wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v2", ItemName: "x86_64"}
+		qt.Assert(t, cat.AddItem(staleRef, wfapi.WareID{Packtype: "tar", Hash: "cccccccccc"}, false), qt.IsNil)
+		qt.Assert(t, cat.SignRelease(staleRef, key), qt.IsNil)
+		qt.Check(t, cat.VerifyRelease(staleRef, []string{pubStr}), qt.IsNil)
+
+		// changing the release invalidates its signatures
+		staleRef.ItemName = "aarch64"
+		qt.Assert(t, cat.AddItem(staleRef, wfapi.WareID{Packtype: "tar", Hash: "dddddddddd"}, false), qt.IsNil)
+		err := cat.VerifyRelease(staleRef, []string{pubStr})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+	})
+	t.Run("policy", func(t *testing.T) {
+		policyPath := filepath.Join(rootPath, magicWorkspaceDirname, dab.MagicFilename_TrustPolicy)
+		writePolicy := func(policy string) {
+			qt.Assert(t, os.WriteFile(policyPath, []byte(policy), 0644), qt.IsNil)
+		}
+		defer os.Remove(policyPath)
+
+		// without a policy, nothing is checked
+		wareId, _, err := wss.GetCatalogWare(otherRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId, qt.IsNotNil)
+
+		// module prefixes only apply to matching modules
+		writePolicy(fmt.Sprintf(`{"trustpolicy.v1": {"modules": {"example.com/": [%q]}}}`, pubStr))
+		_, _, err = wss.GetCatalogWare(ref)
+		qt.Check(t, err, qt.IsNil)
+		_, _, err = wss.GetCatalogWare(otherRef)
+		qt.Check(t, err, qt.IsNil)
+
+		// catalog entries apply to everything in the catalog
+		writePolicy(fmt.Sprintf(`{"trustpolicy.v1": {"catalogs": {"default": [%q]}}}`, pubStr))
+		_, _, err = wss.GetCatalogWare(ref)
+		qt.Check(t, err, qt.IsNil)
+		_, _, err = wss.GetCatalogWare(otherRef)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+
+		qt.Assert(t, cat.SignRelease(otherRef, key), qt.IsNil)
+		_, _, err = wss.GetCatalogWare(otherRef)
+		qt.Check(t, err, qt.IsNil)
+	})
+	t.Run("change", func(t *testing.T) {
+		policyPath := filepath.Join(rootPath, magicWorkspaceDirname, dab.MagicFilename_TrustPolicy)
+		defer os.Remove(policyPath)
+		changeRef := wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v3", ItemName: "x86_64"}
+		qt.Assert(t, cat.AddItem(changeRef, wfapi.WareID{Packtype: "tar", Hash: "eeeeeeeeee"}, false), qt.IsNil)
+
+		// without a policy, or with one that doesn't apply to the release, any release may be changed
+		qt.Check(t, root.CheckReleaseChange("default", changeRef), qt.IsNil)
+		qt.Assert(t, os.WriteFile(policyPath, []byte(fmt.Sprintf(`{"trustpolicy.v1": {"modules": {"other.org/": [%q]}}}`, pubStr)), 0644), qt.IsNil)
+		qt.Assert(t, cat.SignRelease(changeRef, key), qt.IsNil)
+		qt.Check(t, root.CheckReleaseChange("default", changeRef), qt.IsNil)
+
+		// a release the policy trusts may not be changed, since that would invalidate its signature
+		qt.Assert(t, os.WriteFile(policyPath, []byte(fmt.Sprintf(`{"trustpolicy.v1": {"modules": {"example.com/": [%q]}}}`, pubStr)), 0644), qt.IsNil)
+		err := root.CheckReleaseChange("default", changeRef)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+
+		// once it's changed regardless, it's no longer trusted, so there's nothing further to lose
+		status := wfapi.ReleaseStatus{Kind: wfapi.ReleaseStatusKind_Yanked, Reason: "broken"}
+		qt.Assert(t, cat.SetReleaseStatus(changeRef, &status), qt.IsNil)
+		_, _, err = wss.GetCatalogWare(changeRef)
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogUntrusted)
+		qt.Check(t, root.CheckReleaseChange("default", changeRef), qt.IsNil)
+
+		// nor is a release which doesn't exist yet
+		qt.Check(t, root.CheckReleaseChange("default", wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v4"}), qt.IsNil)
+	})
+}
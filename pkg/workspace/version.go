@@ -0,0 +1,197 @@
+package workspace
+
+import (
+	"sort"
+
+	"github.com/facette/natsort"
+
+	"github.com/warptools/warpforge/pkg/semver"
+	"github.com/warptools/warpforge/wfapi"
+)
+
+// ReleaseVersion returns the version of a release, used for ordering releases and matching constraints.
+// This is the "version" metadata entry, if present, or else the release name.
+// Returns false if neither can be parsed as a version.
+func ReleaseVersion(release *wfapi.CatalogRelease) (semver.Version, bool) {
+	if v, ok := release.Metadata.Values[wfapi.ReleaseMetadataVersion]; ok {
+		return semver.Parse(v)
+	}
+	return semver.Parse(string(release.ReleaseName))
+}
+
+// IsYanked returns true if a release has been yanked, as by Catalog.SetReleaseStatus.
+func IsYanked(release *wfapi.CatalogRelease) bool {
+	return release.Status != nil && release.Status.Kind == wfapi.ReleaseStatusKind_Yanked
+}
+
+// CompareReleases returns -1, 0, or 1 if release a is ordered before, the same as, or after release b.
+// Releases without a version are ordered before all others, by the natural order of their names;
+// releases with the same version are also ordered by name.
+func CompareReleases(a, b *wfapi.CatalogRelease) int {
+	aVersion, aOk := ReleaseVersion(a)
+	bVersion, bOk := ReleaseVersion(b)
+	switch {
+	case aOk && !bOk:
+		return 1
+	case !aOk && bOk:
+		return -1
+	case aOk && bOk:
+		if c := semver.Compare(aVersion, bVersion); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a.ReleaseName == b.ReleaseName:
+		return 0
+	case natsort.Compare(string(a.ReleaseName), string(b.ReleaseName)):
+		return -1
+	}
+	return 1
+}
+
+// OrderedReleases returns every release of a module, from the lowest version to the highest.
+// Returns nil if the module does not exist. Releases listed by the module without a release file are skipped.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a release does not match its CID
+func (cat *Catalog) OrderedReleases(moduleName wfapi.ModuleName) ([]wfapi.CatalogRelease, error) {
+	ref := wfapi.CatalogRef{ModuleName: moduleName}
+	module, err := cat.GetModule(ref)
+	if err != nil || module == nil {
+		return nil, err
+	}
+	releases := make([]wfapi.CatalogRelease, 0, len(module.Releases.Keys))
+	for _, releaseName := range module.Releases.Keys {
+		ref.ReleaseName = releaseName
+		release, err := cat.GetRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			releases = append(releases, *release)
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return CompareReleases(&releases[i], &releases[j]) < 0
+	})
+	return releases, nil
+}
+
+// ResolveCatalogRef resolves a reference whose release name is a version constraint
+// to the highest release matching the constraint which has the referenced item and is not yanked,
+// considering every catalog in the workspace set.
+// If several catalogs have the same release, the ware is found as for any other reference,
+// by GetCatalogWare's lookup order.
+// References which aren't constraints are returned unchanged.
+//
+// Errors:
+//
+//    - warpforge-error-release-constraint -- when the constraint is invalid
+//    - warpforge-error-catalog-missing-entry -- when no release matches the constraint
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog or release is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+func (wsSet WorkspaceSet) ResolveCatalogRef(ref wfapi.CatalogRef) (wfapi.CatalogRef, error) {
+	if !semver.IsConstraint(string(ref.ReleaseName)) {
+		return ref, nil
+	}
+	constraint, err := semver.ParseConstraint(string(ref.ReleaseName))
+	if err != nil {
+		return ref, err
+	}
+	best, err := wsSet.highestRelease(ref, func(release *wfapi.CatalogRelease, v semver.Version) bool {
+		return !IsYanked(release) && constraint.Match(v)
+	})
+	if err != nil {
+		return ref, err
+	}
+	if best == nil {
+		return ref, wfapi.ErrorMissingCatalogEntry(ref, false)
+	}
+	ref.ReleaseName = best.ReleaseName
+	return ref, nil
+}
+
+// NewerRelease returns the highest release of the referenced module which is ordered after the referenced release,
+// and has the referenced item, considering every catalog in the workspace set.
+// Only releases with a version which are not yanked are considered, and prereleases only if the referenced release is one.
+// Returns nil if the referenced release has no version, or there is no newer release.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog or release is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+func (wsSet WorkspaceSet) NewerRelease(ref wfapi.CatalogRef) (*wfapi.CatalogRelease, error) {
+	// the release may carry an explicit version, so find it to compare against;
+	// if it isn't in any catalog, its name is all there is to go on.
+	current := &wfapi.CatalogRelease{ReleaseName: ref.ReleaseName}
+	found, err := wsSet.highestRelease(ref, func(release *wfapi.CatalogRelease, v semver.Version) bool {
+		return release.ReleaseName == ref.ReleaseName
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found != nil {
+		current = found
+	}
+	currentVersion, ok := ReleaseVersion(current)
+	if !ok {
+		return nil, nil
+	}
+	return wsSet.highestRelease(ref, func(release *wfapi.CatalogRelease, v semver.Version) bool {
+		if IsYanked(release) || (len(v.Prerelease) > 0 && len(currentVersion.Prerelease) == 0) {
+			return false
+		}
+		return CompareReleases(release, current) > 0
+	})
+}
+
+// highestRelease returns the highest release of the referenced module which has the referenced item,
+// has a version, and is accepted by the filter, considering every catalog in the workspace set.
+// Returns nil if there is no such release.
+//
+// Errors:
+//
+//    - warpforge-error-io -- when reading catalog files fails
+//    - warpforge-error-catalog-parse -- when parsing catalog files fails
+//    - warpforge-error-catalog-invalid -- when a catalog or release is invalid
+//    - warpforge-error-catalog-name -- when a catalog name is invalid
+func (wsSet WorkspaceSet) highestRelease(ref wfapi.CatalogRef, accept func(*wfapi.CatalogRelease, semver.Version) bool) (*wfapi.CatalogRelease, error) {
+	var best *wfapi.CatalogRelease
+	for _, ws := range wsSet {
+		cats, err := ws.ListCatalogs()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cats {
+			cat, err := ws.OpenCatalog(c)
+			if err != nil {
+				return nil, err
+			}
+			releases, err := cat.OrderedReleases(ref.ModuleName)
+			if err != nil {
+				return nil, err
+			}
+			for i := len(releases) - 1; i >= 0; i-- {
+				release := releases[i]
+				if best != nil && CompareReleases(&release, best) <= 0 {
+					break
+				}
+				if _, ok := release.Items.Values[ref.ItemName]; !ok {
+					continue
+				}
+				if v, ok := ReleaseVersion(&release); ok && accept(&release, v) {
+					best = &release
+					break
+				}
+			}
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,144 @@
+package workspace
+
+import (
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func TestReleaseOrdering(t *testing.T) {
+	root, _ := newTestRootWorkspace(t)
+	wss := WorkspaceSet{root}
+
+	addRelease := func(cat Catalog, releaseName wfapi.ReleaseName, version string, items ...wfapi.ItemLabel) {
+		release := wfapi.CatalogRelease{ReleaseName: releaseName}
+		release.Items.Values = map[wfapi.ItemLabel]wfapi.WareID{}
+		for _, item := range items {
+			release.Items.Keys = append(release.Items.Keys, item)
+			release.Items.Values[item] = wfapi.WareID{Packtype: "tar", Hash: string(releaseName) + string(item)}
+		}
+		release.Metadata.Values = map[string]string{}
+		if version != "" {
+			release.Metadata.Keys = []string{wfapi.ReleaseMetadataVersion}
+			release.Metadata.Values[wfapi.ReleaseMetadataVersion] = version
+		}
+		qt.Assert(t, cat.AddRelease(wfapi.CatalogModule{Name: "example.com/gcc"}, release, nil, false), qt.IsNil)
+	}
+	main, err := root.CreateOrOpenCatalog("main")
+	qt.Assert(t, err, qt.IsNil)
+	addRelease(main, "v1.10.0", "", "amd64", "arm64")
+	addRelease(main, "v1.2.0", "", "amd64", "arm64")
+	addRelease(main, "v1.9.0", "", "amd64")
+	addRelease(main, "v2.0.0-rc1", "", "amd64")
+	addRelease(main, "nightly", "", "amd64")
+	addRelease(main, "stable", "1.5.0", "amd64")
+	other, err := root.CreateOrOpenCatalog("other")
+	qt.Assert(t, err, qt.IsNil)
+	addRelease(other, "v1.11.0", "", "arm64")
+
+	releases, err := main.OrderedReleases("example.com/gcc")
+	qt.Assert(t, err, qt.IsNil)
+	var names []wfapi.ReleaseName
+	for _, r := range releases {
+		names = append(names, r.ReleaseName)
+	}
+	qt.Check(t, names, qt.DeepEquals, []wfapi.ReleaseName{"nightly", "v1.2.0", "stable", "v1.9.0", "v1.10.0", "v2.0.0-rc1"})
+
+	resolve := func(releaseName wfapi.ReleaseName, item wfapi.ItemLabel) (wfapi.ReleaseName, error) {
+		ref, err := wss.ResolveCatalogRef(wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: releaseName, ItemName: item})
+		return ref.ReleaseName, err
+	}
+	for _, tc := range []struct {
+		constraint wfapi.ReleaseName
+		item       wfapi.ItemLabel
+		expected   wfapi.ReleaseName
+	}{
+		{"^1.2", "amd64", "v1.10.0"},
+		{"^1.2", "arm64", "v1.11.0"}, // from the other catalog
+		{"~1.5", "amd64", "stable"},  // by its version metadata
+		{"<1.9", "arm64", "v1.2.0"},
+		{"*", "amd64", "v1.10.0"}, // prereleases are excluded
+		{">=2.0.0-rc1", "amd64", "v2.0.0-rc1"},
+		{"v1.2.0", "amd64", "v1.2.0"}, // not a constraint
+	} {
+		actual, err := resolve(tc.constraint, tc.item)
+		qt.Check(t, err, qt.IsNil)
+		qt.Check(t, actual, qt.Equals, tc.expected, qt.Commentf("%s for %s", tc.constraint, tc.item))
+	}
+	_, err = resolve("^3", "amd64")
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogMissingEntry)
+	_, err = resolve("^x", "amd64")
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeReleaseConstraint)
+
+	wareId, _, err := wss.GetCatalogWare(wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: "^1.2", ItemName: "arm64"})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, wareId, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "v1.11.0arm64"})
+	wareId, _, err = wss.GetCatalogWare(wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: "^3", ItemName: "arm64"})
+	qt.Check(t, err, qt.IsNil)
+	qt.Check(t, wareId, qt.IsNil)
+}
+
+func TestConstraintReleaseName(t *testing.T) {
+	cat, err := OpenCatalog(os.DirFS("/"), t.TempDir())
+	qt.Assert(t, err, qt.IsNil)
+	err = cat.AddItem(wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: "^1.2", ItemName: "amd64"}, wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}, false)
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogInvalid)
+}
+
+func TestYankedRelease(t *testing.T) {
+	root, _ := newTestRootWorkspace(t)
+	wss := WorkspaceSet{root}
+	cat, err := root.CreateOrOpenCatalog("main")
+	qt.Assert(t, err, qt.IsNil)
+	for _, releaseName := range []wfapi.ReleaseName{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		ref := wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: releaseName, ItemName: "amd64"}
+		qt.Assert(t, cat.AddItem(ref, wfapi.WareID{Packtype: "tar", Hash: string(releaseName)}, false), qt.IsNil)
+	}
+	ref := func(releaseName wfapi.ReleaseName) wfapi.CatalogRef {
+		return wfapi.CatalogRef{ModuleName: "example.com/gcc", ReleaseName: releaseName, ItemName: "amd64"}
+	}
+
+	before, err := cat.GetRelease(ref("v1.2.0"))
+	qt.Assert(t, err, qt.IsNil)
+	replacement := wfapi.ReleaseName("v1.1.0")
+	status := &wfapi.ReleaseStatus{Kind: wfapi.ReleaseStatusKind_Yanked, Reason: "miscompiles", Replacement: &replacement}
+	qt.Assert(t, cat.SetReleaseStatus(ref("v1.2.0"), status), qt.IsNil)
+
+	// the module is updated with the new CID, so the release still loads
+	after, err := wss.GetCatalogRelease(ref("v1.2.0"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, after.Status, qt.DeepEquals, status)
+	qt.Check(t, after.Cid(), qt.Not(qt.Equals), before.Cid())
+	qt.Check(t, status.Message("example.com/gcc", "v1.2.0"), qt.Equals, `release "example.com/gcc:v1.2.0" is yanked: miscompiles; use "v1.1.0" instead`)
+
+	// yanked releases are skipped by constraints and upgrades, but can still be used directly
+	resolved, err := wss.ResolveCatalogRef(ref("^1"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, resolved.ReleaseName, qt.Equals, wfapi.ReleaseName("v1.1.0"))
+	newer, err := wss.NewerRelease(ref("v1.0.0"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, newer.ReleaseName, qt.Equals, wfapi.ReleaseName("v1.1.0"))
+	wareId, _, err := wss.GetCatalogWare(ref("v1.2.0"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, wareId, qt.DeepEquals, &wfapi.WareID{Packtype: "tar", Hash: "v1.2.0"})
+
+	// deprecated releases are still resolved
+	qt.Assert(t, cat.SetReleaseStatus(ref("v1.2.0"), &wfapi.ReleaseStatus{Kind: wfapi.ReleaseStatusKind_Deprecated, Reason: "old"}), qt.IsNil)
+	resolved, err = wss.ResolveCatalogRef(ref("^1"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, resolved.ReleaseName, qt.Equals, wfapi.ReleaseName("v1.2.0"))
+
+	// clearing the status restores the original release
+	qt.Assert(t, cat.SetReleaseStatus(ref("v1.2.0"), nil), qt.IsNil)
+	after, err = cat.GetRelease(ref("v1.2.0"))
+	qt.Assert(t, err, qt.IsNil)
+	qt.Check(t, after.Cid(), qt.Equals, before.Cid())
+
+	missing := wfapi.ReleaseName("v9")
+	err = cat.SetReleaseStatus(ref("v1.2.0"), &wfapi.ReleaseStatus{Kind: wfapi.ReleaseStatusKind_Yanked, Reason: "x", Replacement: &missing})
+	qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeCatalogMissingEntry)
+}
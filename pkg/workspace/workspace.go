@@ -143,6 +143,15 @@ func (ws *Workspace) WarePath(wareId wfapi.WareID) (string, error) {
 	), nil
 }
 
+// HasWare returns true if the ware is present in the workspace's warehouse.
+func (ws *Workspace) HasWare(wareId wfapi.WareID) bool {
+	if len(wareId.Hash) < 7 {
+		return false
+	}
+	_, err := fs.Stat(ws.fsys, filepath.Join(ws.WarehousePath(), wareId.Subpath()))
+	return err == nil
+}
+
 // IsRootWorkspace returns true if the workspace is a root workspace
 func (ws *Workspace) IsRootWorkspace() bool {
 	return ws.isRootWorkspace
@@ -165,6 +174,15 @@ func (ws *Workspace) MemoPath(fid string) string {
 	)
 }
 
+// Returns the base path which records the WareIDs produced by ingests (e.g., `.../.warpforge/ingests`)
+func (ws *Workspace) IngestBasePath() string {
+	return filepath.Join(
+		"/",
+		ws.InternalPath(),
+		"ingests",
+	)
+}
+
 // Returns the base path which contains named catalogs (e.g., `.../.warpforge/catalogs`)
 func (ws *Workspace) CatalogBasePath() string {
 	return filepath.Join(
@@ -277,6 +295,20 @@ func (ws *Workspace) ListCatalogs() ([]string, error) {
 //     - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
 //     - warpforge-error-catalog-missing-entry -- when catalog item is missing
 func (ws *Workspace) GetCatalogWare(ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi.WarehouseAddr, error) {
+	return ws.getCatalogWare(ref, nil)
+}
+
+// getCatalogWare is GetCatalogWare, additionally enforcing a trust policy if one is given.
+// Catalog entries in the policy only apply to the catalogs of a root workspace.
+//
+// Errors:
+//
+//     - warpforge-error-io -- when reading of lineage or mirror files fails
+//     - warpforge-error-catalog-parse -- when ipld parsing of lineage or mirror files fails
+//     - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
+//     - warpforge-error-catalog-missing-entry -- when catalog item is missing
+//     - warpforge-error-catalog-untrusted -- when the release is not signed by a key the policy requires
+func (ws *Workspace) getCatalogWare(ref wfapi.CatalogRef, policy *wfapi.TrustPolicy) (*wfapi.WareID, *wfapi.WarehouseAddr, error) {
 	// list the catalogs within the "catalogs" subdirectory
 	cats, err := ws.ListCatalogs()
 	if err != nil {
@@ -302,6 +334,13 @@ func (ws *Workspace) GetCatalogWare(ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi
 			// not found in this catalog, keep trying
 			continue
 		}
+		if policy != nil {
+			if keys := policy.TrustedKeys(c, ref.ModuleName); len(keys) > 0 {
+				if err := cat.VerifyRelease(ref, keys); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
 		return wareId, wareAddr, nil
 	}
 
@@ -383,6 +422,43 @@ func (ws *Workspace) CreateOrOpenCatalog(name string) (Catalog, error) {
 	return ws.OpenCatalog(name)
 }
 
+// Get a catalog release from a workspace, doing lookup by CatalogRef.
+// Catalogs are checked in the same order as by GetCatalogReplay.
+//
+// Errors:
+//
+//     - warpforge-error-io -- when reading of release files fails
+//     - warpforge-error-catalog-parse -- when ipld parsing of release files fails
+//     - warpforge-error-catalog-invalid -- when a release does not match its CID
+func (ws *Workspace) GetCatalogRelease(ref wfapi.CatalogRef) (*wfapi.CatalogRelease, error) {
+	cats, err := ws.ListCatalogs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cats {
+		cat, err := ws.OpenCatalog(c)
+		if err != nil {
+			switch serum.Code(err) {
+			case "warpforge-error-catalog-name":
+				// This shouldn't happen
+				panic(err)
+			default:
+				// Error Codes -= warpforge-error-catalog-name
+				return nil, err
+			}
+		}
+		release, err := cat.GetRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			return release, nil
+		}
+	}
+	return nil, nil
+}
+
 // Get a catalog replay from a workspace, doing lookup by CatalogRef.
 // In a root workspace this will check valid catalogs within the "catalogs" subdirectory
 // In a non-root workspace, it will check the "catalog" subdirectory
@@ -445,6 +521,53 @@ func (ws *Workspace) GetMirroringConfig() (wfapi.MirroringConfig, error) {
 	return dab.MirroringConfigFromFile(ws.fsys, filepath.Join(ws.InternalPath(), dab.MagicFilename_MirroringConfig))
 }
 
+// GetTrustPolicy will return the TrustPolicy for this workspace,
+// which is read from the .warpforge/config/trust.json file.
+// Returns nil if the workspace has no trust policy.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors reading from fsys.
+// 	- warpforge-error-serialization -- for errors from try to parse the data as a TrustPolicy.
+// 	- warpforge-error-datatoonew -- if the policy is from a newer version of warpforge
+func (ws *Workspace) GetTrustPolicy() (*wfapi.TrustPolicy, error) {
+	policy, err := dab.TrustPolicyFromFile(ws.fsys, filepath.Join(ws.InternalPath(), dab.MagicFilename_TrustPolicy))
+	if serum.Code(err) == wfapi.ECodeMissing {
+		return nil, nil
+	}
+	// Error Codes -= warpforge-error-missing
+	return policy, err
+}
+
+// GetSubscriptionConfig will return the SubscriptionConfig for this workspace,
+// which is read from the .warpforge/config/subscriptions.json file.
+// Returns nil if the workspace has no subscription config.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors reading from fsys.
+// 	- warpforge-error-serialization -- for errors from try to parse the data as a SubscriptionConfig.
+// 	- warpforge-error-datatoonew -- if the config is from a newer version of warpforge
+func (ws *Workspace) GetSubscriptionConfig() (*wfapi.SubscriptionConfig, error) {
+	cfg, err := dab.SubscriptionConfigFromFile(ws.fsys, filepath.Join(ws.InternalPath(), dab.MagicFilename_Subscriptions))
+	if serum.Code(err) == wfapi.ECodeMissing {
+		return nil, nil
+	}
+	// Error Codes -= warpforge-error-missing
+	return cfg, err
+}
+
+// SetSubscriptionConfig will write the SubscriptionConfig for this workspace
+// to the .warpforge/config/subscriptions.json file.
+//
+// Errors:
+//
+// 	- warpforge-error-io -- for errors writing the file.
+// 	- warpforge-error-serialization -- if the config cannot be serialized.
+func (ws *Workspace) SetSubscriptionConfig(cfg wfapi.SubscriptionConfig) error {
+	return dab.SubscriptionConfigToFile(cfg, filepath.Join("/", ws.InternalPath(), dab.MagicFilename_Subscriptions))
+}
+
 // StoreMemo will save a run record to the workspace
 //
 // Errors:
@@ -32,15 +32,36 @@ func (wsSet WorkspaceSet) Root() *Workspace {
 //  2. looks through all catalogs (within the "catalogs" dir) of the root workspace
 //     in alphabetical order, picking the first matching ware found.
 //
+// If the root workspace has a trust policy which applies to the matching release,
+// the release must be signed by one of the keys the policy trusts for it.
+//
+// If the reference's release name is a version constraint, it's resolved first, as by ResolveCatalogRef.
+//
 // Errors:
 //
 //     - warpforge-error-io -- when an IO error occurs while reading the catalog entry
+//     - warpforge-error-release-constraint -- when the reference has an invalid version constraint
 //     - warpforge-error-catalog-parse -- when ipld parsing of a catalog entry fails
 //     - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
+//     - warpforge-error-catalog-untrusted -- when the root workspace's trust policy applies to the release, and it is not signed by a trusted key
+//     - warpforge-error-serialization -- when the root workspace's trust policy can't be parsed
+//     - warpforge-error-datatoonew -- when the root workspace's trust policy is from a newer version of warpforge
 func (wsSet WorkspaceSet) GetCatalogWare(ref wfapi.CatalogRef) (*wfapi.WareID, *wfapi.WarehouseAddr, error) {
+	ref, err := wsSet.ResolveCatalogRef(ref)
+	if err != nil {
+		if serum.Code(err) == wfapi.ECodeCatalogMissingEntry {
+			return nil, nil, nil
+		}
+		// Error Codes -= warpforge-error-catalog-missing-entry
+		return nil, nil, err
+	}
+	policy, err := wsSet.Root().GetTrustPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
 	// traverse workspace stack
 	for _, ws := range wsSet {
-		wareId, wareAddr, err := ws.GetCatalogWare(ref)
+		wareId, wareAddr, err := ws.getCatalogWare(ref, policy)
 		if errors.Is(err, fs.ErrNotExist) {
 			continue
 		}
@@ -59,18 +80,61 @@ func (wsSet WorkspaceSet) GetCatalogWare(ref wfapi.CatalogRef) (*wfapi.WareID, *
 	return nil, nil, nil
 }
 
+// Get a catalog release from a workspace set, looking through catalogs in the same order as GetCatalogReplay.
+// If the reference's release name is a version constraint, it's resolved first, as by ResolveCatalogRef.
+// Returns nil if the release does not exist.
+//
+// Errors:
+//
+//     - warpforge-error-io -- when an IO error occurs while reading the catalog entry
+//     - warpforge-error-catalog-parse -- when ipld parsing of a catalog entry fails
+//     - warpforge-error-catalog-invalid -- when a release does not match its CID
+//     - warpforge-error-catalog-name -- when a catalog name is invalid
+//     - warpforge-error-release-constraint -- when the reference has an invalid version constraint
+func (wsSet WorkspaceSet) GetCatalogRelease(ref wfapi.CatalogRef) (*wfapi.CatalogRelease, error) {
+	ref, err := wsSet.ResolveCatalogRef(ref)
+	if err != nil {
+		if serum.Code(err) == wfapi.ECodeCatalogMissingEntry {
+			return nil, nil
+		}
+		// Error Codes -= warpforge-error-catalog-missing-entry
+		return nil, err
+	}
+	for _, ws := range wsSet {
+		release, err := ws.GetCatalogRelease(ref)
+		if err != nil {
+			return nil, err
+		}
+		if release != nil {
+			return release, nil
+		}
+	}
+	return nil, nil
+}
+
 // Get a catalog replay from a workspace set.
 // Looks up a ware by CatalogRef, traversing the workspace set:
 //  1. traverses the workspace stack looking in "catalog" dirs.
 //  2. looks through all catalogs (within the "catalogs" dir) of the root workspace
 //     in alphabetical order, picking the first matching ware found.
 //
+// If the reference's release name is a version constraint, it's resolved first, as by ResolveCatalogRef.
+//
 // Errors:
 //
 //     - warpforge-error-io -- when an IO error occurs while reading the catalog entry
 //     - warpforge-error-catalog-parse -- when ipld parsing of a catalog entry fails
 //     - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
+//     - warpforge-error-release-constraint -- when the reference has an invalid version constraint
 func (wsSet WorkspaceSet) GetCatalogReplay(ref wfapi.CatalogRef) (*wfapi.Plot, error) {
+	ref, err := wsSet.ResolveCatalogRef(ref)
+	if err != nil {
+		if serum.Code(err) == wfapi.ECodeCatalogMissingEntry {
+			return nil, nil
+		}
+		// Error Codes -= warpforge-error-catalog-missing-entry
+		return nil, err
+	}
 	// traverse workspace stack
 	for _, ws := range wsSet {
 		replay, err := ws.GetCatalogReplay(ref)
@@ -85,94 +149,270 @@ func (wsSet WorkspaceSet) GetCatalogReplay(ref wfapi.CatalogRef) (*wfapi.Plot, e
 	return nil, nil
 }
 
+// Get every mirror address the catalogs of a workspace set give for the ware of a catalog item,
+// in the order they should be tried.
+// Catalogs are checked in the same order as by GetCatalogWare, and each catalog's mirrors are listed as by Catalog.GetWareMirrors.
+// Catalogs whose item has a different WareID are ignored, so their mirrors are never asked for the wrong ware.
+// Returns no addresses if no catalog has the item.
+//
+// If the reference's release name is a version constraint, it's resolved first, as by ResolveCatalogRef.
+//
+// Errors:
+//
+//     - warpforge-error-io -- when an IO error occurs while reading the catalog entry
+//     - warpforge-error-catalog-parse -- when ipld parsing of a catalog entry fails
+//     - warpforge-error-catalog-invalid -- when ipld parsing of lineage or mirror files fails
+//     - warpforge-error-release-constraint -- when the reference has an invalid version constraint
+func (wsSet WorkspaceSet) GetWareMirrors(ref wfapi.CatalogRef, wareId wfapi.WareID) ([]wfapi.WarehouseAddr, error) {
+	ref, err := wsSet.ResolveCatalogRef(ref)
+	if err != nil {
+		if serum.Code(err) == wfapi.ECodeCatalogMissingEntry {
+			return nil, nil
+		}
+		// Error Codes -= warpforge-error-catalog-missing-entry
+		return nil, err
+	}
+	var addrs []wfapi.WarehouseAddr
+	seen := map[wfapi.WarehouseAddr]bool{}
+	for _, ws := range wsSet {
+		cats, err := ws.ListCatalogs()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cats {
+			cat, err := ws.OpenCatalog(c)
+			if err != nil {
+				switch serum.Code(err) {
+				case "warpforge-error-catalog-name":
+					// This shouldn't happen
+					panic(err)
+				default:
+					// Error Codes -= warpforge-error-catalog-name
+					return nil, err
+				}
+			}
+			catWareId, catAddrs, err := cat.GetWareMirrors(ref)
+			if serum.Code(err) == wfapi.ECodeCatalogMissingEntry {
+				continue
+			}
+			if err != nil {
+				// Error Codes -= warpforge-error-catalog-missing-entry
+				return nil, err
+			}
+			if catWareId == nil || *catWareId != wareId {
+				continue
+			}
+			for _, addr := range catAddrs {
+				if !seen[addr] {
+					seen[addr] = true
+					addrs = append(addrs, addr)
+				}
+			}
+		}
+	}
+	return addrs, nil
+}
+
+// TidyConfig controls what Tidy bundles into the local workspace.
+type TidyConfig struct {
+	// Force overwrites entries in the local catalog which differ from the ones being bundled.
+	Force bool
+	// FetchWare, if set, is used to bring the wares of all bundled catalog items into the local workspace's warehouse.
+	// It returns false if the ware could not be found anywhere.
+	// This is normally fetch.TidyFetcher, which verifies every ware it fetches.
+	FetchWare func(ctx context.Context, wareId wfapi.WareID, ref wfapi.CatalogRef) (bool, error)
+}
+
 // Tidy will bundle plot dependencies into the local workspace from any workspace in the set.
 // The local workspace must be a non-root workspace
 //
+// Every catalog reference used by the plot is bundled, including those used directly by protoformulas.
+// If a bundled item has a replay, the replay is bundled as well,
+// along with the catalog references it uses, transitively.
+// If the config has a FetchWare function, the wares of bundled items are fetched into the local workspace's warehouse with it.
+// Wares which are not available but have a replay are skipped, since they can be rebuilt.
+//
 // Errors:
 //
 //    - warpforge-error-catalog-invalid -- a catalog in this workspace set is invalid
 //    - warpforge-error-catalog-parse -- a catalog in this workspace set can't be parsed
 //    - warpforge-error-catalog-missing-entry -- a dependency can't be found
+//    - warpforge-error-release-constraint -- a dependency has an invalid version constraint
 //    - warpforge-error-catalog-name -- honestly, shouldn't happen
+//    - warpforge-error-already-exists -- a local catalog item differs and force is not set
 //    - warpforge-error-workspace -- workspace stack missing a non-root workspace
-//    - warpforge-error-io -- reading/writing catalog fails
-func (wsSet WorkspaceSet) Tidy(ctx context.Context, plot wfapi.Plot, force bool) error {
+//    - warpforge-error-io -- reading/writing catalog or wares fails
+//    - warpforge-error-serialization -- writing catalog fails
+//    - warpforge-error-missing -- a ware has no replay and can't be found in any warehouse or mirror
+//    - warpforge-error-connection -- when fetching a ware fails
+//    - warpforge-error-ware-corrupt -- when a fetched ware doesn't match its WareID
+//    - warpforge-error-executor-failed -- when a fetched ware cannot be verified
+func (wsSet WorkspaceSet) Tidy(ctx context.Context, plot wfapi.Plot, cfg TidyConfig) error {
+	logger := logging.Ctx(ctx)
 	local := wsSet.Local()
 	if local.IsRootWorkspace() {
 		_, path := local.Path()
 		return wfapi.ErrorWorkspace(path, fmt.Errorf("workspace stack needs a non-root workspace"))
 	}
 
-	refs := gatherCatalogRefs(plot)
-
 	cat, err := local.CreateOrOpenCatalog("")
 	if err != nil {
 		return err
 	}
+
+	// refs grows as replays are found, until the closure is complete
+	refs := plot.CatalogRefs()
+	seen := make(map[wfapi.CatalogRef]struct{}, len(refs))
 	for _, ref := range refs {
+		seen[ref] = struct{}{}
+	}
+	for i := 0; i < len(refs); i++ {
+		// version constraints are bundled as the release they currently resolve to
+		ref, err := wsSet.ResolveCatalogRef(refs[i])
+		if err != nil {
+			return err
+		}
 		wareId, wareAddr, err := wsSet.GetCatalogWare(ref)
 		if err != nil {
 			return err
 		}
-
 		if wareId == nil {
-			return wfapi.ErrorMissingCatalogEntry(ref, true) // We don't care whether the replay is available?
+			return wfapi.ErrorMissingCatalogEntry(ref, false)
+		}
+		replay, err := wsSet.GetCatalogReplay(ref)
+		if err != nil {
+			return err
 		}
 
-		logger := logging.Ctx(ctx)
-		logger.Info("", "bundled \"%s:%s:%s\"\n", ref.ModuleName, ref.ReleaseName, ref.ItemName)
+		if err := tidyItem(cat, ref, *wareId, wareAddr, replay, cfg.Force); err != nil {
+			return err
+		}
+		if err := wsSet.tidySignatures(cat, ref); err != nil {
+			return err
+		}
+		logger.Info("", "bundled \"%s:%s:%s\"", ref.ModuleName, ref.ReleaseName, ref.ItemName)
+
+		if replay != nil {
+			for _, replayRef := range replay.CatalogRefs() {
+				if _, ok := seen[replayRef]; ok {
+					continue
+				}
+				seen[replayRef] = struct{}{}
+				refs = append(refs, replayRef)
+			}
+		}
 
-		cat.AddItem(ref, *wareId, force)
-		if wareAddr != nil {
-			err := cat.AddByWareMirror(ref, *wareId, *wareAddr)
+		if cfg.FetchWare != nil && !local.HasWare(*wareId) {
+			fetched, err := cfg.FetchWare(ctx, *wareId, ref)
 			if err != nil {
-				logger.Debug("", "error adding ware: %s", err)
+				return err
+			}
+			switch {
+			case fetched:
+				logger.Info("", "bundled ware %q", wareId.String())
+			case replay != nil:
+				logger.Info("", "ware %q for %q is not available; it can be rebuilt from its replay", wareId.String(), ref.String())
+			default:
+				return serum.Error(wfapi.ECodeMissing,
+					serum.WithMessageTemplate("ware {{wareID|q}} for {{ref|q}} is not in any warehouse or mirror, and has no replay"),
+					serum.WithDetail("wareID", wareId.String()),
+					serum.WithDetail("ref", ref.String()),
+				)
 			}
 		}
 	}
 	return nil
 }
 
-// GetWarehouses returns a set of warehouse addresses for all the warehouses in the workspace stack
-func (wsSet WorkspaceSet) GetWarehouseAddresses() []wfapi.WarehouseAddr {
-	result := make([]wfapi.WarehouseAddr, 0, len(wsSet))
-	for _, ws := range wsSet {
-		result = append(result, ws.GetWarehouseAddress())
+// Copies a catalog item, its mirror, and its replay into a catalog.
+// Entries which are already present and identical are left alone.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid -- the catalog is invalid
+//    - warpforge-error-catalog-parse -- the catalog can't be parsed
+//    - warpforge-error-already-exists -- the item differs and overwrite is not set
+//    - warpforge-error-io -- reading/writing catalog fails
+//    - warpforge-error-serialization -- writing catalog fails
+func tidyItem(cat Catalog, ref wfapi.CatalogRef, wareId wfapi.WareID, wareAddr *wfapi.WarehouseAddr, replay *wfapi.Plot, overwrite bool) error {
+	existing, _, err := cat.GetWare(ref)
+	if err != nil && serum.Code(err) != wfapi.ECodeCatalogMissingEntry {
+		return err
 	}
-	return result
-}
-
-func gatherCatalogRefs(plot wfapi.Plot) []wfapi.CatalogRef {
-	refs := []wfapi.CatalogRef{}
-
-	// gather this plot's inputs
-	for _, input := range plot.Inputs.Values {
-		if input.Basis().CatalogRef != nil {
-			refs = append(refs, *input.Basis().CatalogRef)
+	if existing == nil || *existing != wareId {
+		if err := cat.AddItem(ref, wareId, overwrite); err != nil {
+			return err
 		}
 	}
+	if wareAddr != nil {
+		if err := cat.AddByWareMirror(ref, wareId, *wareAddr); err != nil {
+			return err
+		}
+	}
+	if replay != nil {
+		existingReplay, err := cat.GetReplay(ref)
+		if err != nil {
+			return err
+		}
+		if existingReplay == nil || existingReplay.Cid() != replay.Cid() {
+			if err := cat.AddReplay(ref, *replay, overwrite); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	// gather subplot inputs
-	for _, step := range plot.Steps.Values {
-		if step.Plot != nil {
-			// recursively gather the refs from subplot(s)
-			newRefs := gatherCatalogRefs(*step.Plot)
-
-			// deduplicate
-			unique := true
-			for _, newRef := range newRefs {
-				for _, existingRef := range refs {
-					if newRef == existingRef {
-						unique = false
-						break
-					}
-				}
-				if unique {
-					refs = append(refs, newRef)
-				}
+// Copies the signatures of a release into a catalog, from the first other workspace which has the release.
+// Signatures are only copied if they are for the exact release now in the catalog.
+//
+// Errors:
+//
+//    - warpforge-error-catalog-invalid -- a catalog is invalid
+//    - warpforge-error-catalog-parse -- a catalog can't be parsed
+//    - warpforge-error-catalog-name -- honestly, shouldn't happen
+//    - warpforge-error-io -- reading/writing catalog fails
+//    - warpforge-error-serialization -- writing catalog fails
+func (wsSet WorkspaceSet) tidySignatures(cat Catalog, ref wfapi.CatalogRef) error {
+	release, err := cat.GetRelease(ref)
+	if err != nil || release == nil {
+		return err
+	}
+	for _, ws := range wsSet[1:] {
+		names, err := ws.ListCatalogs()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			src, err := ws.OpenCatalog(name)
+			if err != nil {
+				return err
+			}
+			srcRelease, err := src.GetRelease(ref)
+			if err != nil {
+				return err
+			}
+			if srcRelease == nil {
+				continue
 			}
+			sigs, err := src.GetSignatures(ref)
+			if err != nil {
+				return err
+			}
+			if sigs == nil || sigs.ReleaseCid != release.Cid() {
+				return nil
+			}
+			return cat.PutSignatures(ref, *sigs)
 		}
 	}
+	return nil
+}
 
-	return refs
+// GetWarehouses returns a set of warehouse addresses for all the warehouses in the workspace stack
+func (wsSet WorkspaceSet) GetWarehouseAddresses() []wfapi.WarehouseAddr {
+	result := make([]wfapi.WarehouseAddr, 0, len(wsSet))
+	for _, ws := range wsSet {
+		result = append(result, ws.GetWarehouseAddress())
+	}
+	return result
 }
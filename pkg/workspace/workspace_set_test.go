@@ -0,0 +1,139 @@
+package workspace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+	"github.com/serum-errors/go-serum"
+
+	"github.com/warptools/warpforge/wfapi"
+)
+
+func parseTestPlot(t *testing.T, serial string) wfapi.Plot {
+	plotCapsule := wfapi.PlotCapsule{}
+	_, err := ipld.Unmarshal([]byte(serial), json.Decode, &plotCapsule, wfapi.TypeSystem.TypeByName("PlotCapsule"))
+	qt.Assert(t, err, qt.IsNil)
+	return *plotCapsule.Plot
+}
+
+func TestTidy(t *testing.T) {
+	root, rootPath := newTestRootWorkspace(t)
+	localPath := filepath.Join(rootPath, "module")
+	qt.Assert(t, os.MkdirAll(filepath.Join(localPath, magicWorkspaceDirname), 0755), qt.IsNil)
+	fsys := os.DirFS("/")
+	qt.Assert(t, root.IsRootWorkspace(), qt.IsTrue)
+	local, err := OpenWorkspace(fsys, localPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	wss := WorkspaceSet{local, root}
+
+	appRef := wfapi.CatalogRef{ModuleName: "example.com/app", ReleaseName: "v1", ItemName: "x86_64"}
+	toolRef := wfapi.CatalogRef{ModuleName: "example.com/tool", ReleaseName: "v2", ItemName: "x86_64"}
+	libRef := wfapi.CatalogRef{ModuleName: "example.com/lib", ReleaseName: "v1", ItemName: "x86_64"}
+	appWare := wfapi.WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+	toolWare := wfapi.WareID{Packtype: "tar", Hash: "bbbbbbbbbb"}
+	libWare := wfapi.WareID{Packtype: "tar", Hash: "cccccccccc"}
+
+	cat, err := root.CreateOrOpenCatalog("default")
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, cat.AddItem(appRef, appWare, false), qt.IsNil)
+	qt.Assert(t, cat.AddItem(toolRef, toolWare, false), qt.IsNil)
+	qt.Assert(t, cat.AddItem(libRef, libWare, false), qt.IsNil)
+	// the app can be rebuilt from a replay, which uses the tool directly in a protoformula
+	replay := parseTestPlot(t, `{"plot.v1": {
+		"inputs": {},
+		"steps": {
+			"build": {
+				"protoformula": {
+					"inputs": {"/": "catalog:example.com/tool:v2:x86_64"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {"out": {"from": "/out", "packtype": "tar"}}
+				}
+			}
+		},
+		"outputs": {"x86_64": "pipe:build:out"}
+	}}`)
+	qt.Assert(t, cat.AddReplay(appRef, replay, false), qt.IsNil)
+	// only the tool's ware is available
+	fetched := map[wfapi.WareID]wfapi.CatalogRef{}
+	fetchWare := func(ctx context.Context, wareId wfapi.WareID, ref wfapi.CatalogRef) (bool, error) {
+		fetched[wareId] = ref
+		if wareId != toolWare {
+			return false, nil
+		}
+		toolPath, err := local.WarePath(toolWare)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, os.MkdirAll(filepath.Dir(toolPath), 0755), qt.IsNil)
+		qt.Assert(t, os.WriteFile(toolPath, []byte("tool ware"), 0644), qt.IsNil)
+		return true, nil
+	}
+
+	plot := parseTestPlot(t, `{"plot.v1": {
+		"inputs": {},
+		"steps": {
+			"one": {
+				"protoformula": {
+					"inputs": {"/": "catalog:example.com/app:v1:x86_64"},
+					"action": {"script": {"interpreter": "/bin/sh", "contents": ["true"]}},
+					"outputs": {}
+				}
+			}
+		},
+		"outputs": {}
+	}}`)
+	ctx := context.Background()
+
+	t.Run("closure", func(t *testing.T) {
+		qt.Assert(t, wss.Tidy(ctx, plot, TidyConfig{FetchWare: fetchWare}), qt.IsNil)
+		qt.Check(t, fetched, qt.DeepEquals, map[wfapi.WareID]wfapi.CatalogRef{appWare: appRef, toolWare: toolRef})
+
+		localCat, err := local.OpenCatalog("")
+		qt.Assert(t, err, qt.IsNil)
+		wareId, _, err := localCat.GetWare(appRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId, qt.DeepEquals, &appWare)
+		wareId, _, err = localCat.GetWare(toolRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, wareId, qt.DeepEquals, &toolWare)
+		localReplay, err := localCat.GetReplay(appRef)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Assert(t, localReplay, qt.IsNotNil)
+		qt.Check(t, localReplay.Cid(), qt.Equals, replay.Cid())
+
+		qt.Check(t, local.HasWare(toolWare), qt.IsTrue)
+		qt.Check(t, local.HasWare(appWare), qt.IsFalse)
+	})
+	t.Run("idempotent", func(t *testing.T) {
+		fetched = map[wfapi.WareID]wfapi.CatalogRef{}
+		qt.Assert(t, wss.Tidy(ctx, plot, TidyConfig{FetchWare: fetchWare}), qt.IsNil)
+		// wares already in the local warehouse aren't fetched again
+		qt.Check(t, fetched, qt.DeepEquals, map[wfapi.WareID]wfapi.CatalogRef{appWare: appRef})
+	})
+	t.Run("missing-ware", func(t *testing.T) {
+		plot := parseTestPlot(t, `{"plot.v1": {
+			"inputs": {"lib": "catalog:example.com/lib:v1:x86_64"},
+			"steps": {},
+			"outputs": {}
+		}}`)
+		err := wss.Tidy(ctx, plot, TidyConfig{FetchWare: fetchWare})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeMissing)
+		// without wares, the catalog entry alone can be bundled
+		qt.Check(t, wss.Tidy(ctx, plot, TidyConfig{}), qt.IsNil)
+	})
+	t.Run("fetch-failure", func(t *testing.T) {
+		plot := parseTestPlot(t, `{"plot.v1": {
+			"inputs": {"lib": "catalog:example.com/lib:v1:x86_64"},
+			"steps": {},
+			"outputs": {}
+		}}`)
+		fetchWare := func(ctx context.Context, wareId wfapi.WareID, ref wfapi.CatalogRef) (bool, error) {
+			return false, wfapi.ErrorWareCorrupt(wareId, wfapi.WareID{Packtype: "tar", Hash: "dddddddddd"})
+		}
+		err := wss.Tidy(ctx, plot, TidyConfig{FetchWare: fetchWare})
+		qt.Check(t, serum.Code(err), qt.Equals, wfapi.ECodeWareCorrupt)
+	})
+}
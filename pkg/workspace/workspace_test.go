@@ -2,12 +2,24 @@ package workspace
 
 import (
 	"io/fs"
+	"os"
+	"path/filepath"
 	"testing"
 	"testing/fstest"
 
 	qt "github.com/frankban/quicktest"
 )
 
+// constructs a root workspace in a temporary directory, returning it and its path
+func newTestRootWorkspace(t *testing.T) (*Workspace, string) {
+	rootPath := t.TempDir()
+	qt.Assert(t, os.MkdirAll(filepath.Join(rootPath, magicWorkspaceDirname), 0755), qt.IsNil)
+	qt.Assert(t, os.WriteFile(filepath.Join(rootPath, magicWorkspaceDirname, "root"), nil, 0644), qt.IsNil)
+	root, err := OpenWorkspace(os.DirFS("/"), rootPath[1:])
+	qt.Assert(t, err, qt.IsNil)
+	return root, rootPath
+}
+
 func TestOpenWorkspace(t *testing.T) {
 	fsys := fstest.MapFS{
 		"test/.warpforge/root": &fstest.MapFile{Mode: 0755},
@@ -2,6 +2,7 @@ package wfapi
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ipfs/go-cid"
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
@@ -67,6 +68,41 @@ type CatalogModule struct {
 	}
 }
 
+// ReleaseMetadataVersion is the CatalogRelease metadata key for an explicit version,
+// which orders the release instead of the version parsed from its name.
+const ReleaseMetadataVersion = "version"
+
+// ReleaseMetadataReplayVerified is the CatalogRelease metadata key for when the release's replay
+// was last executed and reproduced every item, as an RFC 3339 timestamp.
+const ReleaseMetadataReplayVerified = "replay-verified"
+
+// ReleaseMetadataTagMessage is the CatalogRelease metadata key for the message of the
+// annotated git tag a release was ingested from.
+const ReleaseMetadataTagMessage = "tag-message"
+
+// Well-known metadata keys, for both CatalogModule and CatalogRelease metadata.
+// Where a release has one of these keys, it overrides the module's value for that release.
+// Metadata may have any other keys as well, but these have a defined meaning and format,
+// which is validated when they're written to a catalog.
+const (
+	MetadataHomepage    = "homepage"     // URL of the project's home page.
+	MetadataLicense     = "license"      // SPDX license expression, such as "MIT" or "Apache-2.0 OR MIT".
+	MetadataSource      = "source"       // URL of the project's source code, such as a git repository.
+	MetadataDescription = "description"  // A single line describing the project.
+	MetadataMaintainers = "maintainers"  // Comma separated list of maintainers, such as "Jane Doe <jane@example.com>".
+	MetadataReleaseDate = "release-date" // The date of a release, as YYYY-MM-DD.
+)
+
+// WellKnownMetadata lists the well-known metadata keys in the order they're displayed.
+var WellKnownMetadata = []string{
+	MetadataDescription,
+	MetadataHomepage,
+	MetadataSource,
+	MetadataLicense,
+	MetadataMaintainers,
+	MetadataReleaseDate,
+}
+
 type CatalogRelease struct {
 	ReleaseName ReleaseName
 	Items       struct {
@@ -77,6 +113,30 @@ type CatalogRelease struct {
 		Keys   []string
 		Values map[string]string
 	}
+	Status *ReleaseStatus
+}
+
+type ReleaseStatus struct {
+	Kind        ReleaseStatusKind
+	Reason      string
+	Replacement *ReleaseName
+}
+
+type ReleaseStatusKind string
+
+const (
+	ReleaseStatusKind_Yanked     ReleaseStatusKind = "yanked"
+	ReleaseStatusKind_Deprecated ReleaseStatusKind = "deprecated"
+)
+
+// Message describes the status of a release for a warning, e.g.
+// `release "example.com/foo:v1.0" is yanked: broken build; use "v1.1" instead`.
+func (s *ReleaseStatus) Message(moduleName ModuleName, releaseName ReleaseName) string {
+	msg := fmt.Sprintf("release \"%s:%s\" is %s: %s", moduleName, releaseName, s.Kind, s.Reason)
+	if s.Replacement != nil {
+		msg += fmt.Sprintf("; use %q instead", *s.Replacement)
+	}
+	return msg
 }
 
 func (rel *CatalogRelease) Cid() CatalogReleaseCID {
@@ -105,3 +165,74 @@ func (rel *CatalogRelease) Cid() CatalogReleaseCID {
 type ReplayCapsule struct {
 	Plot *Plot
 }
+
+type CatalogReleaseSignaturesCapsule struct {
+	CatalogReleaseSignatures *CatalogReleaseSignatures
+}
+
+type CatalogReleaseSignatures struct {
+	ReleaseCid CatalogReleaseCID
+	Signatures []CatalogSignature
+}
+
+type CatalogSignature struct {
+	PublicKey string
+	Signature string
+}
+
+type TrustPolicyCapsule struct {
+	TrustPolicy *TrustPolicy
+}
+
+type TrustPolicy struct {
+	Catalogs *struct {
+		Keys   []string
+		Values map[string][]string
+	}
+	Modules *struct {
+		Keys   []string
+		Values map[string][]string
+	}
+}
+
+// TrustedKeys returns the keys which must have signed a release found in the given catalog of the root workspace,
+// combining the entries for the catalog and for every prefix of the module name.
+// Returns nil if the policy does not apply to the release.
+// For catalogs outside the root workspace, the catalog name should be empty.
+func (p *TrustPolicy) TrustedKeys(catalogName string, moduleName ModuleName) []string {
+	var keys []string
+	if p.Catalogs != nil && catalogName != "" {
+		keys = append(keys, p.Catalogs.Values[catalogName]...)
+	}
+	if p.Modules != nil {
+		for _, prefix := range p.Modules.Keys {
+			if strings.HasPrefix(string(moduleName), prefix) {
+				keys = append(keys, p.Modules.Values[prefix]...)
+			}
+		}
+	}
+	return keys
+}
+
+type SubscriptionConfigCapsule struct {
+	SubscriptionConfig *SubscriptionConfig
+}
+
+type SubscriptionConfig struct {
+	Catalogs struct {
+		Keys   []string
+		Values map[string]CatalogSubscription
+	}
+}
+
+type CatalogSubscription struct {
+	Source  string
+	Branch  *string
+	Commit  *string
+	Enabled *bool
+}
+
+// IsEnabled returns true unless the subscription has been explicitly disabled.
+func (s CatalogSubscription) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
@@ -11,16 +11,19 @@ import (
 const (
 	ECodeArgument               = "warpforge-error-invalid-argument"         // ECodeArgument may be used when invalid arguments are provided to the warpforge command line.
 	ECodeAlreadyExists          = "warpforge-error-already-exists"           // ECodeAlreadyExists may be used when _something_ already exists. Specify _what_ when using this code.  Prefer more specific codes.
+	ECodeBundleInvalid          = "warpforge-error-bundle-invalid"           // ECodeBundleInvalid is returned when a transfer bundle is malformed or its contents do not match their hashes.
 	ECodeCatalogInvalid         = "warpforge-error-catalog-invalid"          // ECodeCatalogInvalid may be used when a catalog contains invalid data.
 	ECodeCatalogMissingEntry    = "warpforge-error-catalog-missing-entry"    // ECodeCatalogMissingEntry may be used when a catalog item cannot be found.
 	ECodeCatalogName            = "warpforge-error-catalog-name"             // ECodeCatalogName may be used for invalid catalog names.
 	ECodeCatalogParse           = "warpforge-error-catalog-parse"            // ECodeCatalogParse may be used when parsing catalog data fails.
+	ECodeCatalogUntrusted       = "warpforge-error-catalog-untrusted"        // ECodeCatalogUntrusted is returned when a catalog release is not signed by a key trusted for it.
 	ECodeDataTooNew             = "warpforge-error-datatoonew"               // ErrorDataTooNew is returned when some data was (partially) deserialized, but only enough that we could recognize it as being a newer version of message than this application supports.
 	ECodeExecutorFailed         = "warpforge-error-executor-failed"          // ECodeExecutorFailed wraps executor errors (e.g. runc errors).
 	ECodeFormulaExecutionFailed = "warpforge-error-formula-execution-failed" // EcodeFormulaExecutionFailed wraps generic errors that caused formula execution to fail.
 	ECodeFormulaInvalid         = "warpforge-error-formula-invalid"          // ECodeFormulaInvalid may be used when a formula contains invalid data.
 	ECodeGeneratorFailed        = "warpforge-error-generator-failed"         // ECodeGeneratorFailed may be used when an external plot generator fails.
 	ECodeGit                    = "warpforge-error-git"                      // ECodeGit wraps errors from git libraries or execution.
+	ECodeIngest                 = "warpforge-error-ingest"                   // ECodeIngest is used when an ingest fails to produce a ware, e.g. when a download does not match its expected hash.
 	ECodeInternal               = "warpforge-error-internal"                 // ECodeInternal is used for errors that are internal and cannot be handled by users. Prefer more specific codes.
 	ECodeInvalid                = "warpforge-error-invalid"                  // ECodeInvalid is used when something is invalid. Prefer more specific codes.
 	ECodeIo                     = "warpforge-error-io"                       // ECodeIo wraps generic io errors.
@@ -28,11 +31,14 @@ const (
 	ECodeModuleInvalid          = "warpforge-error-module-invalid"           // ECodeModuleInvalid is returned when a module contains invalid data.
 	ECodePlotExecution          = "warpforge-error-plot-execution-failed"    // ECodePlotExecution is used to wrap errors around plot execution.
 	ECodePlotInvalid            = "warpforge-error-plot-invalid"             // ECodePlotInvalid is returned when a plot contains invalid data.
+	ECodePlotLockDrift          = "warpforge-error-plot-lock-drift"          // ECodePlotLockDrift is returned when catalog references no longer resolve as recorded in a plot lock.
 	ECodePlotStepFailed         = "warpforge-error-plot-step-failed"         // ECodePlotStepFailed is returned execution of a Step within a Plot fails.
+	ECodeReleaseConstraint      = "warpforge-error-release-constraint"       // ECodeReleaseConstraint is returned when a version constraint in place of a release name is invalid.
 	ECodeSearchingFilesystem    = "warpforge-error-searching-filesystem"     // ECodeSearchingFilesystem is used to wrap filesystem searching errors.
 	ECodeSerialization          = "warpforge-error-serialization"            // ECodeSerialization is used for wrapping generic serialization or deserialization failures.
 	ECodeSyscall                = "warpforge-error-syscall"                  // ECodeSyscall is used to wrap generic syscall errors. Prefer more specific codes.
 	ECodeUnknown                = "warpforge-error-unknown"                  // ECodeUnknown is used for unknown errors. Avoid whenever possible.
+	ECodeWareCorrupt            = "warpforge-error-ware-corrupt"             // ECodeWareCorrupt is returned when the content of a ware does not match its WareID.
 	ECodeWareIdInvalid          = "warpforge-error-wareid-invalid"           // ECodeWareIdInvalid is used for parsing malformed ware IDs.
 	ECodeWarePack               = "warpforge-error-ware-pack"                // ECodeWarePack is used when packing a ware fails.
 	ECodeWareUnpack             = "warpforge-error-ware-unpack"              // ECodeWareUnpack is used when unpacking a ware fails.
@@ -166,6 +172,20 @@ func ErrorWarePack(path string, cause error) error {
 	)
 }
 
+// ErrorWareCorrupt is returned when the content of a ware does not match its WareID,
+// as when a mirror serves the wrong file for a ware.
+//
+// Errors:
+//
+//    - warpforge-error-ware-corrupt --
+func ErrorWareCorrupt(wareId WareID, actual WareID) error {
+	return serum.Error(ECodeWareCorrupt,
+		serum.WithMessageTemplate("ware {{wareId}} is corrupt: its content hashes to {{actual}}"),
+		serum.WithDetail("wareId", wareId.String()),
+		serum.WithDetail("actual", actual.String()),
+	)
+}
+
 // ErrorWareIdInvalid is returned when a malformed WareID is parsed
 //
 // Errors:
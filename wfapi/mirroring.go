@@ -15,6 +15,8 @@ type WarehouseMirroringConfig struct {
 
 type WarehousePushConfig struct {
 	S3   *S3PushConfig
+	Fs   *FsPushConfig
+	Http *HttpPushConfig
 	Mock *MockPushConfig
 }
 
@@ -25,5 +27,17 @@ type S3PushConfig struct {
 	Path     *string
 }
 
+type FsPushConfig struct {
+	Path string
+}
+
+type HttpPushConfig struct {
+	Endpoint string
+	Headers  *struct {
+		Keys   []string
+		Values map[string]string
+	}
+}
+
 type MockPushConfig struct {
 }
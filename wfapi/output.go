@@ -9,4 +9,215 @@ type ApiOutput struct {
 	Log         *LogOutput
 	RunRecord   *RunRecord
 	PlotResults *PlotResults
+	PlotPlan    *PlotPlan
+
+	CatalogSearchResults *CatalogSearchResults
+	CatalogDiff          *CatalogDiff
+	ReverseDependencies  *ReverseDependencies
+	TagIngestResults     *TagIngestResults
+	MirrorReport         *MirrorReport
+	MirrorCheckReport    *MirrorCheckReport
+	FetchReport          *FetchReport
+
+	ReplayVerificationReport *ReplayVerificationReport
+}
+
+type CatalogSearchResults struct {
+	Results []CatalogSearchResult
+}
+
+type CatalogSearchResult struct {
+	Workspace string
+	Catalog   string
+	Ref       CatalogRef
+	WareID    WareID
+}
+
+type CatalogDiff struct {
+	Old     string
+	New     string
+	Entries []CatalogDiffEntry
+}
+
+type CatalogDiffEntry struct {
+	Kind    CatalogDiffKind
+	Module  ModuleName
+	Release *ReleaseName
+	Item    *ItemLabel
+	Old     *string
+	New     *string
+}
+
+type CatalogDiffKind string
+
+const (
+	CatalogDiffKind_ModuleAdded    CatalogDiffKind = "module_added"
+	CatalogDiffKind_ModuleRemoved  CatalogDiffKind = "module_removed"
+	CatalogDiffKind_ReleaseAdded   CatalogDiffKind = "release_added"
+	CatalogDiffKind_ReleaseRemoved CatalogDiffKind = "release_removed"
+	CatalogDiffKind_ReleaseChanged CatalogDiffKind = "release_changed"
+	CatalogDiffKind_ItemAdded      CatalogDiffKind = "item_added"
+	CatalogDiffKind_ItemRemoved    CatalogDiffKind = "item_removed"
+	CatalogDiffKind_ItemChanged    CatalogDiffKind = "item_changed"
+	CatalogDiffKind_MirrorAdded    CatalogDiffKind = "mirror_added"
+	CatalogDiffKind_MirrorRemoved  CatalogDiffKind = "mirror_removed"
+)
+
+type ReverseDependencies struct {
+	Target     string
+	Dependents []ReverseDependency
+}
+
+type ReverseDependency struct {
+	Workspace string
+	Catalog   string
+	Module    ModuleName
+	Release   ReleaseName
+	Depth     int
+	Via       []string
+	Possible  bool
+}
+
+type TagIngestResults struct {
+	Module ModuleName
+	Item   ItemLabel
+	Tags   []TagIngestResult
+}
+
+type TagIngestResult struct {
+	Tag      string
+	Release  ReleaseName
+	WareID   WareID
+	Action   TagIngestAction
+	Ingested bool
+}
+
+type TagIngestAction string
+
+const (
+	TagIngestAction_Add    TagIngestAction = "add"
+	TagIngestAction_Exists TagIngestAction = "exists"
+	TagIngestAction_Moved  TagIngestAction = "moved"
+	TagIngestAction_Older  TagIngestAction = "older"
+)
+
+type ReplayVerificationReport struct {
+	Releases []ReplayVerification
+}
+
+type ReplayVerification struct {
+	Module     ModuleName
+	Release    ReleaseName
+	Status     ReplayVerificationStatus
+	Error      *string
+	Items      []ReplayVerificationItem
+	VerifiedAt *string
+}
+
+type ReplayVerificationStatus string
+
+const (
+	ReplayVerificationStatus_Verified ReplayVerificationStatus = "verified"
+	ReplayVerificationStatus_Mismatch ReplayVerificationStatus = "mismatch"
+	ReplayVerificationStatus_Failed   ReplayVerificationStatus = "failed"
+	ReplayVerificationStatus_NoReplay ReplayVerificationStatus = "no_replay"
+)
+
+type ReplayVerificationItem struct {
+	Item     ItemLabel
+	Status   ReplayItemStatus
+	Expected *WareID
+	Produced *WareID
+}
+
+type ReplayItemStatus string
+
+const (
+	ReplayItemStatus_Match    ReplayItemStatus = "match"
+	ReplayItemStatus_Mismatch ReplayItemStatus = "mismatch"
+	ReplayItemStatus_Missing  ReplayItemStatus = "missing"
+	ReplayItemStatus_Extra    ReplayItemStatus = "extra"
+)
+
+type FetchReport struct {
+	Wares []FetchWareResult
 }
+
+type FetchWareResult struct {
+	WareID WareID
+	Ref    *CatalogRef
+	Status FetchStatus
+	Source *WarehouseAddr
+	Errors []string
+}
+
+type FetchStatus string
+
+const (
+	FetchStatus_Present     FetchStatus = "present"
+	FetchStatus_Fetched     FetchStatus = "fetched"
+	FetchStatus_Unavailable FetchStatus = "unavailable"
+	FetchStatus_Unsupported FetchStatus = "unsupported"
+	FetchStatus_Failed      FetchStatus = "failed"
+)
+
+type MirrorReport struct {
+	Warehouses []MirrorWarehouseReport
+}
+
+type MirrorWarehouseReport struct {
+	Warehouse      WarehouseAddr
+	Pushed         int
+	AlreadyPresent int
+	MissingLocally int
+	Failed         int
+	Error          *string
+	Wares          []MirrorWareResult
+}
+
+type MirrorCheckReport struct {
+	Warehouses []MirrorCheckWarehouseReport
+}
+
+type MirrorCheckWarehouseReport struct {
+	Warehouse WarehouseAddr
+	Ok        int
+	Missing   int
+	Corrupt   int
+	Failed    int
+	Rehashed  bool
+	Error     *string
+	Wares     []MirrorCheckWareResult
+}
+
+type MirrorWareResult struct {
+	WareID WareID
+	Ref    CatalogRef
+	Status MirrorPushStatus
+	Error  *string
+}
+
+type MirrorPushStatus string
+
+const (
+	MirrorPushStatus_Pushed         MirrorPushStatus = "pushed"
+	MirrorPushStatus_AlreadyPresent MirrorPushStatus = "already_present"
+	MirrorPushStatus_MissingLocally MirrorPushStatus = "missing_locally"
+	MirrorPushStatus_Failed         MirrorPushStatus = "failed"
+)
+
+type MirrorCheckWareResult struct {
+	WareID WareID
+	Ref    CatalogRef
+	Status MirrorCheckStatus
+	Error  *string
+}
+
+type MirrorCheckStatus string
+
+const (
+	MirrorCheckStatus_Ok      MirrorCheckStatus = "ok"
+	MirrorCheckStatus_Missing MirrorCheckStatus = "missing"
+	MirrorCheckStatus_Corrupt MirrorCheckStatus = "corrupt"
+	MirrorCheckStatus_Failed  MirrorCheckStatus = "failed"
+)
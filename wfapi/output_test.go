@@ -0,0 +1,75 @@
+package wfapi
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/json"
+)
+
+// Each kind of ApiOutput must survive serialization, since that's the only way it's ever used.
+func TestApiOutputRoundTrip(t *testing.T) {
+	ref := CatalogRef{ModuleName: "example.com/foo", ReleaseName: "v1.0", ItemName: "src"}
+	wareId := WareID{Packtype: "tar", Hash: "aaaaaaaaaa"}
+	otherId := WareID{Packtype: "tar", Hash: "bbbbbbbbbb"}
+	errMsg := "connection refused"
+	verifiedAt := "2026-10-19T00:00:00Z"
+	source := WarehouseAddr("ca+https://example.com")
+	for name, out := range map[string]ApiOutput{
+		"catalogsearch": {CatalogSearchResults: &CatalogSearchResults{Results: []CatalogSearchResult{
+			{Workspace: "/home/user", Catalog: "default", Ref: ref, WareID: wareId},
+		}}},
+		"catalogdiff": {CatalogDiff: &CatalogDiff{Old: "default@HEAD~1", New: "default", Entries: []CatalogDiffEntry{
+			{Kind: CatalogDiffKind_ModuleAdded, Module: "example.com/foo"},
+			{Kind: CatalogDiffKind_ItemChanged, Module: ref.ModuleName, Release: &ref.ReleaseName, Item: &ref.ItemName, Old: &wareId.Hash, New: &wareId.Hash},
+		}}},
+		"rdeps": {ReverseDependencies: &ReverseDependencies{Target: ref.String(), Dependents: []ReverseDependency{
+			{Workspace: "/home/user", Catalog: "default", Module: "example.com/bar", Release: "v2", Depth: 1, Via: []string{ref.String()}, Possible: true},
+		}}},
+		"tagingest": {TagIngestResults: &TagIngestResults{Module: ref.ModuleName, Item: ref.ItemName, Tags: []TagIngestResult{
+			{Tag: "v1.0", Release: "v1.0", WareID: wareId, Action: TagIngestAction_Add, Ingested: true},
+		}}},
+		"fetch": {FetchReport: &FetchReport{Wares: []FetchWareResult{
+			{WareID: wareId, Ref: &ref, Status: FetchStatus_Present, Errors: nil},
+			{WareID: wareId, Status: FetchStatus_Fetched, Source: &source, Errors: []string{errMsg}},
+		}}},
+		"mirror": {MirrorReport: &MirrorReport{Warehouses: []MirrorWarehouseReport{
+			{Warehouse: "s3://bucket", Pushed: 1, Failed: 1, Wares: []MirrorWareResult{
+				{WareID: wareId, Ref: ref, Status: MirrorPushStatus_Pushed},
+				{WareID: wareId, Ref: ref, Status: MirrorPushStatus_Failed, Error: &errMsg},
+			}},
+			{Warehouse: "ca+https://example.com", MissingLocally: 1, Wares: []MirrorWareResult{
+				{WareID: wareId, Ref: ref, Status: MirrorPushStatus_MissingLocally},
+			}},
+			{Warehouse: "ca+https://example.org", Error: &errMsg, Wares: nil},
+		}}},
+		"mirrorcheck": {MirrorCheckReport: &MirrorCheckReport{Warehouses: []MirrorCheckWarehouseReport{
+			{Warehouse: "ca+https://example.com", Ok: 1, Corrupt: 1, Rehashed: true, Wares: []MirrorCheckWareResult{
+				{WareID: wareId, Ref: ref, Status: MirrorCheckStatus_Ok},
+				{WareID: wareId, Ref: ref, Status: MirrorCheckStatus_Corrupt, Error: &errMsg},
+			}},
+		}}},
+		"verifyreplay": {ReplayVerificationReport: &ReplayVerificationReport{Releases: []ReplayVerification{
+			{Module: "example.com/foo", Release: "v1.0", Status: ReplayVerificationStatus_Verified, VerifiedAt: &verifiedAt, Items: []ReplayVerificationItem{
+				{Item: "src", Status: ReplayItemStatus_Match, Expected: &wareId, Produced: &wareId},
+				{Item: "docs", Status: ReplayItemStatus_Extra, Produced: &otherId},
+			}},
+			{Module: "example.com/foo", Release: "v1.1", Status: ReplayVerificationStatus_Mismatch, Items: []ReplayVerificationItem{
+				{Item: "src", Status: ReplayItemStatus_Mismatch, Expected: &wareId, Produced: &otherId},
+				{Item: "bin", Status: ReplayItemStatus_Missing, Expected: &otherId},
+			}},
+			{Module: "example.com/bar", Release: "v2", Status: ReplayVerificationStatus_Failed, Error: &errMsg},
+			{Module: "example.com/bar", Release: "v3", Status: ReplayVerificationStatus_NoReplay},
+		}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			serial, err := ipld.Marshal(json.Encode, &out, TypeSystem.TypeByName("ApiOutput"))
+			qt.Assert(t, err, qt.IsNil)
+			var back ApiOutput
+			_, err = ipld.Unmarshal(serial, json.Decode, &back, TypeSystem.TypeByName("ApiOutput"))
+			qt.Assert(t, err, qt.IsNil)
+			qt.Check(t, back, qt.DeepEquals, out)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
 	"github.com/ipld/go-ipld-prime/node/bindnode"
 	"github.com/ipld/go-ipld-prime/schema"
+	"github.com/serum-errors/go-serum"
 )
 
 type PlotCID string
@@ -55,6 +56,79 @@ func (plot *Plot) Cid() PlotCID {
 	return PlotCID(cid)
 }
 
+// CatalogRefs returns every CatalogRef used as an input within the plot,
+// including the inputs of protoformulas and subplots.
+// Each reference appears once, in order of first use.
+func (plot *Plot) CatalogRefs() []CatalogRef {
+	var result []CatalogRef
+	seen := make(map[CatalogRef]struct{})
+	plot.eachInput(func(input PlotInput) {
+		ref := input.Basis().CatalogRef
+		if ref == nil {
+			return
+		}
+		if _, ok := seen[*ref]; ok {
+			return
+		}
+		seen[*ref] = struct{}{}
+		result = append(result, *ref)
+	})
+	return result
+}
+
+// WareIDs returns every WareID used directly as an input within the plot,
+// including the inputs of protoformulas and subplots.
+// Each WareID appears once, in order of first use.
+func (plot *Plot) WareIDs() []WareID {
+	var result []WareID
+	seen := make(map[WareID]struct{})
+	plot.eachInput(func(input PlotInput) {
+		wareId := input.Basis().WareID
+		if wareId == nil {
+			return
+		}
+		if _, ok := seen[*wareId]; ok {
+			return
+		}
+		seen[*wareId] = struct{}{}
+		result = append(result, *wareId)
+	})
+	return result
+}
+
+// RewriteCatalogRefs replaces every CatalogRef used as an input within the plot,
+// including the inputs of protoformulas and subplots, with the result of calling fn on it.
+// The plot is modified in place, so the order of its keys is preserved.
+func (plot *Plot) RewriteCatalogRefs(fn func(CatalogRef) CatalogRef) {
+	plot.eachInput(func(input PlotInput) {
+		basis := input.Basis()
+		if basis.CatalogRef == nil {
+			return
+		}
+		ref := fn(*basis.CatalogRef)
+		basis.CatalogRef = &ref
+	})
+}
+
+// eachInput calls fn on every input of the plot, then on the inputs of its steps in order,
+// recursing into subplots.
+func (plot *Plot) eachInput(fn func(PlotInput)) {
+	for _, label := range plot.Inputs.Keys {
+		fn(plot.Inputs.Values[label])
+	}
+	for _, name := range plot.Steps.Keys {
+		step := plot.Steps.Values[name]
+		switch {
+		case step.Protoformula != nil:
+			for _, port := range step.Protoformula.Inputs.Keys {
+				fn(step.Protoformula.Inputs.Values[port])
+			}
+		case step.Plot != nil:
+			step.Plot.eachInput(fn)
+		}
+	}
+}
+
 // StepName is for assigning string names to Steps in a Plot.
 // StepNames will be part of wiring things together using Pipes.
 //
@@ -90,6 +164,24 @@ func (pi *PlotInput) Basis() *PlotInputSimple {
 	}
 }
 
+// ParsePlotInput parses a PlotInput from its string representation,
+// e.g. "ware:tar:abcd..." or "catalog:warpsys.org/busybox:v1.35.0:amd64-static".
+// This applies the same rules as parsing a PlotInput within a plot file.
+//
+// Errors:
+//
+//    - warpforge-error-serialization -- when the string is not a valid PlotInput
+func ParsePlotInput(s string) (PlotInput, error) {
+	nb := bindnode.Prototype(&PlotInput{}, TypeSystem.TypeByName("PlotInput")).Representation().NewBuilder()
+	if err := nb.AssignString(s); err != nil {
+		return PlotInput{}, serum.Error(ECodeSerialization, serum.WithCause(err),
+			serum.WithMessageTemplate("invalid plot input {{input|q}}"),
+			serum.WithDetail("input", s),
+		)
+	}
+	return *bindnode.Unwrap(nb.Build()).(*PlotInput), nil
+}
+
 type PlotInputSimple struct {
 	WareID     *WareID
 	Mount      *Mount
@@ -149,7 +241,54 @@ type PlotResults struct {
 	Values map[LocalLabel]WareID
 }
 
+type PlotPlan struct {
+	Steps []StepPlan
+}
+
+type StepPlan struct {
+	Step      string
+	Status    StepPlanStatus
+	FormulaID *string
+	Reasons   []string
+}
+
+type StepPlanStatus string
+
+const (
+	StepPlanStatus_Memoized StepPlanStatus = "memoized"
+	StepPlanStatus_Execute  StepPlanStatus = "execute"
+	StepPlanStatus_Blocked  StepPlanStatus = "blocked"
+)
+
+type PlotLockCapsule struct {
+	PlotLock *PlotLock
+}
+
+type PlotLock struct {
+	CatalogRefs struct {
+		Keys   []CatalogRef
+		Values map[CatalogRef]PlotLockEntry
+	}
+}
+
+type PlotLockEntry struct {
+	Ware     WareID
+	Addr     *WarehouseAddr
+	Resolved *CatalogRef
+}
+
 type PlotExecConfig struct {
 	Recursive         bool
 	FormulaExecConfig FormulaExecConfig
+	// Steps, if not empty, restricts execution of the top level plot to these steps
+	// and the steps they transitively depend on.
+	// The results will contain the outputs of these steps rather than the plot's outputs.
+	Steps []StepName
+	// InputOverrides replaces the values of inputs of the top level plot before execution.
+	// Every label must already be an input of the plot.
+	InputOverrides map[LocalLabel]PlotInput
+	// Lock, if set, pins the resolution of catalog references in the top level plot and its subplots.
+	// Execution fails if the catalogs resolve any locked reference to a different ware,
+	// or if the plot uses a reference that is not locked.
+	Lock *PlotLock
 }
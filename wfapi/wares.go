@@ -3,6 +3,7 @@ package wfapi
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 )
 
 type WareID struct {
@@ -43,10 +44,35 @@ const (
 )
 
 type Ingest struct {
-	GitIngest *GitIngest
+	GitIngest     *GitIngest
+	HostDirIngest *HostDirIngest
+	HttpIngest    *HttpIngest
 }
 
 type GitIngest struct {
 	HostPath string
 	Ref      string
 }
+
+// HostDirIngest is the host path of a directory to ingest.
+type HostDirIngest string
+
+func (i HostDirIngest) HostPath() string {
+	return string(i)
+}
+
+// HttpIngest is the expected hash of a file to download, an "@", and its URL.
+type HttpIngest string
+
+// Hash returns the part of the ingest before the first "@".
+func (i HttpIngest) Hash() string {
+	hash, _, _ := strings.Cut(string(i), "@")
+	return hash
+}
+
+// Url returns the part of the ingest after the first "@", which may contain more of them.
+// It's empty if there is no "@".
+func (i HttpIngest) Url() string {
+	_, url, _ := strings.Cut(string(i), "@")
+	return url
+}